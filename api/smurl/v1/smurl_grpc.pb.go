@@ -0,0 +1,220 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v4.24.4
+// source: api/smurl/v1/smurl.proto
+
+package smurlv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	Smurl_Create_FullMethodName      = "/smurl.v1.Smurl/Create"
+	Smurl_BatchCreate_FullMethodName = "/smurl.v1.Smurl/BatchCreate"
+	Smurl_Resolve_FullMethodName     = "/smurl.v1.Smurl/Resolve"
+	Smurl_GetStats_FullMethodName    = "/smurl.v1.Smurl/GetStats"
+)
+
+// SmurlClient is the client API for Smurl service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type SmurlClient interface {
+	Create(ctx context.Context, in *CreateRequest, opts ...grpc.CallOption) (*Link, error)
+	BatchCreate(ctx context.Context, in *BatchCreateRequest, opts ...grpc.CallOption) (*BatchCreateResponse, error)
+	Resolve(ctx context.Context, in *ResolveRequest, opts ...grpc.CallOption) (*Link, error)
+	GetStats(ctx context.Context, in *GetStatsRequest, opts ...grpc.CallOption) (*LinkStats, error)
+}
+
+type smurlClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSmurlClient(cc grpc.ClientConnInterface) SmurlClient {
+	return &smurlClient{cc}
+}
+
+func (c *smurlClient) Create(ctx context.Context, in *CreateRequest, opts ...grpc.CallOption) (*Link, error) {
+	out := new(Link)
+	err := c.cc.Invoke(ctx, Smurl_Create_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *smurlClient) BatchCreate(ctx context.Context, in *BatchCreateRequest, opts ...grpc.CallOption) (*BatchCreateResponse, error) {
+	out := new(BatchCreateResponse)
+	err := c.cc.Invoke(ctx, Smurl_BatchCreate_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *smurlClient) Resolve(ctx context.Context, in *ResolveRequest, opts ...grpc.CallOption) (*Link, error) {
+	out := new(Link)
+	err := c.cc.Invoke(ctx, Smurl_Resolve_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *smurlClient) GetStats(ctx context.Context, in *GetStatsRequest, opts ...grpc.CallOption) (*LinkStats, error) {
+	out := new(LinkStats)
+	err := c.cc.Invoke(ctx, Smurl_GetStats_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SmurlServer is the server API for Smurl service.
+// All implementations must embed UnimplementedSmurlServer
+// for forward compatibility
+type SmurlServer interface {
+	Create(context.Context, *CreateRequest) (*Link, error)
+	BatchCreate(context.Context, *BatchCreateRequest) (*BatchCreateResponse, error)
+	Resolve(context.Context, *ResolveRequest) (*Link, error)
+	GetStats(context.Context, *GetStatsRequest) (*LinkStats, error)
+	mustEmbedUnimplementedSmurlServer()
+}
+
+// UnimplementedSmurlServer must be embedded to have forward compatible implementations.
+type UnimplementedSmurlServer struct {
+}
+
+func (UnimplementedSmurlServer) Create(context.Context, *CreateRequest) (*Link, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Create not implemented")
+}
+func (UnimplementedSmurlServer) BatchCreate(context.Context, *BatchCreateRequest) (*BatchCreateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchCreate not implemented")
+}
+func (UnimplementedSmurlServer) Resolve(context.Context, *ResolveRequest) (*Link, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Resolve not implemented")
+}
+func (UnimplementedSmurlServer) GetStats(context.Context, *GetStatsRequest) (*LinkStats, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStats not implemented")
+}
+func (UnimplementedSmurlServer) mustEmbedUnimplementedSmurlServer() {}
+
+// UnsafeSmurlServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SmurlServer will
+// result in compilation errors.
+type UnsafeSmurlServer interface {
+	mustEmbedUnimplementedSmurlServer()
+}
+
+func RegisterSmurlServer(s grpc.ServiceRegistrar, srv SmurlServer) {
+	s.RegisterService(&Smurl_ServiceDesc, srv)
+}
+
+func _Smurl_Create_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SmurlServer).Create(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Smurl_Create_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SmurlServer).Create(ctx, req.(*CreateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Smurl_BatchCreate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchCreateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SmurlServer).BatchCreate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Smurl_BatchCreate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SmurlServer).BatchCreate(ctx, req.(*BatchCreateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Smurl_Resolve_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResolveRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SmurlServer).Resolve(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Smurl_Resolve_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SmurlServer).Resolve(ctx, req.(*ResolveRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Smurl_GetStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SmurlServer).GetStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Smurl_GetStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SmurlServer).GetStats(ctx, req.(*GetStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Smurl_ServiceDesc is the grpc.ServiceDesc for Smurl service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Smurl_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "smurl.v1.Smurl",
+	HandlerType: (*SmurlServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Create",
+			Handler:    _Smurl_Create_Handler,
+		},
+		{
+			MethodName: "BatchCreate",
+			Handler:    _Smurl_BatchCreate_Handler,
+		},
+		{
+			MethodName: "Resolve",
+			Handler:    _Smurl_Resolve_Handler,
+		},
+		{
+			MethodName: "GetStats",
+			Handler:    _Smurl_GetStats_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "api/smurl/v1/smurl.proto",
+}
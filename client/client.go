@@ -0,0 +1,279 @@
+// Package client is a Go client of the smurl JSON API described
+// by the OpenAPI document served at /api/openapi.json
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Application error codes of Error
+const (
+	CodeInternal       int64 = 1
+	CodeInvalidRequest int64 = 2
+	CodeNotFound       int64 = 3
+	CodeExpired        int64 = 4
+	CodeBlocked        int64 = 5
+	CodeConflict       int64 = 6
+	CodeRateLimited    int64 = 7
+	CodeDeleted        int64 = 8
+	CodeNotAllowed     int64 = 9
+	CodeDisabled       int64 = 10
+)
+
+// Passthrough modes of a link
+const (
+	PassthroughOff      = ""
+	PassthroughKeep     = "keep"
+	PassthroughOverride = "override"
+)
+
+// Granularities of a time series
+const (
+	Hourly = "hour"
+	Daily  = "day"
+)
+
+// CreateRequest describes a link to create
+type CreateRequest struct {
+	LongURL     string `json:"long_url"`
+	Passthrough string `json:"passthrough,omitempty"`
+	UTMSource   string `json:"utm_source,omitempty"`
+	UTMMedium   string `json:"utm_medium,omitempty"`
+	UTMCampaign string `json:"utm_campaign,omitempty"`
+	UTMTerm     string `json:"utm_term,omitempty"`
+	UTMContent  string `json:"utm_content,omitempty"`
+}
+
+// EditRequest describes the changes of a link, nil fields are kept
+type EditRequest struct {
+	LongURL   *string    `json:"long_url,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// Removes the expiry, ignored when ExpiresAt is set
+	RemoveExpiry bool    `json:"remove_expiry,omitempty"`
+	Passthrough  *string `json:"passthrough,omitempty"`
+	Sticky       *bool   `json:"sticky,omitempty"`
+}
+
+// Link is a small url. The admin fields are only set for the owner
+type Link struct {
+	SmallURL    string     `json:"small_url"`
+	ShortLink   string     `json:"short_link"`
+	AdminURL    string     `json:"admin_url,omitempty"`
+	AdminLink   string     `json:"admin_link,omitempty"`
+	LongURL     string     `json:"long_url"`
+	Passthrough string     `json:"passthrough,omitempty"`
+	Sticky      bool       `json:"sticky"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ModifiedAt  time.Time  `json:"modified_at"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+// StatItem is the number of clicks with a value of a click property
+type StatItem struct {
+	Name  string `json:"name"`
+	Count uint64 `json:"count"`
+}
+
+// LinkStats is a link with its statistics
+type LinkStats struct {
+	Link
+	Count    uint64 `json:"count"`
+	Uniques  uint64 `json:"uniques"`
+	BotCount uint64 `json:"bot_count"`
+	// Clicks counted by the shortener the link was imported from
+	ImportedClicks uint64     `json:"imported_clicks,omitempty"`
+	Referrers      []StatItem `json:"referrers"`
+	Browsers       []StatItem `json:"browsers"`
+	OS             []StatItem `json:"os"`
+	Devices        []StatItem `json:"devices"`
+	Countries      []StatItem `json:"countries"`
+}
+
+// BulkResult is the result of one link of a bulk request
+type BulkResult struct {
+	Link  *Link  `json:"link,omitempty"`
+	Error *Error `json:"error,omitempty"`
+}
+
+// TimeBucket is the number of clicks in the bucket starting at Start
+type TimeBucket struct {
+	Start time.Time `json:"start"`
+	Count uint64    `json:"count"`
+}
+
+// TimeSeries is the number of clicks over time
+type TimeSeries struct {
+	Granularity string       `json:"granularity"`
+	From        time.Time    `json:"from"`
+	To          time.Time    `json:"to"`
+	Buckets     []TimeBucket `json:"buckets"`
+}
+
+// Error is an error returned by the API
+type Error struct {
+	// Status code of the response, not set in bulk results
+	StatusCode int    `json:"-"`
+	Status     string `json:"status"`
+	Code       int64  `json:"code,omitempty"`
+	Message    string `json:"error,omitempty"`
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("smurl: %s (code %d)", e.Status, e.Code)
+	}
+	return fmt.Sprintf("smurl: %s (code %d): %s", e.Status, e.Code, e.Message)
+}
+
+// Client calls the API of a smurl server
+type Client struct {
+	baseURL string
+	http    *http.Client
+}
+
+// New creates a client of the server at baseURL, e.g.
+// http://localhost:1234/. A nil httpClient means http.DefaultClient
+func New(baseURL string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		http:    httpClient,
+	}
+}
+
+// Create creates a link
+func (c *Client) Create(ctx context.Context, req CreateRequest) (*Link, error) {
+	var link Link
+	err := c.do(ctx, http.MethodPost, "/api/links", req, &link)
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// BulkCreate creates up to 100 links. A failed link does not stop the
+// others, the results are in the order of the requests
+func (c *Client) BulkCreate(ctx context.Context, reqs []CreateRequest) ([]BulkResult, error) {
+	var resp struct {
+		Results []BulkResult `json:"results"`
+	}
+	body := struct {
+		Links []CreateRequest `json:"links"`
+	}{Links: reqs}
+	err := c.do(ctx, http.MethodPost, "/api/links/bulk", body, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Results, nil
+}
+
+// Lookup returns the link of a small url without counting a click
+func (c *Client) Lookup(ctx context.Context, smallURL string) (*Link, error) {
+	var link Link
+	err := c.do(ctx, http.MethodGet, "/api/links/"+url.PathEscape(smallURL), nil, &link)
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// Stats returns a link with its statistics
+func (c *Client) Stats(ctx context.Context, adminURL string) (*LinkStats, error) {
+	var stats LinkStats
+	err := c.do(ctx, http.MethodGet, "/api/admin/"+url.PathEscape(adminURL), nil, &stats)
+	if err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// Edit changes a link and returns the changed link
+func (c *Client) Edit(ctx context.Context, adminURL string, req EditRequest) (*Link, error) {
+	var link Link
+	err := c.do(ctx, http.MethodPatch, "/api/admin/"+url.PathEscape(adminURL), req, &link)
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// Delete deletes a link
+func (c *Client) Delete(ctx context.Context, adminURL string) error {
+	return c.do(ctx, http.MethodDelete, "/api/admin/"+url.PathEscape(adminURL), nil, nil)
+}
+
+// TimeSeries returns the clicks of a link between from and to in
+// buckets of the granularity. Zero times use the server defaults
+func (c *Client) TimeSeries(ctx context.Context, adminURL string, granularity string, from, to time.Time) (*TimeSeries, error) {
+	query := url.Values{}
+	if granularity != "" {
+		query.Set("granularity", granularity)
+	}
+	if !from.IsZero() {
+		query.Set("from", from.Format(time.RFC3339))
+	}
+	if !to.IsZero() {
+		query.Set("to", to.Format(time.RFC3339))
+	}
+	path := "/api/stats/" + url.PathEscape(adminURL) + "/clicks"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	var series TimeSeries
+	err := c.do(ctx, http.MethodGet, path, nil, &series)
+	if err != nil {
+		return nil, err
+	}
+	return &series, nil
+}
+
+// do sends a request with the JSON body and decodes the
+// JSON response into out, errors of the API into *Error
+func (c *Client) do(ctx context.Context, method, path string, body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("smurl: encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("smurl: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("smurl: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		apiErr := &Error{StatusCode: resp.StatusCode}
+		if err := json.NewDecoder(resp.Body).Decode(apiErr); err != nil || apiErr.Status == "" {
+			apiErr.Status = http.StatusText(resp.StatusCode)
+		}
+		return apiErr
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("smurl: decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/sanyarise/smurl/internal/delivery"
+	helpers "github.com/sanyarise/smurl/internal/helpers/mocks"
+	"github.com/sanyarise/smurl/internal/models"
+	"github.com/sanyarise/smurl/internal/usecase/mocks"
+	"github.com/sanyarise/smurl/static"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+var (
+	ctx     = context.Background()
+	created = time.Date(2023, 3, 5, 14, 7, 0, 0, time.UTC)
+)
+
+type TestStatement struct {
+	helpers *helpers.MockHelper
+	usecase *mocks.MockUsecase
+	server  *httptest.Server
+	client  *Client
+}
+
+// NewTestStatement runs the router of the service with mocked
+// usecase and helpers, and a client of it
+func NewTestStatement(t *testing.T, ctrl *gomock.Controller) *TestStatement {
+	helpers := helpers.NewMockHelper(ctrl)
+	usecase := mocks.NewMockUsecase(ctrl)
+	router, err := delivery.NewRouter(usecase, helpers, zap.L(), "http://sm.url/", delivery.DefaultTheme(), static.Assets(""))
+	require.NoError(t, err)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return &TestStatement{
+		helpers: helpers,
+		usecase: usecase,
+		server:  server,
+		client:  New(server.URL+"/", server.Client()),
+	}
+}
+
+func TestCreate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(t, ctrl)
+
+	tagged := "http://mail.ru?utm_source=news"
+	s.helpers.EXPECT().CheckURL(tagged).Return(tagged, nil)
+	s.usecase.EXPECT().Create(gomock.Any(), tagged, models.PassthroughKeep).Return(&models.Smurl{
+		SmallURL: "abc", AdminURL: "admin", LongURL: tagged, Passthrough: models.PassthroughKeep, CreatedAt: created,
+	}, nil)
+	link, err := s.client.Create(ctx, CreateRequest{LongURL: "http://mail.ru", UTMSource: "news", Passthrough: PassthroughKeep})
+	require.NoError(t, err)
+	require.Equal(t, "abc", link.SmallURL)
+	require.Equal(t, "http://sm.url/r/abc", link.ShortLink)
+	require.Equal(t, "admin", link.AdminURL)
+	require.Equal(t, "http://sm.url/s/admin", link.AdminLink)
+	require.Equal(t, PassthroughKeep, link.Passthrough)
+	require.True(t, created.Equal(link.CreatedAt))
+	require.Nil(t, link.ExpiresAt)
+
+	s.helpers.EXPECT().CheckURL("http://blocked.com").Return("http://blocked.com", nil)
+	s.usecase.EXPECT().Create(gomock.Any(), "http://blocked.com", models.PassthroughOff).Return(nil, models.ErrBlocked)
+	_, err = s.client.Create(ctx, CreateRequest{LongURL: "http://blocked.com"})
+	var apiErr *Error
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, 403, apiErr.StatusCode)
+	require.Equal(t, CodeBlocked, apiErr.Code)
+	require.NotEmpty(t, apiErr.Message)
+}
+
+func TestBulkCreate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(t, ctrl)
+
+	s.helpers.EXPECT().CheckURL("http://a.com").Return("http://a.com", nil)
+	s.helpers.EXPECT().CheckURL("http://b.com").Return("http://b.com", nil)
+	s.usecase.EXPECT().Create(gomock.Any(), "http://a.com", models.PassthroughOff).Return(&models.Smurl{SmallURL: "a", AdminURL: "aa", LongURL: "http://a.com"}, nil)
+	s.usecase.EXPECT().Create(gomock.Any(), "http://b.com", models.PassthroughOff).Return(nil, &models.ValidationError{Reason: "too long"})
+	results, err := s.client.BulkCreate(ctx, []CreateRequest{{LongURL: "http://a.com"}, {LongURL: "http://b.com"}})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	require.Equal(t, "a", results[0].Link.SmallURL)
+	require.Nil(t, results[0].Error)
+	require.Nil(t, results[1].Link)
+	require.Equal(t, CodeInvalidRequest, results[1].Error.Code)
+	require.Equal(t, "too long", results[1].Error.Message)
+
+	_, err = s.client.BulkCreate(ctx, nil)
+	var apiErr *Error
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, 400, apiErr.StatusCode)
+}
+
+func TestLookup(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(t, ctrl)
+
+	s.usecase.EXPECT().FindURL(gomock.Any(), "abc").Return(&models.Smurl{SmallURL: "abc", LongURL: "http://mail.ru"}, nil)
+	link, err := s.client.Lookup(ctx, "abc")
+	require.NoError(t, err)
+	require.Equal(t, "http://mail.ru", link.LongURL)
+	require.Empty(t, link.AdminURL)
+
+	s.usecase.EXPECT().FindURL(gomock.Any(), "gone").Return(nil, models.ErrExpired)
+	_, err = s.client.Lookup(ctx, "gone")
+	var apiErr *Error
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, 410, apiErr.StatusCode)
+	require.Equal(t, CodeExpired, apiErr.Code)
+}
+
+func TestStats(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(t, ctrl)
+
+	s.usecase.EXPECT().ReadStat(gomock.Any(), "admin").Return(&models.Smurl{
+		SmallURL: "abc", AdminURL: "admin", LongURL: "http://mail.ru", Count: 5, Uniques: 3, BotCount: 1, ImportedClicks: 40,
+		Stats: models.ClickStats{Browsers: []models.StatItem{{Name: "Firefox", Count: 5}}},
+	}, nil)
+	stats, err := s.client.Stats(ctx, "admin")
+	require.NoError(t, err)
+	require.Equal(t, "abc", stats.SmallURL)
+	require.Equal(t, uint64(5), stats.Count)
+	require.Equal(t, uint64(3), stats.Uniques)
+	require.Equal(t, uint64(1), stats.BotCount)
+	require.Equal(t, uint64(40), stats.ImportedClicks)
+	require.Equal(t, []StatItem{{Name: "Firefox", Count: 5}}, stats.Browsers)
+	require.Empty(t, stats.Referrers)
+
+	s.usecase.EXPECT().ReadStat(gomock.Any(), "nope").Return(nil, models.ErrNotFound)
+	_, err = s.client.Stats(ctx, "nope")
+	var apiErr *Error
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, CodeNotFound, apiErr.Code)
+}
+
+func TestEdit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(t, ctrl)
+
+	longURL := "http://mail.ru/new"
+	expires := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	sticky := true
+	s.usecase.EXPECT().Edit(gomock.Any(), "admin", gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ string, edit models.Edit) (*models.Smurl, error) {
+			require.Equal(t, longURL, *edit.LongURL)
+			require.True(t, expires.Equal(*edit.ExpiresAt))
+			require.True(t, *edit.Sticky)
+			require.Nil(t, edit.Passthrough)
+			return &models.Smurl{SmallURL: "abc", AdminURL: "admin", LongURL: longURL, ExpiresAt: expires, Sticky: true}, nil
+		})
+	link, err := s.client.Edit(ctx, "admin", EditRequest{LongURL: &longURL, ExpiresAt: &expires, Sticky: &sticky})
+	require.NoError(t, err)
+	require.Equal(t, longURL, link.LongURL)
+	require.True(t, expires.Equal(*link.ExpiresAt))
+	require.True(t, link.Sticky)
+
+	// Removing the expiry sends a zero time to the usecase
+	s.usecase.EXPECT().Edit(gomock.Any(), "admin", gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ string, edit models.Edit) (*models.Smurl, error) {
+			require.NotNil(t, edit.ExpiresAt)
+			require.True(t, edit.ExpiresAt.IsZero())
+			return &models.Smurl{SmallURL: "abc", AdminURL: "admin"}, nil
+		})
+	link, err = s.client.Edit(ctx, "admin", EditRequest{RemoveExpiry: true})
+	require.NoError(t, err)
+	require.Nil(t, link.ExpiresAt)
+
+	s.usecase.EXPECT().Edit(gomock.Any(), "admin", gomock.Any()).Return(nil, &models.ValidationError{Reason: "nothing to change"})
+	_, err = s.client.Edit(ctx, "admin", EditRequest{})
+	var apiErr *Error
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, 400, apiErr.StatusCode)
+	require.Equal(t, "nothing to change", apiErr.Message)
+}
+
+func TestDelete(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(t, ctrl)
+
+	s.usecase.EXPECT().Delete(gomock.Any(), "admin").Return(nil)
+	require.NoError(t, s.client.Delete(ctx, "admin"))
+
+	s.usecase.EXPECT().Delete(gomock.Any(), "admin").Return(models.ErrDeleted)
+	err := s.client.Delete(ctx, "admin")
+	var apiErr *Error
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, 410, apiErr.StatusCode)
+	require.Equal(t, CodeDeleted, apiErr.Code)
+
+	s.usecase.EXPECT().Delete(gomock.Any(), "admin").Return(errors.New("connection refused"))
+	err = s.client.Delete(ctx, "admin")
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, 500, apiErr.StatusCode)
+	require.Equal(t, CodeInternal, apiErr.Code)
+	require.Empty(t, apiErr.Message)
+}
+
+func TestTimeSeries(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(t, ctrl)
+
+	from := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)
+	buckets := []models.TimeBucket{{Start: from, Count: 3}, {Start: to, Count: 1}}
+	s.usecase.EXPECT().TimeSeries(gomock.Any(), "admin", models.Hourly, from, to).Return(buckets, nil)
+	series, err := s.client.TimeSeries(ctx, "admin", Hourly, from, to)
+	require.NoError(t, err)
+	require.Equal(t, Hourly, series.Granularity)
+	require.Len(t, series.Buckets, 2)
+	require.Equal(t, uint64(3), series.Buckets[0].Count)
+}
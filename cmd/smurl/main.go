@@ -2,18 +2,30 @@ package main
 
 import (
 	"context"
+	"expvar"
+	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/sanyarise/smurl/config"
 	"github.com/sanyarise/smurl/internal/delivery"
+	"github.com/sanyarise/smurl/internal/delivery/rpc"
 	"github.com/sanyarise/smurl/internal/helpers"
+	"github.com/sanyarise/smurl/internal/helpers/codegen"
+	"github.com/sanyarise/smurl/internal/infrastructure/clicksink"
+	"github.com/sanyarise/smurl/internal/infrastructure/domainlist"
+	"github.com/sanyarise/smurl/internal/infrastructure/geoip"
 	"github.com/sanyarise/smurl/internal/infrastructure/logger"
+	"github.com/sanyarise/smurl/internal/infrastructure/retention"
 	"github.com/sanyarise/smurl/internal/infrastructure/server"
+	"github.com/sanyarise/smurl/internal/infrastructure/webhook"
 	"github.com/sanyarise/smurl/internal/repository"
 	"github.com/sanyarise/smurl/internal/usecase"
+	"github.com/sanyarise/smurl/static"
 	"go.uber.org/zap"
 )
 
@@ -36,12 +48,103 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
-	helpers := helpers.NewHelpers(logger)
+	// Domain allow and deny lists init, reloaded when the file changes
+	domainList, err := domainlist.NewDomainList(cfg.DomainListPath, logger)
+	if err != nil {
+		log.Fatal(err)
+	}
+	go domainList.Watch(ctx, time.Duration(cfg.DomainListReload)*time.Second)
+
+	// GeoIP database init, optional
+	geo, err := geoip.NewGeoIP(cfg.GeoIPPath, logger)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	helpers := helpers.NewHelpers(logger, cfg.ServerURL, cfg.AllowedSchemes, cfg.VisitorSecret, cfg.IPMode, cfg.IPHashKey)
+	// Code generators init
+	codes, err := codegen.New(cfg.CodeStrategy, cfg.CodeAlphabet, cfg.CodeLength, cfg.CodeSalt, cfg.CodeNode, repository)
+	if err != nil {
+		log.Fatal(err)
+	}
+	adminCodes, adminCodeLength, err := codegen.NewAdminGenerator(cfg.CodeAlphabet, cfg.AdminCodeLength)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if adminCodeLength != cfg.AdminCodeLength {
+		logger.Warn("admin code length is too short, using the minimum",
+			zap.Int("admin_code_length", cfg.AdminCodeLength),
+			zap.Int("minimum", adminCodeLength))
+	}
+	// Click streaming to the configured sinks, in the background
+	// so that slow sinks don't delay the redirects
+	sinks, err := newClickSinks(cfg, logger)
+	if err != nil {
+		log.Fatal(err)
+	}
+	clicks := clicksink.NewStream(sinks, cfg.ClickBuffer, logger)
+	expvar.Publish("clicks", clicks)
+	clicksDone := make(chan struct{})
+	go func() {
+		clicks.Run(ctx)
+		close(clicksDone)
+	}()
+
 	// Interface layer init
-	usecase := usecase.NewSmurlUsecase(repository, helpers, logger)
+	usecase := usecase.NewSmurlUsecase(repository, domainList, geo, clicks, helpers, codes, adminCodes, logger)
+
+	// Deleting click details after the retention period
+	retention := retention.NewRetention(usecase, time.Duration(cfg.RetentionDays)*24*time.Hour, logger)
+	go retention.Run(ctx, time.Hour)
+
+	// Sending the link events of the outbox to the webhooks
+	dispatcher := webhook.NewDispatcher(repository, &http.Client{Timeout: time.Duration(cfg.WebhookTimeout) * time.Second},
+		cfg.WebhookMaxAttempts, time.Duration(cfg.WebhookBackoff)*time.Second, logger)
+	go dispatcher.Run(ctx, time.Duration(cfg.WebhookInterval)*time.Second)
 
-	// Router init
-	router := delivery.NewRouter(usecase, helpers, logger, cfg.ServerURL)
+	// Router init, the embedded templates and assets
+	// may be replaced with the files of the template directory
+	theme := delivery.Theme{
+		Name:       cfg.Theme.Name,
+		Tagline:    cfg.Theme.Tagline,
+		Logo:       cfg.Theme.Logo,
+		Background: cfg.Theme.Background,
+		Text:       cfg.Theme.Text,
+		Accent:     cfg.Theme.Accent,
+		Alert:      cfg.Theme.Alert,
+		Highlight:  cfg.Theme.Highlight,
+		FooterText: cfg.Theme.FooterText,
+		FooterURL:  cfg.Theme.FooterURL,
+	}
+	router, err := delivery.NewRouter(usecase, helpers, logger, cfg.ServerURL, theme, static.Assets(cfg.TemplateDir))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// gRPC server init for internal callers, optional
+	var grpcServer *server.GRPCServer
+	if cfg.GRPCPort != "" {
+		// The keys of the configuration and those managed with smurlctl
+		auth := rpc.Authenticators{rpc.NewKeys(cfg.GRPCAPIKeys), rpc.NewStoredKeys(repository)}
+		metrics := rpc.NewMetrics()
+		expvar.Publish("grpc", metrics)
+		service := rpc.NewService(usecase, helpers, logger, cfg.ServerURL)
+		grpcServer = server.NewGRPCServer(":"+cfg.GRPCPort, rpc.NewServer(service, auth, metrics, logger), logger)
+		if err := grpcServer.Start(); err != nil {
+			log.Fatal(err)
+		}
+		logger.Info("Start grpc server successfull",
+			zap.String("Port ", ":"+cfg.GRPCPort))
+	}
+
+	// Metrics server init, optional
+	var metricsServer *server.Server
+	if cfg.MetricsPort != "" {
+		metricsServer = server.NewServer(":"+cfg.MetricsPort, expvar.Handler(), logger, cfg.ReadTimeout, cfg.WriteTimeout, cfg.WriteHeaderTimeout)
+		metricsServer.Start()
+		logger.Info("Start metrics server successfull",
+			zap.String("Port ", ":"+cfg.MetricsPort))
+	}
 
 	// Server init
 	server := server.NewServer(":"+cfg.Port, router, logger, cfg.ReadTimeout, cfg.WriteTimeout, cfg.WriteHeaderTimeout)
@@ -53,11 +156,48 @@ func main() {
 
 	<-ctx.Done()
 
-	// Stopping the server when receiving a context termination signal
+	// Stopping the servers when receiving a context termination signal
 	server.Stop()
+	if grpcServer != nil {
+		grpcServer.Stop()
+	}
+	if metricsServer != nil {
+		metricsServer.Stop()
+	}
 	logger.Info("Server stopped successfull")
 	cancel()
 
+	// Writing the clicks left
+	<-clicksDone
+
 	// Database shutdown
 	repository.Close()
+	geo.Close()
+}
+
+// newClickSinks creates the sinks of the clicks named in the configuration
+func newClickSinks(cfg *config.Config, logger *zap.Logger) ([]clicksink.Sink, error) {
+	var sinks []clicksink.Sink
+	for _, name := range cfg.ClickSinks {
+		switch name {
+		case "file":
+			file, err := clicksink.NewFile(cfg.ClickFileDir, int64(cfg.ClickFileMaxSize)<<20,
+				time.Duration(cfg.ClickFileRotate)*time.Second)
+			if err != nil {
+				return nil, err
+			}
+			sinks = append(sinks, file)
+		case "nats":
+			nats, err := clicksink.NewNATS(cfg.NATSURL, cfg.NATSSubject, logger)
+			if err != nil {
+				return nil, err
+			}
+			sinks = append(sinks, nats)
+		case "kafka":
+			sinks = append(sinks, clicksink.NewKafka(cfg.KafkaBrokers, cfg.KafkaTopic, logger))
+		default:
+			return nil, fmt.Errorf("unknown click sink %q, expected file, nats or kafka", name)
+		}
+	}
+	return sinks, nil
 }
@@ -0,0 +1,799 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sanyarise/smurl/internal/helpers"
+	"github.com/sanyarise/smurl/internal/helpers/codegen"
+	"github.com/sanyarise/smurl/internal/models"
+	"github.com/sanyarise/smurl/internal/transfer"
+)
+
+// errUsage reports a command called with wrong arguments
+var errUsage = errors.New("usage")
+
+// Store is the part of the repository used by the commands
+type Store interface {
+	FindURL(ctx context.Context, smallUrl string) (*models.Smurl, error)
+	List(ctx context.Context, filter models.ListFilter) ([]models.Smurl, error)
+	ReadClickStats(ctx context.Context, smallUrl string) (*models.ClickStats, error)
+	SetDisabled(ctx context.Context, smallUrl string, disabled bool) error
+	Delete(ctx context.Context, smallUrl string) error
+	CreateAPIKey(ctx context.Context, key models.APIKey) (*models.APIKey, error)
+	ListAPIKeys(ctx context.Context) ([]models.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id int64) error
+	ExportLinks(ctx context.Context, fn func(models.Smurl) error) error
+	ImportLink(ctx context.Context, smurl models.Smurl) (*models.Smurl, error)
+	CreateWebhook(ctx context.Context, webhook models.Webhook) (*models.Webhook, error)
+	ListWebhooks(ctx context.Context) ([]models.Webhook, error)
+	DeleteWebhook(ctx context.Context, id int64) error
+	ListAttempts(ctx context.Context, webhookID int64, limit int) ([]models.WebhookAttempt, error)
+}
+
+// Creator creates links with the checks of the service
+type Creator interface {
+	Create(ctx context.Context, longUrl string, passthrough models.Passthrough) (*models.Smurl, error)
+}
+
+// Ctl runs the commands of smurlctl
+type Ctl struct {
+	store   Store
+	usecase Creator
+	helpers helpers.Helper
+	// Allow and deny lists of the destinations
+	policy transfer.DomainPolicy
+	// Generates the admin tokens of links imported from other shorteners
+	adminCodes codegen.CodeGenerator
+	url        string
+	// Whether the output is JSON instead of tables
+	json bool
+	in   io.Reader
+	out  io.Writer
+}
+
+// Link as printed by the commands
+type Link struct {
+	SmallURL  string `json:"small_url"`
+	ShortLink string `json:"short_link"`
+	AdminURL  string `json:"admin_url,omitempty"`
+	AdminLink string `json:"admin_link,omitempty"`
+	LongURL   string `json:"long_url"`
+	Status    string `json:"status"`
+	Count     uint64 `json:"count"`
+	Uniques   uint64 `json:"uniques"`
+	BotCount  uint64 `json:"bot_count"`
+	// Clicks counted by the shortener the link was imported from
+	ImportedClicks uint64     `json:"imported_clicks,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ModifiedAt     time.Time  `json:"modified_at"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	DeletedAt      *time.Time `json:"deleted_at,omitempty"`
+	DisabledAt     *time.Time `json:"disabled_at,omitempty"`
+}
+
+// Statistics as printed by the stats command
+type Stats struct {
+	Link
+	Referrers []models.StatItem `json:"referrers"`
+	Browsers  []models.StatItem `json:"browsers"`
+	OS        []models.StatItem `json:"os"`
+	Devices   []models.StatItem `json:"devices"`
+	Countries []models.StatItem `json:"countries"`
+}
+
+// API key as printed by the keys commands. The key itself
+// is only known when it is created
+type APIKey struct {
+	ID        int64      `json:"id"`
+	Name      string     `json:"name"`
+	Key       string     `json:"key,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Webhook as printed by the webhooks commands. The secret
+// is only shown when the webhook is created
+type Webhook struct {
+	ID int64 `json:"id"`
+	// API key whose links are followed, all links when omitted
+	KeyID     int64              `json:"key_id,omitempty"`
+	URL       string             `json:"url"`
+	Events    []models.EventType `json:"events"`
+	Secret    string             `json:"secret,omitempty"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+// Entry of the delivery log as printed by the webhooks log command
+type Attempt struct {
+	WebhookID  int64            `json:"webhook_id"`
+	DeliveryID int64            `json:"delivery_id"`
+	Event      models.EventType `json:"event"`
+	SmallURL   string           `json:"small_url"`
+	Attempt    int              `json:"attempt"`
+	At         time.Time        `json:"at"`
+	StatusCode int              `json:"status_code,omitempty"`
+	Error      string           `json:"error,omitempty"`
+	DurationMs int64            `json:"duration_ms"`
+}
+
+// timeOrNil returns nil for a zero time
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// status describes whether a small url redirects
+func status(smurl models.Smurl, now time.Time) string {
+	switch {
+	case smurl.Deleted():
+		return "deleted"
+	case smurl.Disabled():
+		return "disabled"
+	case smurl.Expired(now):
+		return "expired"
+	}
+	return "active"
+}
+
+func (c *Ctl) link(smurl models.Smurl) Link {
+	link := Link{
+		SmallURL:       smurl.SmallURL,
+		ShortLink:      c.url + "r/" + smurl.SmallURL,
+		LongURL:        smurl.LongURL,
+		Status:         status(smurl, time.Now()),
+		Count:          smurl.Count,
+		Uniques:        smurl.Uniques,
+		BotCount:       smurl.BotCount,
+		ImportedClicks: smurl.ImportedClicks,
+		CreatedAt:      smurl.CreatedAt,
+		ModifiedAt:     smurl.ModifiedAt,
+		ExpiresAt:      timeOrNil(smurl.ExpiresAt),
+		DeletedAt:      timeOrNil(smurl.DeletedAt),
+		DisabledAt:     timeOrNil(smurl.DisabledAt),
+	}
+	if smurl.AdminURL != "" {
+		link.AdminURL = smurl.AdminURL
+		link.AdminLink = c.url + "s/" + smurl.AdminURL
+	}
+	return link
+}
+
+func apiKey(key models.APIKey) APIKey {
+	return APIKey{
+		ID:        key.ID,
+		Name:      key.Name,
+		CreatedAt: key.CreatedAt,
+		RevokedAt: timeOrNil(key.RevokedAt),
+	}
+}
+
+// Run runs the command of the arguments
+func (c *Ctl) Run(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	command, args := args[0], args[1:]
+	switch command {
+	case "create":
+		return c.create(ctx, args)
+	case "get":
+		return c.get(ctx, args)
+	case "list":
+		return c.list(ctx, args, false)
+	case "search":
+		return c.list(ctx, args, true)
+	case "disable":
+		return c.setDisabled(ctx, args, true)
+	case "enable":
+		return c.setDisabled(ctx, args, false)
+	case "delete":
+		return c.delete(ctx, args)
+	case "export":
+		return c.export(ctx, args)
+	case "import":
+		return c.importLinks(ctx, args)
+	case "stats":
+		return c.stats(ctx, args)
+	case "keys":
+		return c.keys(ctx, args)
+	case "webhooks":
+		return c.webhooks(ctx, args)
+	}
+	return fmt.Errorf("%w: unknown command %s", errUsage, command)
+}
+
+// parse parses the flags of a command, which must be
+// followed by the given number of arguments
+func parse(flags *flag.FlagSet, args []string, n int) ([]string, error) {
+	flags.SetOutput(io.Discard)
+	if err := flags.Parse(args); err != nil {
+		return nil, fmt.Errorf("%w: %s", errUsage, err)
+	}
+	if flags.NArg() != n {
+		return nil, fmt.Errorf("%w: %s needs %d arguments", errUsage, flags.Name(), n)
+	}
+	return flags.Args(), nil
+}
+
+func (c *Ctl) create(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("create", flag.ContinueOnError)
+	passthrough := flags.String("passthrough", "", "forward the query of a click: keep or override")
+	args, err := parse(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	longURL, err := c.helpers.CheckURL(args[0])
+	if err != nil {
+		return err
+	}
+	smurl, err := c.usecase.Create(ctx, longURL, models.Passthrough(*passthrough))
+	if err != nil {
+		return err
+	}
+	link := c.link(*smurl)
+	if c.json {
+		return c.printJSON(link)
+	}
+	return c.printFields([][2]string{
+		{"short link", link.ShortLink},
+		{"admin link", link.AdminLink},
+		{"long url", link.LongURL},
+	})
+}
+
+func (c *Ctl) get(ctx context.Context, args []string) error {
+	args, err := parse(flag.NewFlagSet("get", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+	smurl, err := c.store.FindURL(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	link := c.link(*smurl)
+	if c.json {
+		return c.printJSON(link)
+	}
+	return c.printFields(linkFields(link))
+}
+
+// linkFields lists the fields of a link for printing
+func linkFields(link Link) [][2]string {
+	fields := [][2]string{
+		{"small url", link.SmallURL},
+		{"short link", link.ShortLink},
+		{"long url", link.LongURL},
+		{"status", link.Status},
+		{"clicks", strconv.FormatUint(link.Count, 10)},
+		{"uniques", strconv.FormatUint(link.Uniques, 10)},
+		{"bot clicks", strconv.FormatUint(link.BotCount, 10)},
+		{"created", formatTime(&link.CreatedAt)},
+		{"modified", formatTime(&link.ModifiedAt)},
+	}
+	if link.ImportedClicks > 0 {
+		fields = append(fields, [2]string{"imported clicks", strconv.FormatUint(link.ImportedClicks, 10)})
+	}
+	for _, field := range []struct {
+		name string
+		time *time.Time
+	}{{"expires", link.ExpiresAt}, {"deleted", link.DeletedAt}, {"disabled", link.DisabledAt}} {
+		if field.time != nil {
+			fields = append(fields, [2]string{field.name, formatTime(field.time)})
+		}
+	}
+	return fields
+}
+
+func (c *Ctl) list(ctx context.Context, args []string, search bool) error {
+	name := "list"
+	n := 0
+	if search {
+		name = "search"
+		n = 1
+	}
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	limit := flags.Int("limit", 50, "number of links")
+	offset := flags.Int("offset", 0, "number of links skipped")
+	deleted := flags.Bool("deleted", false, "include deleted links")
+	args, err := parse(flags, args, n)
+	if err != nil {
+		return err
+	}
+	filter := models.ListFilter{Limit: *limit, Offset: *offset, Deleted: *deleted}
+	if search {
+		filter.Query = args[0]
+	}
+	smurls, err := c.store.List(ctx, filter)
+	if err != nil {
+		return err
+	}
+	links := make([]Link, 0, len(smurls))
+	for _, smurl := range smurls {
+		links = append(links, c.link(smurl))
+	}
+	if c.json {
+		return c.printJSON(links)
+	}
+	rows := make([][]string, 0, len(links))
+	for _, link := range links {
+		rows = append(rows, []string{link.SmallURL, link.Status, strconv.FormatUint(link.Count, 10),
+			formatTime(&link.CreatedAt), link.LongURL})
+	}
+	return c.printTable([]string{"SMALL URL", "STATUS", "CLICKS", "CREATED", "LONG URL"}, rows)
+}
+
+func (c *Ctl) setDisabled(ctx context.Context, args []string, disabled bool) error {
+	name := "enable"
+	if disabled {
+		name = "disable"
+	}
+	args, err := parse(flag.NewFlagSet(name, flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+	if err := c.store.SetDisabled(ctx, args[0], disabled); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.out, "%sd %s\n", name, args[0])
+	return nil
+}
+
+func (c *Ctl) delete(ctx context.Context, args []string) error {
+	args, err := parse(flag.NewFlagSet("delete", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+	if err := c.store.Delete(ctx, args[0]); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.out, "deleted %s\n", args[0])
+	return nil
+}
+
+// export writes all links with their codes, counters, rules and
+// variants, so that import can restore them into another database
+func (c *Ctl) export(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := flags.String("format", string(transfer.JSONL), "jsonl or csv")
+	if _, err := parse(flags, args, 0); err != nil {
+		return err
+	}
+	f, err := transfer.ParseFormat(*format)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errUsage, err)
+	}
+	_, err = transfer.Export(ctx, c.store, c.out, f)
+	return err
+}
+
+// importLinks reads the links written by export, or with -from the links
+// exported by another shortener, from a file, or from the standard input
+// without one or with -, and prints what was done
+func (c *Ctl) importLinks(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := flags.String("format", "", "jsonl or csv, by default csv for .csv files and jsonl otherwise")
+	from := flags.String("from", "", "csv, yourls or shlink, to import the links of another shortener")
+	flags.SetOutput(io.Discard)
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("%w: %s", errUsage, err)
+	}
+	if flags.NArg() > 1 {
+		return fmt.Errorf("%w: import needs at most 1 argument", errUsage)
+	}
+	name := flags.Arg(0)
+	var source transfer.Source
+	if *from != "" {
+		s, err := transfer.ParseSource(*from)
+		if err != nil {
+			return fmt.Errorf("%w: %s", errUsage, err)
+		}
+		source = s
+	}
+	if *format == "" {
+		*format = string(transfer.JSONL)
+		if strings.HasSuffix(strings.ToLower(name), ".csv") {
+			*format = string(transfer.CSV)
+		}
+	}
+	f, err := transfer.ParseFormat(*format)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errUsage, err)
+	}
+	in := c.in
+	if name != "" && name != "-" {
+		file, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		in = file
+	}
+	var report *transfer.Report
+	destinations := transfer.NewDestinations(c.helpers, c.policy)
+	if source != "" {
+		report, err = transfer.ImportFrom(ctx, c.store, in, source, c.adminCodes, destinations)
+	} else {
+		report, err = transfer.Import(ctx, c.store, in, f, destinations)
+	}
+	if report == nil {
+		return err
+	}
+	if printErr := c.printReport(report); printErr != nil && err == nil {
+		err = printErr
+	}
+	if err != nil {
+		return err
+	}
+	if n := len(report.Conflicts) + len(report.Invalid); n > 0 {
+		return fmt.Errorf("%d links were not imported", n)
+	}
+	return nil
+}
+
+func (c *Ctl) printReport(report *transfer.Report) error {
+	if c.json {
+		return c.printJSON(report)
+	}
+	fmt.Fprintf(c.out, "created %d, unchanged %d, conflicts %d, invalid %d\n",
+		report.Created, report.Unchanged, len(report.Conflicts), len(report.Invalid))
+	if len(report.AdminTokens) > 0 {
+		// The admin links of imported links are only shown here
+		rows := make([][]string, 0, len(report.AdminTokens))
+		for _, token := range report.AdminTokens {
+			rows = append(rows, []string{token.SmallURL, c.url + "s/" + token.Token})
+		}
+		fmt.Fprintln(c.out)
+		if err := c.printTable([]string{"SMALL URL", "ADMIN LINK"}, rows); err != nil {
+			return err
+		}
+	}
+	if len(report.Conflicts)+len(report.Invalid) == 0 {
+		return nil
+	}
+	fmt.Fprintln(c.out)
+	var rows [][]string
+	for _, problem := range report.Conflicts {
+		rows = append(rows, []string{strconv.Itoa(problem.Line), problem.SmallURL, "conflict: " + problem.Reason})
+	}
+	for _, problem := range report.Invalid {
+		rows = append(rows, []string{strconv.Itoa(problem.Line), problem.SmallURL, "invalid: " + problem.Reason})
+	}
+	return c.printTable([]string{"LINE", "SMALL URL", "PROBLEM"}, rows)
+}
+
+func (c *Ctl) stats(ctx context.Context, args []string) error {
+	args, err := parse(flag.NewFlagSet("stats", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+	smurl, err := c.store.FindURL(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	clickStats, err := c.store.ReadClickStats(ctx, smurl.SmallURL)
+	if err != nil {
+		return err
+	}
+	stats := Stats{
+		Link:      c.link(*smurl),
+		Referrers: clickStats.Referrers,
+		Browsers:  clickStats.Browsers,
+		OS:        clickStats.OS,
+		Devices:   clickStats.Devices,
+		Countries: clickStats.Countries,
+	}
+	if c.json {
+		return c.printJSON(stats)
+	}
+	if err := c.printFields(linkFields(stats.Link)); err != nil {
+		return err
+	}
+	for _, breakdown := range []struct {
+		name  string
+		items []models.StatItem
+	}{
+		{"REFERRER", stats.Referrers},
+		{"BROWSER", stats.Browsers},
+		{"OS", stats.OS},
+		{"DEVICE", stats.Devices},
+		{"COUNTRY", stats.Countries},
+	} {
+		if len(breakdown.items) == 0 {
+			continue
+		}
+		rows := make([][]string, 0, len(breakdown.items))
+		for _, item := range breakdown.items {
+			rows = append(rows, []string{item.Name, strconv.FormatUint(item.Count, 10)})
+		}
+		fmt.Fprintln(c.out)
+		if err := c.printTable([]string{breakdown.name, "CLICKS"}, rows); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Ctl) keys(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: keys needs create, list or revoke", errUsage)
+	}
+	command, args := args[0], args[1:]
+	switch command {
+	case "create":
+		args, err := parse(flag.NewFlagSet("keys create", flag.ContinueOnError), args, 1)
+		if err != nil {
+			return err
+		}
+		token, err := helpers.NewAPIKey()
+		if err != nil {
+			return err
+		}
+		key, err := c.store.CreateAPIKey(ctx, models.APIKey{
+			Name:      args[0],
+			Hash:      helpers.HashToken(token),
+			CreatedAt: time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+		created := apiKey(*key)
+		created.Key = token
+		if c.json {
+			return c.printJSON(created)
+		}
+		return c.printFields([][2]string{
+			{"id", strconv.FormatInt(created.ID, 10)},
+			{"name", created.Name},
+			{"key", created.Key},
+		})
+	case "list":
+		if _, err := parse(flag.NewFlagSet("keys list", flag.ContinueOnError), args, 0); err != nil {
+			return err
+		}
+		keys, err := c.store.ListAPIKeys(ctx)
+		if err != nil {
+			return err
+		}
+		result := make([]APIKey, 0, len(keys))
+		rows := make([][]string, 0, len(keys))
+		for _, key := range keys {
+			result = append(result, apiKey(key))
+			state := "active"
+			if key.Revoked() {
+				state = "revoked " + formatTime(&key.RevokedAt)
+			}
+			rows = append(rows, []string{strconv.FormatInt(key.ID, 10), key.Name, formatTime(&key.CreatedAt), state})
+		}
+		if c.json {
+			return c.printJSON(result)
+		}
+		return c.printTable([]string{"ID", "NAME", "CREATED", "STATUS"}, rows)
+	case "revoke":
+		args, err := parse(flag.NewFlagSet("keys revoke", flag.ContinueOnError), args, 1)
+		if err != nil {
+			return err
+		}
+		id, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("%w: invalid key id %s", errUsage, args[0])
+		}
+		if err := c.store.RevokeAPIKey(ctx, id); err != nil {
+			return err
+		}
+		fmt.Fprintf(c.out, "revoked key %d\n", id)
+		return nil
+	}
+	return fmt.Errorf("%w: unknown keys command %s", errUsage, command)
+}
+
+func webhook(w models.Webhook) Webhook {
+	events := w.Events
+	if events == nil {
+		events = []models.EventType{}
+	}
+	return Webhook{
+		ID:        w.ID,
+		KeyID:     w.OwnerID,
+		URL:       w.URL,
+		Events:    events,
+		CreatedAt: w.CreatedAt,
+	}
+}
+
+// parseEvents parses a comma separated list of event types, empty for all
+func parseEvents(list string) ([]models.EventType, error) {
+	var events []models.EventType
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		event := models.EventType(name)
+		if !event.Valid() {
+			return nil, fmt.Errorf("%w: unknown event %s", errUsage, name)
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+func (c *Ctl) webhooks(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: webhooks needs add, list, remove or log", errUsage)
+	}
+	command, args := args[0], args[1:]
+	switch command {
+	case "add":
+		flags := flag.NewFlagSet("webhooks add", flag.ContinueOnError)
+		keyID := flags.Int64("key", 0, "follow only the links created with the API key of this id")
+		eventList := flags.String("events", "", "comma separated event types, all by default")
+		args, err := parse(flags, args, 1)
+		if err != nil {
+			return err
+		}
+		events, err := parseEvents(*eventList)
+		if err != nil {
+			return err
+		}
+		u, err := url.Parse(args[0])
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%w: webhook url %s is not an absolute http url", errUsage, args[0])
+		}
+		secret, err := helpers.NewWebhookSecret()
+		if err != nil {
+			return err
+		}
+		created, err := c.store.CreateWebhook(ctx, models.Webhook{
+			OwnerID:   *keyID,
+			URL:       u.String(),
+			Secret:    secret,
+			Events:    events,
+			CreatedAt: time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+		result := webhook(*created)
+		result.Secret = secret
+		if c.json {
+			return c.printJSON(result)
+		}
+		return c.printFields([][2]string{
+			{"id", strconv.FormatInt(result.ID, 10)},
+			{"url", result.URL},
+			{"events", eventNames(result.Events)},
+			{"secret", result.Secret},
+		})
+	case "list":
+		if _, err := parse(flag.NewFlagSet("webhooks list", flag.ContinueOnError), args, 0); err != nil {
+			return err
+		}
+		webhooks, err := c.store.ListWebhooks(ctx)
+		if err != nil {
+			return err
+		}
+		result := make([]Webhook, 0, len(webhooks))
+		rows := make([][]string, 0, len(webhooks))
+		for _, w := range webhooks {
+			result = append(result, webhook(w))
+			key := "all"
+			if w.OwnerID != 0 {
+				key = strconv.FormatInt(w.OwnerID, 10)
+			}
+			rows = append(rows, []string{strconv.FormatInt(w.ID, 10), w.URL, key, eventNames(w.Events),
+				formatTime(&w.CreatedAt)})
+		}
+		if c.json {
+			return c.printJSON(result)
+		}
+		return c.printTable([]string{"ID", "URL", "KEY", "EVENTS", "CREATED"}, rows)
+	case "remove":
+		args, err := parse(flag.NewFlagSet("webhooks remove", flag.ContinueOnError), args, 1)
+		if err != nil {
+			return err
+		}
+		id, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("%w: invalid webhook id %s", errUsage, args[0])
+		}
+		if err := c.store.DeleteWebhook(ctx, id); err != nil {
+			return err
+		}
+		fmt.Fprintf(c.out, "removed webhook %d\n", id)
+		return nil
+	case "log":
+		flags := flag.NewFlagSet("webhooks log", flag.ContinueOnError)
+		webhookID := flags.Int64("webhook", 0, "show only the attempts of this webhook")
+		limit := flags.Int("limit", 20, "number of attempts")
+		if _, err := parse(flags, args, 0); err != nil {
+			return err
+		}
+		attempts, err := c.store.ListAttempts(ctx, *webhookID, *limit)
+		if err != nil {
+			return err
+		}
+		result := make([]Attempt, 0, len(attempts))
+		rows := make([][]string, 0, len(attempts))
+		for _, a := range attempts {
+			result = append(result, Attempt{
+				WebhookID:  a.WebhookID,
+				DeliveryID: a.DeliveryID,
+				Event:      a.EventType,
+				SmallURL:   a.SmallURL,
+				Attempt:    a.Attempt,
+				At:         a.At,
+				StatusCode: a.StatusCode,
+				Error:      a.Error,
+				DurationMs: a.Duration.Milliseconds(),
+			})
+			outcome := "ok"
+			if a.Error != "" {
+				outcome = a.Error
+			}
+			rows = append(rows, []string{formatTime(&a.At), strconv.FormatInt(a.WebhookID, 10),
+				string(a.EventType), a.SmallURL, strconv.Itoa(a.Attempt), outcome, a.Duration.String()})
+		}
+		if c.json {
+			return c.printJSON(result)
+		}
+		return c.printTable([]string{"AT", "WEBHOOK", "EVENT", "SMALL URL", "ATTEMPT", "RESULT", "DURATION"}, rows)
+	}
+	return fmt.Errorf("%w: unknown webhooks command %s", errUsage, command)
+}
+
+// eventNames joins the event types, all for none
+func eventNames(events []models.EventType) string {
+	if len(events) == 0 {
+		return "all"
+	}
+	names := make([]string, 0, len(events))
+	for _, event := range events {
+		names = append(names, string(event))
+	}
+	return strings.Join(names, ",")
+}
+
+// formatTime formats a time for tables, empty for nil
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+func (c *Ctl) printJSON(v interface{}) error {
+	encoder := json.NewEncoder(c.out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// printFields prints the names and the values in two columns
+func (c *Ctl) printFields(fields [][2]string) error {
+	w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	for _, field := range fields {
+		fmt.Fprintf(w, "%s:\t%s\n", field[0], field[1])
+	}
+	return w.Flush()
+}
+
+// printTable prints the rows under the header in aligned columns
+func (c *Ctl) printTable(header []string, rows [][]string) error {
+	w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
@@ -0,0 +1,464 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	helper "github.com/sanyarise/smurl/internal/helpers"
+	"github.com/sanyarise/smurl/internal/helpers/codegen"
+	helpers "github.com/sanyarise/smurl/internal/helpers/mocks"
+	"github.com/sanyarise/smurl/internal/models"
+	repository "github.com/sanyarise/smurl/internal/repository/mocks"
+	"github.com/sanyarise/smurl/internal/transfer"
+	"github.com/sanyarise/smurl/internal/usecase/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	ctx     = context.Background()
+	created = time.Date(2023, 3, 5, 14, 7, 0, 0, time.UTC)
+)
+
+// fakeStore keeps the links and the keys in memory
+type fakeStore struct {
+	smurls   []models.Smurl
+	keys     []models.APIKey
+	webhooks []models.Webhook
+	attempts []models.WebhookAttempt
+	filters  []models.ListFilter
+}
+
+func (s *fakeStore) find(smallUrl string) *models.Smurl {
+	for i := range s.smurls {
+		if s.smurls[i].SmallURL == smallUrl {
+			return &s.smurls[i]
+		}
+	}
+	return nil
+}
+
+func (s *fakeStore) FindURL(ctx context.Context, smallUrl string) (*models.Smurl, error) {
+	smurl := s.find(smallUrl)
+	if smurl == nil {
+		return nil, models.ErrNotFound
+	}
+	found := *smurl
+	return &found, nil
+}
+
+func (s *fakeStore) List(ctx context.Context, filter models.ListFilter) ([]models.Smurl, error) {
+	s.filters = append(s.filters, filter)
+	var result []models.Smurl
+	for _, smurl := range s.smurls {
+		if strings.Contains(smurl.SmallURL+" "+smurl.LongURL, filter.Query) && (filter.Deleted || !smurl.Deleted()) {
+			result = append(result, smurl)
+		}
+	}
+	if filter.Offset >= len(result) {
+		return nil, nil
+	}
+	result = result[filter.Offset:]
+	if len(result) > filter.Limit {
+		result = result[:filter.Limit]
+	}
+	return result, nil
+}
+
+func (s *fakeStore) ReadClickStats(ctx context.Context, smallUrl string) (*models.ClickStats, error) {
+	return &models.ClickStats{Browsers: []models.StatItem{{Name: "Firefox", Count: 4}}}, nil
+}
+
+func (s *fakeStore) SetDisabled(ctx context.Context, smallUrl string, disabled bool) error {
+	smurl := s.find(smallUrl)
+	if smurl == nil {
+		return models.ErrNotFound
+	}
+	smurl.DisabledAt = time.Time{}
+	if disabled {
+		smurl.DisabledAt = time.Now()
+	}
+	return nil
+}
+
+func (s *fakeStore) Delete(ctx context.Context, smallUrl string) error {
+	smurl := s.find(smallUrl)
+	if smurl == nil || smurl.Deleted() {
+		return models.ErrNotFound
+	}
+	smurl.DeletedAt = time.Now()
+	return nil
+}
+
+func (s *fakeStore) CreateAPIKey(ctx context.Context, key models.APIKey) (*models.APIKey, error) {
+	key.ID = int64(len(s.keys) + 1)
+	s.keys = append(s.keys, key)
+	return &key, nil
+}
+
+func (s *fakeStore) ListAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	return s.keys, nil
+}
+
+func (s *fakeStore) RevokeAPIKey(ctx context.Context, id int64) error {
+	for i := range s.keys {
+		if s.keys[i].ID == id && !s.keys[i].Revoked() {
+			s.keys[i].RevokedAt = time.Now()
+			return nil
+		}
+	}
+	return models.ErrNotFound
+}
+
+func (s *fakeStore) ExportLinks(ctx context.Context, fn func(models.Smurl) error) error {
+	for _, smurl := range s.smurls {
+		if err := fn(smurl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *fakeStore) ImportLink(ctx context.Context, smurl models.Smurl) (*models.Smurl, error) {
+	if existing := s.find(smurl.SmallURL); existing != nil {
+		found := *existing
+		return &found, models.ErrConflict
+	}
+	s.smurls = append(s.smurls, smurl)
+	return nil, nil
+}
+
+func (s *fakeStore) CreateWebhook(ctx context.Context, webhook models.Webhook) (*models.Webhook, error) {
+	webhook.ID = int64(len(s.webhooks) + 1)
+	s.webhooks = append(s.webhooks, webhook)
+	return &webhook, nil
+}
+
+func (s *fakeStore) ListWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+	for _, webhook := range s.webhooks {
+		if webhook.DeletedAt.IsZero() {
+			webhooks = append(webhooks, webhook)
+		}
+	}
+	return webhooks, nil
+}
+
+func (s *fakeStore) DeleteWebhook(ctx context.Context, id int64) error {
+	for i := range s.webhooks {
+		if s.webhooks[i].ID == id && s.webhooks[i].DeletedAt.IsZero() {
+			s.webhooks[i].DeletedAt = time.Now()
+			return nil
+		}
+	}
+	return models.ErrNotFound
+}
+
+func (s *fakeStore) ListAttempts(ctx context.Context, webhookID int64, limit int) ([]models.WebhookAttempt, error) {
+	var attempts []models.WebhookAttempt
+	for _, attempt := range s.attempts {
+		if (webhookID == 0 || attempt.WebhookID == webhookID) && len(attempts) < limit {
+			attempts = append(attempts, attempt)
+		}
+	}
+	return attempts, nil
+}
+
+type TestStatement struct {
+	store   *fakeStore
+	usecase *mocks.MockUsecase
+	helpers *helpers.MockHelper
+	policy  *repository.MockDomainPolicy
+	out     *bytes.Buffer
+	ctl     *Ctl
+}
+
+func NewTestStatement(ctrl *gomock.Controller) *TestStatement {
+	store := &fakeStore{smurls: []models.Smurl{
+		{SmallURL: "abc", LongURL: "http://mail.ru", Count: 7, Uniques: 5, CreatedAt: created, ModifiedAt: created},
+		{SmallURL: "def", LongURL: "http://vk.com", CreatedAt: created, ModifiedAt: created, DeletedAt: created},
+	}}
+	usecase := mocks.NewMockUsecase(ctrl)
+	helpers := helpers.NewMockHelper(ctrl)
+	policy := repository.NewMockDomainPolicy(ctrl)
+	out := &bytes.Buffer{}
+	return &TestStatement{
+		store:   store,
+		usecase: usecase,
+		helpers: helpers,
+		policy:  policy,
+		out:     out,
+		ctl: &Ctl{
+			store:   store,
+			usecase: usecase,
+			helpers: helpers,
+			policy:  policy,
+			url:     "http://sm.url/",
+			out:     out,
+		},
+	}
+}
+
+func TestCreate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(ctrl)
+
+	s.helpers.EXPECT().CheckURL("http://mail.ru").Return("http://mail.ru", nil)
+	s.usecase.EXPECT().Create(ctx, "http://mail.ru", models.PassthroughKeep).Return(&models.Smurl{
+		SmallURL: "new", AdminURL: "token", LongURL: "http://mail.ru",
+	}, nil)
+	require.NoError(t, s.ctl.Run(ctx, []string{"create", "-passthrough", "keep", "http://mail.ru"}))
+	require.Contains(t, s.out.String(), "http://sm.url/r/new")
+	require.Contains(t, s.out.String(), "http://sm.url/s/token")
+
+	require.ErrorIs(t, s.ctl.Run(ctx, []string{"create"}), errUsage)
+}
+
+func TestGet(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(ctrl)
+
+	require.NoError(t, s.ctl.Run(ctx, []string{"get", "abc"}))
+	require.Contains(t, s.out.String(), "http://mail.ru")
+	require.Contains(t, s.out.String(), "active")
+
+	s.out.Reset()
+	s.ctl.json = true
+	require.NoError(t, s.ctl.Run(ctx, []string{"get", "def"}))
+	var link Link
+	require.NoError(t, json.Unmarshal(s.out.Bytes(), &link))
+	require.Equal(t, "deleted", link.Status)
+	require.True(t, created.Equal(*link.DeletedAt))
+	require.Empty(t, link.AdminURL)
+
+	require.ErrorIs(t, s.ctl.Run(ctx, []string{"get", "nope"}), models.ErrNotFound)
+}
+
+func TestList(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(ctrl)
+
+	require.NoError(t, s.ctl.Run(ctx, []string{"list"}))
+	lines := strings.Split(strings.TrimSpace(s.out.String()), "\n")
+	require.Len(t, lines, 2)
+	require.True(t, strings.HasPrefix(lines[0], "SMALL URL"))
+	require.Contains(t, lines[1], "abc")
+	require.Equal(t, models.ListFilter{Limit: 50}, s.store.filters[0])
+
+	s.out.Reset()
+	s.ctl.json = true
+	require.NoError(t, s.ctl.Run(ctx, []string{"search", "-deleted", "-limit", "10", "vk"}))
+	var links []Link
+	require.NoError(t, json.Unmarshal(s.out.Bytes(), &links))
+	require.Len(t, links, 1)
+	require.Equal(t, "def", links[0].SmallURL)
+	require.Equal(t, models.ListFilter{Query: "vk", Deleted: true, Limit: 10}, s.store.filters[1])
+
+	require.ErrorIs(t, s.ctl.Run(ctx, []string{"search"}), errUsage)
+	require.ErrorIs(t, s.ctl.Run(ctx, []string{"list", "-limit", "x"}), errUsage)
+}
+
+func TestDisable(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(ctrl)
+
+	require.NoError(t, s.ctl.Run(ctx, []string{"disable", "abc"}))
+	require.Equal(t, "disabled abc\n", s.out.String())
+	require.True(t, s.store.find("abc").Disabled())
+	require.NoError(t, s.ctl.Run(ctx, []string{"enable", "abc"}))
+	require.False(t, s.store.find("abc").Disabled())
+
+	require.NoError(t, s.ctl.Run(ctx, []string{"delete", "abc"}))
+	require.True(t, s.store.find("abc").Deleted())
+	require.ErrorIs(t, s.ctl.Run(ctx, []string{"delete", "abc"}), models.ErrNotFound)
+}
+
+func TestExport(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(ctrl)
+
+	require.NoError(t, s.ctl.Run(ctx, []string{"export"}))
+	lines := strings.Split(strings.TrimSpace(s.out.String()), "\n")
+	require.Len(t, lines, 2)
+	var record transfer.Record
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &record))
+	// Deleted links are exported too
+	require.Equal(t, "def", record.SmallURL)
+	require.True(t, created.Equal(*record.DeletedAt))
+
+	s.out.Reset()
+	require.NoError(t, s.ctl.Run(ctx, []string{"export", "-format", "csv"}))
+	records, err := csv.NewReader(s.out).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	require.Equal(t, "small_url", records[0][0])
+	require.Equal(t, "abc", records[1][0])
+
+	require.ErrorIs(t, s.ctl.Run(ctx, []string{"export", "-format", "xml"}), errUsage)
+}
+
+func TestImport(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(ctrl)
+
+	hash := helper.HashToken("token")
+	s.helpers.EXPECT().CheckURL(gomock.Any()).DoAndReturn(func(longURL string) (string, error) {
+		return longURL, nil
+	}).AnyTimes()
+	s.policy.EXPECT().Check("ya.ru").Return(nil).AnyTimes()
+	s.policy.EXPECT().Check("mail.ru").Return(nil)
+	s.policy.EXPECT().Check("denied.ru").Return(models.ErrBlocked)
+	s.ctl.in = strings.NewReader(fmt.Sprintf(`{"small_url":"abc","admin_hash":"%[1]s","long_url":"http://mail.ru"}
+{"small_url":"new","admin_hash":"%[1]s","long_url":"http://ya.ru"}
+{"small_url":"bad","admin_hash":"%[1]s","long_url":"http://denied.ru"}
+`, hash))
+	err := s.ctl.Run(ctx, []string{"import"})
+	require.EqualError(t, err, "2 links were not imported")
+	require.Contains(t, s.out.String(), "created 1, unchanged 0, conflicts 1, invalid 1")
+	require.Nil(t, s.store.find("bad"))
+	require.Contains(t, s.out.String(), "conflict: the small url exists")
+	require.Equal(t, "http://ya.ru", s.store.find("new").LongURL)
+
+	// The format follows the extension of the file
+	name := filepath.Join(t.TempDir(), "links.csv")
+	require.NoError(t, os.WriteFile(name, []byte("small_url,admin_hash,long_url\nnew,"+hash+",http://ya.ru\n"), 0o600))
+	s.out.Reset()
+	s.ctl.json = true
+	require.NoError(t, s.ctl.Run(ctx, []string{"import", name}))
+	var report transfer.Report
+	require.NoError(t, json.Unmarshal(s.out.Bytes(), &report))
+	require.Equal(t, 1, report.Unchanged)
+
+	require.ErrorIs(t, s.ctl.Run(ctx, []string{"import", "-format", "xml"}), errUsage)
+	require.ErrorIs(t, s.ctl.Run(ctx, []string{"import", "a", "b"}), errUsage)
+}
+
+func TestImportFrom(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(ctrl)
+	s.ctl.adminCodes = codegen.NewRandom("abcdef", 16)
+	s.helpers.EXPECT().CheckURL("http://ya.ru").Return("http://ya.ru", nil)
+	s.policy.EXPECT().Check("ya.ru").Return(nil)
+
+	s.ctl.in = strings.NewReader(`{"shortUrls": {"data": [{"shortCode": "new", "longUrl": "http://ya.ru", "visitsCount": 12}]}}`)
+	require.NoError(t, s.ctl.Run(ctx, []string{"import", "-from", "shlink"}))
+	require.Contains(t, s.out.String(), "created 1, unchanged 0, conflicts 0, invalid 0")
+	require.Contains(t, s.out.String(), "http://sm.url/s/")
+	require.Equal(t, uint64(12), s.store.find("new").ImportedClicks)
+
+	s.out.Reset()
+	require.NoError(t, s.ctl.Run(ctx, []string{"get", "new"}))
+	require.Contains(t, s.out.String(), "imported clicks:")
+
+	require.ErrorIs(t, s.ctl.Run(ctx, []string{"import", "-from", "bitly"}), errUsage)
+}
+
+func TestStats(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(ctrl)
+
+	require.NoError(t, s.ctl.Run(ctx, []string{"stats", "abc"}))
+	require.Contains(t, s.out.String(), "BROWSER")
+	require.Contains(t, s.out.String(), "Firefox")
+	require.NotContains(t, s.out.String(), "COUNTRY")
+
+	s.out.Reset()
+	s.ctl.json = true
+	require.NoError(t, s.ctl.Run(ctx, []string{"stats", "abc"}))
+	var stats Stats
+	require.NoError(t, json.Unmarshal(s.out.Bytes(), &stats))
+	require.Equal(t, uint64(7), stats.Count)
+	require.Equal(t, []models.StatItem{{Name: "Firefox", Count: 4}}, stats.Browsers)
+}
+
+func TestKeys(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(ctrl)
+	s.ctl.json = true
+
+	require.NoError(t, s.ctl.Run(ctx, []string{"keys", "create", "push"}))
+	var key APIKey
+	require.NoError(t, json.Unmarshal(s.out.Bytes(), &key))
+	require.Equal(t, int64(1), key.ID)
+	require.NotEmpty(t, key.Key)
+	// Only the hash of the key is stored
+	require.Equal(t, helper.HashToken(key.Key), s.store.keys[0].Hash)
+
+	s.out.Reset()
+	require.NoError(t, s.ctl.Run(ctx, []string{"keys", "revoke", "1"}))
+	require.True(t, s.store.keys[0].Revoked())
+
+	s.out.Reset()
+	require.NoError(t, s.ctl.Run(ctx, []string{"keys", "list"}))
+	var keys []APIKey
+	require.NoError(t, json.Unmarshal(s.out.Bytes(), &keys))
+	require.Len(t, keys, 1)
+	require.Empty(t, keys[0].Key)
+	require.NotNil(t, keys[0].RevokedAt)
+
+	require.ErrorIs(t, s.ctl.Run(ctx, []string{"keys", "revoke", "x"}), errUsage)
+	require.ErrorIs(t, s.ctl.Run(ctx, []string{"keys"}), errUsage)
+	require.ErrorIs(t, s.ctl.Run(ctx, []string{"unknown"}), errUsage)
+}
+
+func TestWebhooks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(ctrl)
+	s.ctl.json = true
+
+	require.NoError(t, s.ctl.Run(ctx, []string{"webhooks", "add", "-key", "3", "-events", "link.created,link.milestone",
+		"https://hooks.example.com/smurl"}))
+	var webhook Webhook
+	require.NoError(t, json.Unmarshal(s.out.Bytes(), &webhook))
+	require.Equal(t, int64(1), webhook.ID)
+	require.Equal(t, int64(3), webhook.KeyID)
+	require.Equal(t, []models.EventType{models.EventCreated, models.EventMilestone}, webhook.Events)
+	require.Equal(t, "whsec_", webhook.Secret[:len("whsec_")])
+	require.Equal(t, webhook.Secret, s.store.webhooks[0].Secret)
+
+	// The secret is not listed
+	s.out.Reset()
+	require.NoError(t, s.ctl.Run(ctx, []string{"webhooks", "list"}))
+	var webhooks []Webhook
+	require.NoError(t, json.Unmarshal(s.out.Bytes(), &webhooks))
+	require.Len(t, webhooks, 1)
+	require.Empty(t, webhooks[0].Secret)
+
+	s.store.attempts = []models.WebhookAttempt{
+		{DeliveryID: 4, WebhookID: 1, EventType: models.EventCreated, SmallURL: "abc", Attempt: 2, At: created,
+			StatusCode: 500, Error: "unexpected status 500", Duration: 1500 * time.Millisecond},
+		{DeliveryID: 5, WebhookID: 2, EventType: models.EventDeleted, SmallURL: "def", Attempt: 1, At: created},
+	}
+	s.ctl.json = false
+	s.out.Reset()
+	require.NoError(t, s.ctl.Run(ctx, []string{"webhooks", "log", "-webhook", "1"}))
+	require.Contains(t, s.out.String(), "unexpected status 500")
+	require.NotContains(t, s.out.String(), "def")
+
+	s.out.Reset()
+	require.NoError(t, s.ctl.Run(ctx, []string{"webhooks", "remove", "1"}))
+	require.Equal(t, "removed webhook 1\n", s.out.String())
+	require.ErrorIs(t, s.ctl.Run(ctx, []string{"webhooks", "remove", "1"}), models.ErrNotFound)
+
+	require.ErrorIs(t, s.ctl.Run(ctx, []string{"webhooks", "add", "-events", "link.visited", "https://a.b"}), errUsage)
+	require.ErrorIs(t, s.ctl.Run(ctx, []string{"webhooks", "add", "ftp://a.b"}), errUsage)
+	require.ErrorIs(t, s.ctl.Run(ctx, []string{"webhooks"}), errUsage)
+}
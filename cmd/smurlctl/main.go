@@ -0,0 +1,139 @@
+// Command smurlctl manages the links and the API keys of smurl
+// in its database, with the configuration of the service
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/sanyarise/smurl/config"
+	"github.com/sanyarise/smurl/internal/helpers"
+	"github.com/sanyarise/smurl/internal/helpers/codegen"
+	"github.com/sanyarise/smurl/internal/infrastructure/clicksink"
+	"github.com/sanyarise/smurl/internal/infrastructure/domainlist"
+	"github.com/sanyarise/smurl/internal/infrastructure/geoip"
+	"github.com/sanyarise/smurl/internal/infrastructure/logger"
+	"github.com/sanyarise/smurl/internal/repository"
+	"github.com/sanyarise/smurl/internal/usecase"
+	"go.uber.org/zap"
+)
+
+const usage = `Usage: smurlctl [-config-path file] [-json] [-v] command [arguments]
+
+Commands:
+  create [-passthrough keep|override] long_url   create a link
+  get small_url                                  show a link
+  list [-limit n] [-offset n] [-deleted]         list the newest links
+  search [-limit n] [-offset n] [-deleted] text  list the links whose small or long url contains text
+  disable small_url                              stop a link from redirecting, e.g. for abuse
+  enable small_url                               let a disabled link redirect again
+  delete small_url                               delete a link
+  export [-format jsonl|csv]                     write all links with their counters, rules, variants and click chart
+  import [-format jsonl|csv] [file]              restore exported links, skipping those already imported
+  import -from csv|yourls|shlink [file]          import the links of another shortener with their codes and clicks
+  stats small_url                                show the counters and the breakdowns of the clicks
+  keys create name                               create a gRPC API key, shown only once
+  keys list                                      list the API keys
+  keys revoke id                                 revoke an API key
+  webhooks add [-key id] [-events list] url      subscribe a url to link events, the secret is shown only once
+  webhooks list                                  list the webhooks
+  webhooks remove id                             remove a webhook, its pending deliveries fail
+  webhooks log [-webhook id] [-limit n]          show the newest delivery attempts
+
+Flags:
+`
+
+func main() {
+	os.Exit(run())
+}
+
+// run runs the command and returns the exit status
+func run() int {
+	jsonOutput := flag.Bool("json", false, "print JSON instead of tables")
+	verbose := flag.Bool("v", false, "log at the configured level")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	// Parses the flags above with the configuration path, without
+	// logging the configuration
+	cfg := config.Load()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		return 2
+	}
+
+	// Logs would mix with the output, they are only written with -v
+	l := zap.NewNop()
+	if *verbose {
+		l = logger.NewLogger(cfg.LogLevel).Logger
+	}
+	l.Debug("Configuration loaded", zap.Any("config", cfg.Redacted()))
+
+	repository, err := repository.NewSmurlRepository(cfg.DNS, l)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "smurlctl: %s\n", err)
+		return 1
+	}
+	defer repository.Close()
+
+	// The same admin tokens as the service
+	adminCodes, _, err := codegen.NewAdminGenerator(cfg.CodeAlphabet, cfg.AdminCodeLength)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "smurlctl: %s\n", err)
+		return 1
+	}
+	domainList, err := domainlist.NewDomainList(cfg.DomainListPath, l)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "smurlctl: %s\n", err)
+		return 1
+	}
+	usecase, err := newUsecase(cfg, repository, domainList, adminCodes, l)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "smurlctl: %s\n", err)
+		return 1
+	}
+	ctl := &Ctl{
+		store:      repository,
+		usecase:    usecase,
+		policy:     domainList,
+		adminCodes: adminCodes,
+		helpers:    helpers.NewHelpers(l, cfg.ServerURL, cfg.AllowedSchemes, cfg.VisitorSecret, cfg.IPMode, cfg.IPHashKey),
+		url:        cfg.ServerURL,
+		json:       *jsonOutput,
+		in:         os.Stdin,
+		out:        os.Stdout,
+	}
+	err = ctl.Run(context.Background(), flag.Args())
+	if errors.Is(err, errUsage) {
+		fmt.Fprintf(os.Stderr, "smurlctl: %s\n", err)
+		flag.Usage()
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "smurlctl: %s\n", err)
+		return 1
+	}
+	return 0
+}
+
+// newUsecase creates the usecase of the service for creating links
+// with the same checks and code generators
+func newUsecase(cfg *config.Config, repository *repository.SmurlRepository, domainList *domainlist.DomainList,
+	adminCodes codegen.CodeGenerator, logger *zap.Logger) (*usecase.SmurlUsecase, error) {
+	geo, err := geoip.NewGeoIP("", logger)
+	if err != nil {
+		return nil, err
+	}
+	helpers := helpers.NewHelpers(logger, cfg.ServerURL, cfg.AllowedSchemes, cfg.VisitorSecret, cfg.IPMode, cfg.IPHashKey)
+	codes, err := codegen.New(cfg.CodeStrategy, cfg.CodeAlphabet, cfg.CodeLength, cfg.CodeSalt, cfg.CodeNode, repository)
+	if err != nil {
+		return nil, err
+	}
+	// smurlctl makes no redirects, there are no clicks to stream
+	clicks := clicksink.NewStream(nil, 0, logger)
+	return usecase.NewSmurlUsecase(repository, domainList, geo, clicks, helpers, codes, adminCodes, logger), nil
+}
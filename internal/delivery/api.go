@@ -0,0 +1,75 @@
+package delivery
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/sanyarise/smurl/internal/models"
+)
+
+// Default time ranges of a time series when the from parameter is omitted
+var defaultRanges = map[models.Granularity]time.Duration{
+	models.Hourly: 48 * time.Hour,
+	models.Daily:  30 * 24 * time.Hour,
+}
+
+// Time series response object structure
+type TimeSeries struct {
+	Granularity models.Granularity  `json:"granularity"`
+	From        time.Time           `json:"from"`
+	To          time.Time           `json:"to"`
+	Buckets     []models.TimeBucket `json:"buckets"`
+}
+
+func (TimeSeries) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// TimeSeries returns clicks aggregated into hourly or daily buckets as JSON.
+// Query parameters: granularity (hour or day, default day), from and to
+// in RFC 3339 format (default the last 48 hours or 30 days up to now)
+func (router *Router) TimeSeries(w http.ResponseWriter, r *http.Request) {
+	router.logger.Debug("Enter in delivery TimeSeries()")
+	adminURL := chi.URLParam(r, "adminUrl")
+	query := r.URL.Query()
+
+	granularity := models.Granularity(query.Get("granularity"))
+	if granularity == "" {
+		granularity = models.Daily
+	}
+	to := time.Now()
+	if value := query.Get("to"); value != "" {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			render.Render(w, r, ErrInvalidRequest(fmt.Errorf("incorrect to: %w", err)))
+			return
+		}
+		to = t
+	}
+	from := to.Add(-defaultRanges[granularity])
+	if value := query.Get("from"); value != "" {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			render.Render(w, r, ErrInvalidRequest(fmt.Errorf("incorrect from: %w", err)))
+			return
+		}
+		from = t
+	}
+
+	buckets, err := router.usecase.TimeSeries(context.Background(), adminURL, granularity, from, to)
+	if err != nil {
+		router.apiError(w, r, err)
+		return
+	}
+	series := TimeSeries{
+		Granularity: granularity,
+		From:        granularity.Truncate(from),
+		To:          granularity.Truncate(to),
+		Buckets:     buckets,
+	}
+	render.Render(w, r, series)
+}
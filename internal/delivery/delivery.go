@@ -4,11 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"html/template"
 	"net/http"
+	"strconv"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
+	"github.com/sanyarise/smurl/internal/helpers"
 	"github.com/sanyarise/smurl/internal/models"
 	"go.uber.org/zap"
 )
@@ -16,14 +17,54 @@ import (
 const (
 	status200 = http.StatusOK
 	status201 = http.StatusCreated
-	status400 = http.StatusBadRequest
 	status500 = http.StatusInternalServerError
-	page200   = "./static/result.tmpl"
-	pageStat  = "./static/statistics.tmpl"
-	page400   = "./static/400.tmpl"
-	page500   = "./static/500.tmpl"
+	pageHome  = "home.tmpl"
+	page200   = "result.tmpl"
+	pageStat  = "statistics.tmpl"
+	pageDocs  = "docs.tmpl"
+	page400   = "400.tmpl"
+	page403   = "403.tmpl"
+	page404   = "404.tmpl"
+	page405   = "405.tmpl"
+	page409   = "409.tmpl"
+	page410   = "410.tmpl"
+	page429   = "429.tmpl"
+	page500   = "500.tmpl"
 )
 
+// Error pages of the status codes
+var statusPages = map[int]string{
+	http.StatusBadRequest:          page400,
+	http.StatusForbidden:           page403,
+	http.StatusNotFound:            page404,
+	http.StatusMethodNotAllowed:    page405,
+	http.StatusConflict:            page409,
+	http.StatusGone:                page410,
+	http.StatusTooManyRequests:     page429,
+	http.StatusInternalServerError: page500,
+}
+
+// Reason shown when the destination domain is not allowed
+const reasonBlocked = "the destination domain is blocked"
+
+// Reason shown when the link was disabled by an operator
+const reasonDisabled = "the link was disabled"
+
+// Lifetime in seconds of the cookie keeping the variant of a visitor
+const variantCookieAge = 30 * 24 * 60 * 60
+
+// variantCookie returns the name of the cookie keeping
+// the variant of a small url the visitor got
+func variantCookie(smallUrl string) string {
+	return "smurl_variant_" + smallUrl
+}
+
+// Data for rendering error pages
+type ErrorData struct {
+	Page
+	Reason string
+}
+
 type Smurl struct {
 	CreatedAt  string
 	ModifiedAt string
@@ -32,7 +73,29 @@ type Smurl struct {
 	AdminURL   string
 	IPInfo     []string
 	Count      string
-	URL        string
+	Uniques    string
+	BotCount   string
+	// Empty unless the small url was imported with clicks
+	ImportedClicks string
+	Stats          models.ClickStats
+	StatsAPI       string
+	ForgetURL      string
+	Rules          []models.Rule
+	RulesURL       string
+	Platforms      []string
+	Variants       []Variant
+	VariantsURL    string
+	Sticky         bool
+	Passthrough    string
+	PassthroughURL string
+	Page
+}
+
+// Variant with its share of the traffic for the statistics page
+type Variant struct {
+	models.Variant
+	// Percentage of the total weight
+	Share string
 }
 
 // Get method displaying the start page
@@ -40,24 +103,11 @@ func (router *Router) HomePage(w http.ResponseWriter, r *http.Request) {
 	router.logger.Debug("Enter in delivery HomePage()")
 	w.WriteHeader(http.StatusOK)
 
-	tmpl, err := template.ParseFiles("./static/home.tmpl")
-	if err != nil {
-		router.logger.Error(fmt.Sprintf("error on template parse home page files:%s", err))
-		w.WriteHeader(http.StatusInternalServerError)
-		err = router.ErrorPage(w, page500, status500)
-		if err != nil {
-			router.logger.Error(fmt.Sprintf("error on error page func: %s", err))
-
-			render.Render(w, r, ErrRender(err))
-		}
-		return
-	}
-
-	err = tmpl.Execute(w, nil)
+	err := router.templates[pageHome].ExecuteTemplate(w, pageHome, router.page(r))
 
 	if err != nil {
 		router.logger.Error(fmt.Sprintf("error on execute home page files: %s", err))
-		err = router.ErrorPage(w, page500, status500)
+		err = router.ErrorPage(w, r, page500, status500, "")
 		if err != nil {
 			router.logger.Error(fmt.Sprintf("error on execute home page files: %s", err))
 			render.Render(w, r, ErrRender(err))
@@ -66,46 +116,65 @@ func (router *Router) HomePage(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// NotFound displays the error page for unknown paths
+func (router *Router) NotFound(w http.ResponseWriter, r *http.Request) {
+	router.Error(w, r, models.ErrNotFound)
+}
+
+// MethodNotAllowed displays the error page for known paths
+// requested with a method they do not serve
+func (router *Router) MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	router.Error(w, r, errMethodNotAllowed)
+}
+
 // PostCreate creating a minified url
 func (router *Router) Create(w http.ResponseWriter, r *http.Request) {
 	router.logger.Debug("Enter in delivery Create()")
-	// Reading the long address from the request body
-	longURL := r.FormValue("long_url")
-	router.logger.Debug(fmt.Sprintf("LongURL: %s", longURL))
-	// Checking the validity of a long address
-	ok := router.helpers.CheckURL(longURL)
-	if !ok {
-		router.logger.Error("incorrect long url")
-		err := router.ErrorPage(w, page400, status400)
-		if err != nil {
-			router.logger.Error(err.Error())
-			render.Render(w, r, ErrInvalidRequest(fmt.Errorf("incorrect long url")))
-		}
-		return
+	// Reading the long address and the optional UTM tags and passthrough mode
+	utm := helpers.UTM{
+		Source:   r.FormValue("utm_source"),
+		Medium:   r.FormValue("utm_medium"),
+		Campaign: r.FormValue("utm_campaign"),
+		Term:     r.FormValue("utm_term"),
+		Content:  r.FormValue("utm_content"),
 	}
-	router.logger.Debug("URL check sucess")
-
-	// Calling usecase method to create a reduced url
-	newSmurl, err := router.usecase.Create(context.Background(), longURL)
+	passthrough := models.Passthrough(r.FormValue("passthrough"))
+	newSmurl, err := router.create(context.Background(), r.FormValue("long_url"), utm, passthrough)
 	if err != nil {
-		router.logger.Error(fmt.Sprintf("create smurl error %s: ", err))
-		err := router.ErrorPage(w, page500, status500)
-		if err != nil {
-			router.logger.Error(err.Error())
-			render.Render(w, r, ErrRender(err))
-		}
+		router.Error(w, r, err)
 		return
 	}
 	router.logger.Debug("Create smurl success")
 
 	// Call the function to render the page with the result
-	err = router.ResultPage(w, page200, newSmurl, status201)
+	err = router.ResultPage(w, r, page200, newSmurl, status201)
 	if err != nil {
 		router.logger.Error("",
 			zap.Error(err))
 	}
 }
 
+// create adds the UTM tags to the long url, checks it
+// and creates the small url, for the form and the JSON API
+func (router *Router) create(ctx context.Context, longURL string, utm helpers.UTM, passthrough models.Passthrough) (*models.Smurl, error) {
+	router.logger.Debug(fmt.Sprintf("LongURL: %s", longURL))
+	longURL = helpers.AddUTM(longURL, utm)
+	// Checking the validity of a long address
+	longURL, err := router.helpers.CheckURL(longURL)
+	if err != nil {
+		reason := "incorrect long url"
+		var urlErr *helpers.URLError
+		if errors.As(err, &urlErr) {
+			reason = string(urlErr.Reason)
+		}
+		return nil, &models.ValidationError{Reason: reason}
+	}
+	router.logger.Debug("URL check sucess")
+
+	// Calling usecase method to create a reduced url
+	return router.usecase.Create(ctx, longURL, passthrough)
+}
+
 // GetSmallUrl following the reduced url received from the query string
 func (router *Router) Redirect(w http.ResponseWriter, r *http.Request) {
 	router.logger.Debug("Enter in delivery Redirect()")
@@ -115,42 +184,47 @@ func (router *Router) Redirect(w http.ResponseWriter, r *http.Request) {
 	// Search for a small url in the database
 	smurl, err := router.usecase.FindURL(ctx, smallUrl)
 	if err != nil {
-		if errors.Is(err, models.ErrNotFound) {
-			router.logger.Debug(fmt.Sprintf("smallUrl %s is not exist", smallUrl))
-			err := router.ErrorPage(w, page400, status400)
-			if err != nil {
-				router.logger.Error(err.Error())
-				render.Render(w, r, ErrInvalidRequest(fmt.Errorf("incorrect small url")))
-				return
-			}
-		} else {
-			err := router.ErrorPage(w, page500, status500)
-			if err != nil {
-				router.logger.Error(err.Error())
-				render.Render(w, r, ErrRender(err))
-				return
-			}
-		}
+		router.Error(w, r, err)
 		return
 	}
 	// Getting information about IP
 	ip := router.helpers.GetIP(r)
+	// Getting information about the click
+	click := models.Click{
+		IP:             ip,
+		Referer:        r.Referer(),
+		UserAgent:      r.UserAgent(),
+		AcceptLanguage: r.Header.Get("Accept-Language"),
+		Bot:            router.helpers.IsBot(r),
+	}
+	// The variant the visitor got on a previous click
+	if cookie, err := r.Cookie(variantCookie(smallUrl)); err == nil {
+		click.Variant, _ = strconv.ParseInt(cookie.Value, 10, 64)
+	}
+	// Choose the destination by the routing rules and the variants
+	destination, variant := router.usecase.Route(*smurl, click)
+	destination = helpers.PassQuery(destination, r.URL.RawQuery, smurl.Passthrough)
+	click.Variant = variant
+	if smurl.Sticky && variant != 0 {
+		http.SetCookie(w, &http.Cookie{
+			Name:     variantCookie(smallUrl),
+			Value:    strconv.FormatInt(variant, 10),
+			Path:     "/",
+			MaxAge:   variantCookieAge,
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
 	// Call the handler to search for a small url,
 	// search for the corresponding long url, update
 	// statistics
-	smurl.IPInfo = append(smurl.IPInfo, ip)
-	err = router.usecase.UpdateStat(ctx, *smurl)
+	err = router.usecase.UpdateStat(ctx, *smurl, click)
 	if err != nil {
-		router.logger.Error(err.Error())
-		err = router.ErrorPage(w, page500, status500)
-		if err != nil {
-			router.logger.Error(err.Error())
-			render.Render(w, r, ErrRender(err))
-			return
-		}
+		router.Error(w, r, err)
+		return
 	}
-	// Redirect to the found long address
-	http.Redirect(w, r, smurl.LongURL, http.StatusTemporaryRedirect)
+	// Redirect to the chosen destination
+	http.Redirect(w, r, destination, http.StatusTemporaryRedirect)
 	router.logger.Info("Redirect on long url success")
 }
 
@@ -164,29 +238,14 @@ func (router *Router) GetStat(w http.ResponseWriter, r *http.Request) {
 	// Сall the handler to get statistics
 	smurl, err := router.usecase.ReadStat(context.Background(), adminURL)
 	if err != nil {
-		if errors.Is(err, models.ErrNotFound) {
-			router.logger.Debug(fmt.Sprintf("adminUrl %s is not exist", adminURL))
-			err := router.ErrorPage(w, page400, status400)
-			if err != nil {
-				router.logger.Error(err.Error())
-				render.Render(w, r, ErrInvalidRequest(fmt.Errorf("incorrect small url")))
-				return
-			}
-		} else {
-			err := router.ErrorPage(w, page500, status500)
-			if err != nil {
-				router.logger.Error(err.Error())
-				render.Render(w, r, ErrRender(err))
-				return
-			}
-		}
+		router.Error(w, r, err)
 		return
 	}
 	// Call the function to display the result
-	err = router.ResultPage(w, pageStat, smurl, status200)
+	err = router.ResultPage(w, r, pageStat, smurl, status200)
 	if err != nil {
 		router.logger.Error(fmt.Sprintf("create smurl error %s: ", err))
-		err := router.ErrorPage(w, page500, status500)
+		err := router.ErrorPage(w, r, page500, status500, "")
 		if err != nil {
 			router.logger.Error(err.Error())
 			render.Render(w, r, ErrRender(err))
@@ -195,64 +254,235 @@ func (router *Router) GetStat(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// ForgetVisitors deletes the visitor data of a link and returns to its statistics
+func (router *Router) ForgetVisitors(w http.ResponseWriter, r *http.Request) {
+	router.logger.Debug("Enter in delivery ForgetVisitors()")
+	adminURL := chi.URLParam(r, "adminUrl")
+
+	err := router.usecase.ForgetVisitors(context.Background(), adminURL)
+	if err != nil {
+		router.Error(w, r, err)
+		return
+	}
+	http.Redirect(w, r, router.url+"s/"+adminURL, http.StatusSeeOther)
+}
+
+// AddRule adds a routing rule from the statistics page form
+func (router *Router) AddRule(w http.ResponseWriter, r *http.Request) {
+	router.logger.Debug("Enter in delivery AddRule()")
+	adminURL := chi.URLParam(r, "adminUrl")
+	rule := models.Rule{
+		Platform: r.FormValue("platform"),
+		Country:  r.FormValue("country"),
+		Language: r.FormValue("language"),
+		URL:      r.FormValue("url"),
+	}
+	if position := r.FormValue("position"); position != "" {
+		var err error
+		rule.Position, err = strconv.Atoi(position)
+		if err != nil {
+			router.Error(w, r, &models.ValidationError{Reason: "the position must be a number"})
+			return
+		}
+	}
+	err := router.usecase.AddRule(context.Background(), adminURL, rule)
+	if err != nil {
+		router.Error(w, r, err)
+		return
+	}
+	http.Redirect(w, r, router.url+"s/"+adminURL, http.StatusSeeOther)
+}
+
+// DeleteRule deletes a routing rule from the statistics page
+func (router *Router) DeleteRule(w http.ResponseWriter, r *http.Request) {
+	router.logger.Debug("Enter in delivery DeleteRule()")
+	adminURL := chi.URLParam(r, "adminUrl")
+	id, err := strconv.ParseInt(chi.URLParam(r, "ruleID"), 10, 64)
+	if err != nil {
+		router.Error(w, r, models.ErrNotFound)
+		return
+	}
+	err = router.usecase.DeleteRule(context.Background(), adminURL, id)
+	if err != nil {
+		router.Error(w, r, err)
+		return
+	}
+	http.Redirect(w, r, router.url+"s/"+adminURL, http.StatusSeeOther)
+}
+
+// AddVariant adds a weighted destination from the statistics page form
+func (router *Router) AddVariant(w http.ResponseWriter, r *http.Request) {
+	router.logger.Debug("Enter in delivery AddVariant()")
+	adminURL := chi.URLParam(r, "adminUrl")
+	variant := models.Variant{
+		URL:    r.FormValue("url"),
+		Weight: 1,
+	}
+	if weight := r.FormValue("weight"); weight != "" {
+		var err error
+		variant.Weight, err = strconv.Atoi(weight)
+		if err != nil {
+			router.Error(w, r, &models.ValidationError{Reason: "the weight must be a number"})
+			return
+		}
+	}
+	err := router.usecase.AddVariant(context.Background(), adminURL, variant)
+	if err != nil {
+		router.Error(w, r, err)
+		return
+	}
+	http.Redirect(w, r, router.url+"s/"+adminURL, http.StatusSeeOther)
+}
+
+// DeleteVariant deletes a weighted destination from the statistics page
+func (router *Router) DeleteVariant(w http.ResponseWriter, r *http.Request) {
+	router.logger.Debug("Enter in delivery DeleteVariant()")
+	adminURL := chi.URLParam(r, "adminUrl")
+	id, err := strconv.ParseInt(chi.URLParam(r, "variantID"), 10, 64)
+	if err != nil {
+		router.Error(w, r, models.ErrNotFound)
+		return
+	}
+	err = router.usecase.DeleteVariant(context.Background(), adminURL, id)
+	if err != nil {
+		router.Error(w, r, err)
+		return
+	}
+	http.Redirect(w, r, router.url+"s/"+adminURL, http.StatusSeeOther)
+}
+
+// SetSticky switches whether visitors keep the variant they got first
+func (router *Router) SetSticky(w http.ResponseWriter, r *http.Request) {
+	router.logger.Debug("Enter in delivery SetSticky()")
+	adminURL := chi.URLParam(r, "adminUrl")
+	sticky := r.FormValue("sticky") == "on"
+	err := router.usecase.SetSticky(context.Background(), adminURL, sticky)
+	if err != nil {
+		router.Error(w, r, err)
+		return
+	}
+	http.Redirect(w, r, router.url+"s/"+adminURL, http.StatusSeeOther)
+}
+
+// SetPassthrough sets how the query of a click is forwarded to the destination
+func (router *Router) SetPassthrough(w http.ResponseWriter, r *http.Request) {
+	router.logger.Debug("Enter in delivery SetPassthrough()")
+	adminURL := chi.URLParam(r, "adminUrl")
+	passthrough := models.Passthrough(r.FormValue("passthrough"))
+	err := router.usecase.SetPassthrough(context.Background(), adminURL, passthrough)
+	if err != nil {
+		router.Error(w, r, err)
+		return
+	}
+	http.Redirect(w, r, router.url+"s/"+adminURL, http.StatusSeeOther)
+}
+
 // ResultPage display result page
-func (router *Router) ResultPage(w http.ResponseWriter, page string, smurl *models.Smurl, status int) error {
+func (router *Router) ResultPage(w http.ResponseWriter, r *http.Request, page string, smurl *models.Smurl, status int) error {
 	router.logger.Debug("Enter in delivery ResultPage()")
+	statsAPI := router.url + "api/stats/" + smurl.AdminURL + "/clicks"
+	forgetURL := router.url + "s/" + smurl.AdminURL + "/forget"
+	rulesURL := router.url + "s/" + smurl.AdminURL + "/rules"
+	variantsURL := router.url + "s/" + smurl.AdminURL + "/variants"
+	passthroughURL := router.url + "s/" + smurl.AdminURL + "/passthrough"
 	// Write the full address to the resulting structure
 	smurl.SmallURL = router.url + "r/" + smurl.SmallURL
 	smurl.AdminURL = router.url + "s/" + smurl.AdminURL
 	var outSmurl Smurl
+	outSmurl.Page = router.page(r)
+	lang := outSmurl.Lang
 	if status == status201 {
 		outSmurl.AdminURL = smurl.AdminURL
 		outSmurl.SmallURL = smurl.SmallURL
-		outSmurl.URL = router.url
 	} else if status == status200 {
 		outSmurl.AdminURL = smurl.AdminURL
 		outSmurl.SmallURL = smurl.SmallURL
-		outSmurl.CreatedAt = smurl.CreatedAt.String()
-		outSmurl.ModifiedAt = smurl.ModifiedAt.String()
+		outSmurl.CreatedAt = router.i18n.FormatDate(lang, smurl.CreatedAt)
+		outSmurl.ModifiedAt = router.i18n.FormatDate(lang, smurl.ModifiedAt)
 		outSmurl.LongURL = smurl.LongURL
 		outSmurl.Count = fmt.Sprint(smurl.Count)
+		outSmurl.Uniques = fmt.Sprint(smurl.Uniques)
+		outSmurl.BotCount = fmt.Sprint(smurl.BotCount)
+		if smurl.ImportedClicks > 0 {
+			outSmurl.ImportedClicks = fmt.Sprint(smurl.ImportedClicks)
+		}
 		outSmurl.IPInfo = smurl.IPInfo
-		outSmurl.URL = router.url
+		outSmurl.Stats = smurl.Stats
+		outSmurl.StatsAPI = statsAPI
+		outSmurl.ForgetURL = forgetURL
+		outSmurl.Rules = smurl.Rules
+		outSmurl.RulesURL = rulesURL
+		outSmurl.Platforms = helpers.Platforms()
+		outSmurl.Variants = variantShares(smurl.Variants)
+		outSmurl.VariantsURL = variantsURL
+		outSmurl.Sticky = smurl.Sticky
+		outSmurl.Passthrough = string(smurl.Passthrough)
+		outSmurl.PassthroughURL = passthroughURL
 	}
 	router.logger.Debug(fmt.Sprintf("smurlWithServerUrl: %v \n", outSmurl))
 	w.WriteHeader(status)
-	ts, err := template.ParseFiles(page)
+	err := router.templates[page].ExecuteTemplate(w, page, outSmurl)
 	if err != nil {
 		router.logger.Error(err.Error())
-		err = router.ErrorPage(w, page500, status500)
+		err = router.ErrorPage(w, r, page500, status500, "")
 		if err != nil {
 			router.logger.Error(err.Error())
 			return err
 		}
 		return nil
 	}
-	router.logger.Debug("Parse template success")
-	err = ts.Execute(w, outSmurl)
+	router.logger.Debug("Execute template success")
+	return nil
+}
+
+// variantShares computes the share of the traffic of each variant
+func variantShares(variants []models.Variant) []Variant {
+	total := 0
+	for _, variant := range variants {
+		total += variant.Weight
+	}
+	shares := make([]Variant, 0, len(variants))
+	for _, variant := range variants {
+		share := 0.0
+		if total > 0 {
+			share = float64(variant.Weight) * 100 / float64(total)
+		}
+		shares = append(shares, Variant{Variant: variant, Share: fmt.Sprintf("%.1f%%", share)})
+	}
+	return shares
+}
+
+// Error reports an error mapped by ErrResponseFor, as JSON when the
+// client accepts it and as the error page of the status otherwise
+func (router *Router) Error(w http.ResponseWriter, r *http.Request, err error) {
+	resp := ErrResponseFor(err)
+	if resp.HTTPStatusCode == status500 {
+		router.logger.Error("request failed",
+			zap.String("path", r.URL.Path),
+			zap.Error(err))
+	} else {
+		router.logger.Debug("request rejected",
+			zap.String("path", r.URL.Path),
+			zap.Int("status", resp.HTTPStatusCode),
+			zap.Error(err))
+	}
+	if render.GetAcceptedContentType(r) == render.ContentTypeJSON {
+		render.Render(w, r, resp)
+		return
+	}
+	err = router.ErrorPage(w, r, statusPages[resp.HTTPStatusCode], resp.HTTPStatusCode, resp.ErrorText)
 	if err != nil {
 		router.logger.Error(err.Error())
-		err = router.ErrorPage(w, page500, status500)
-		if err != nil {
-			router.logger.Error(err.Error())
-			return err
-		}
-		return nil
+		render.Render(w, r, resp)
 	}
-	router.logger.Debug("Execute template success")
-	return nil
 }
 
-// ErrorPage display the error page
-func (router *Router) ErrorPage(w http.ResponseWriter, page string, status int) error {
+// ErrorPage display the error page, reason is an optional
+// explanation shown to the user
+func (router *Router) ErrorPage(w http.ResponseWriter, r *http.Request, page string, status int, reason string) error {
 	router.logger.Debug("Enter in delivery ErrorPage()")
 	w.WriteHeader(status)
-	ts, err := template.ParseFiles(page)
-	if err != nil {
-		router.logger.Error(fmt.Sprintf("error on parse template file: %v", err))
-		return err
-	}
-	err = ts.Execute(w, router.url)
+	err := router.templates[page].ExecuteTemplate(w, page, ErrorData{Page: router.page(r), Reason: reason})
 	if err != nil {
 		router.logger.Error(fmt.Sprintf("error on execute template file: %v", err))
 		return err
@@ -3,16 +3,26 @@ package delivery
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/golang/mock/gomock"
+	helper "github.com/sanyarise/smurl/internal/helpers"
 	helpers "github.com/sanyarise/smurl/internal/helpers/mocks"
 	"github.com/sanyarise/smurl/internal/models"
 	"github.com/sanyarise/smurl/internal/usecase/mocks"
+	"github.com/sanyarise/smurl/static"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 )
@@ -39,13 +49,15 @@ var (
 		SmallURL: "test",
 		AdminURL: "test",
 		Count:    0,
-		IPInfo:   []string{"testIpInfo"},
 	}
 	testSmurlUpd2 = models.Smurl{
 		SmallURL: "test",
 		AdminURL: "test",
 		Count:    0,
-		IPInfo:   []string{"testIpInfo"},
+	}
+	testClick = models.Click{
+		IP:        "testIpInfo",
+		UserAgent: "Go-http-client/1.1",
 	}
 )
 
@@ -60,7 +72,7 @@ func NewTestStatement(ctrl *gomock.Controller) *TestStatement {
 	helpers := helpers.NewMockHelper(ctrl)
 	usecase := mocks.NewMockUsecase(ctrl)
 	logger := zap.L()
-	router := NewRouter(usecase, helpers, logger, "testUrl")
+	router, _ := NewRouter(usecase, helpers, logger, "testUrl", DefaultTheme(), static.Assets(""))
 	return &TestStatement{
 		helpers: helpers,
 		logger:  logger,
@@ -87,7 +99,7 @@ func TestCreate(t *testing.T) {
 
 	r := GetRequest(testLong, server.URL, "POST")
 	client := server.Client()
-	s.helpers.EXPECT().CheckURL(testLong).Return(false)
+	s.helpers.EXPECT().CheckURL(testLong).Return("", &helper.URLError{URL: testLong, Reason: helper.ReasonScheme})
 	resp, err := client.Do(r)
 	if err != nil {
 		t.Error(err)
@@ -104,8 +116,8 @@ func TestCreate2(t *testing.T) {
 
 	r := GetRequest(testLong, server.URL, "POST")
 	client := server.Client()
-	s.helpers.EXPECT().CheckURL(testLong).Return(true)
-	s.usecase.EXPECT().Create(ctx, testLong).Return(nil, err)
+	s.helpers.EXPECT().CheckURL(testLong).Return(testLong, nil)
+	s.usecase.EXPECT().Create(ctx, testLong, models.PassthroughOff).Return(nil, err)
 	resp, err := client.Do(r)
 	if err != nil {
 		t.Error(err)
@@ -122,8 +134,8 @@ func TestCreate3(t *testing.T) {
 
 	r := GetRequest(testLong, server.URL, "POST")
 	client := server.Client()
-	s.helpers.EXPECT().CheckURL(testLong).Return(true)
-	s.usecase.EXPECT().Create(ctx, testLong).Return(testSmurl, nil)
+	s.helpers.EXPECT().CheckURL(testLong).Return(testLong, nil)
+	s.usecase.EXPECT().Create(ctx, testLong, models.PassthroughOff).Return(testSmurl, nil)
 	resp, err := client.Do(r)
 	if err != nil {
 		t.Error(err)
@@ -132,6 +144,78 @@ func TestCreate3(t *testing.T) {
 	resp.Body.Close()
 }
 
+func TestCreateBlocked(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(ctrl)
+	server := httptest.NewServer(s.router)
+
+	r := GetRequest(testLong, server.URL, "POST")
+	client := server.Client()
+	s.helpers.EXPECT().CheckURL(testLong).Return(testLong, nil)
+	s.usecase.EXPECT().Create(ctx, testLong, models.PassthroughOff).Return(nil, models.ErrBlocked)
+	resp, err := client.Do(r)
+	if err != nil {
+		t.Error(err)
+	}
+	require.Equal(t, 403, resp.StatusCode)
+	resp.Body.Close()
+}
+
+func TestCreateUTM(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(ctrl)
+	server := httptest.NewServer(s.router)
+	client := server.Client()
+
+	form := url.Values{
+		"long_url":     {testLong + "/?utm_source=old"},
+		"utm_source":   {"newsletter"},
+		"utm_campaign": {"spring"},
+		"passthrough":  {"keep"},
+	}
+	tagged := testLong + "/?utm_source=newsletter&utm_campaign=spring"
+	s.helpers.EXPECT().CheckURL(tagged).Return(tagged, nil)
+	s.usecase.EXPECT().Create(ctx, tagged, models.PassthroughKeep).Return(testSmurl, nil)
+	resp, err := client.PostForm(server.URL+"/create", form)
+	require.NoError(t, err)
+	require.Equal(t, 201, resp.StatusCode)
+	resp.Body.Close()
+
+	form.Set("passthrough", "always")
+	s.helpers.EXPECT().CheckURL(tagged).Return(tagged, nil)
+	s.usecase.EXPECT().Create(ctx, tagged, models.Passthrough("always")).
+		Return(nil, &models.ValidationError{Reason: `unknown passthrough mode "always"`})
+	resp, err = client.PostForm(server.URL+"/create", form)
+	require.NoError(t, err)
+	require.Equal(t, 400, resp.StatusCode)
+	resp.Body.Close()
+}
+
+func TestRedirectPassthrough(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(ctrl)
+	server := httptest.NewServer(s.router)
+	client := server.Client()
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	smurl := &models.Smurl{SmallURL: "test", LongURL: "http://mail.ru/?a=1", Passthrough: models.PassthroughKeep}
+	s.usecase.EXPECT().FindURL(ctx, "testSmallUrl").Return(smurl, nil)
+	s.helpers.EXPECT().GetIP(gomock.Any()).Return("testIpInfo")
+	s.helpers.EXPECT().IsBot(gomock.Any()).Return(false)
+	s.usecase.EXPECT().Route(gomock.Any(), gomock.Any()).Return(smurl.LongURL, int64(0))
+	s.usecase.EXPECT().UpdateStat(ctx, gomock.Any(), gomock.Any()).Return(nil)
+	resp, err := client.Get(server.URL + "/r/testSmallUrl?a=2&gclid=x")
+	require.NoError(t, err)
+	require.Equal(t, 307, resp.StatusCode)
+	require.Equal(t, "http://mail.ru/?a=1&gclid=x", resp.Header.Get("Location"))
+	resp.Body.Close()
+}
+
 func TestRedirect(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -145,7 +229,7 @@ func TestRedirect(t *testing.T) {
 	if err != nil {
 		t.Error(err)
 	}
-	require.Equal(t, 400, resp.StatusCode)
+	require.Equal(t, 404, resp.StatusCode)
 	resp.Body.Close()
 }
 
@@ -166,19 +250,93 @@ func TestRedirect2(t *testing.T) {
 	resp.Body.Close()
 }
 
+func TestRedirectBlocked(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(ctrl)
+	server := httptest.NewServer(s.router)
+
+	r, _ := http.NewRequest("GET", server.URL+"/r/testSmallUrl", nil)
+	client := server.Client()
+	s.usecase.EXPECT().FindURL(ctx, "testSmallUrl").Return(nil, models.ErrBlocked)
+	resp, err := client.Do(r)
+	if err != nil {
+		t.Error(err)
+	}
+	require.Equal(t, 403, resp.StatusCode)
+	resp.Body.Close()
+}
+
+func TestRedirectBot(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(ctrl)
+	server := httptest.NewServer(s.router)
+
+	r, _ := http.NewRequest("HEAD", server.URL+"/r/testSmallUrl", nil)
+	r.Header.Set("User-Agent", "Slackbot-LinkExpanding 1.0")
+	client := server.Client()
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	s.usecase.EXPECT().FindURL(ctx, "testSmallUrl").Return(&models.Smurl{SmallURL: "test", LongURL: "http://mail.ru"}, nil)
+	s.helpers.EXPECT().GetIP(gomock.Any()).Return("testIpInfo")
+	s.helpers.EXPECT().IsBot(gomock.Any()).Return(true)
+	s.usecase.EXPECT().UpdateStat(ctx, models.Smurl{SmallURL: "test", LongURL: "http://mail.ru"}, models.Click{
+		IP:        "testIpInfo",
+		UserAgent: "Slackbot-LinkExpanding 1.0",
+		Bot:       true,
+	}).Return(nil)
+	s.usecase.EXPECT().Route(gomock.Any(), gomock.Any()).Return("http://mail.ru", int64(0))
+	resp, err := client.Do(r)
+	if err != nil {
+		t.Error(err)
+	}
+	require.Equal(t, 307, resp.StatusCode)
+	resp.Body.Close()
+}
+
+func TestRedirectRoute(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(ctrl)
+	server := httptest.NewServer(s.router)
+
+	r, _ := http.NewRequest("GET", server.URL+"/r/testSmallUrl", nil)
+	r.Header.Set("Accept-Language", "de")
+	client := server.Client()
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	smurl := models.Smurl{SmallURL: "test", LongURL: "http://mail.ru",
+		Rules: []models.Rule{{ID: 1, Language: "de", URL: "http://mail.de"}}}
+	click := models.Click{IP: "testIpInfo", UserAgent: "Go-http-client/1.1", AcceptLanguage: "de"}
+	s.usecase.EXPECT().FindURL(ctx, "testSmallUrl").Return(&smurl, nil)
+	s.helpers.EXPECT().GetIP(gomock.Any()).Return("testIpInfo")
+	s.helpers.EXPECT().IsBot(gomock.Any()).Return(false)
+	s.usecase.EXPECT().UpdateStat(ctx, smurl, click).Return(nil)
+	s.usecase.EXPECT().Route(smurl, click).Return("http://mail.de", int64(0))
+	resp, err := client.Do(r)
+	require.NoError(t, err)
+	require.Equal(t, 307, resp.StatusCode)
+	require.Equal(t, "http://mail.de", resp.Header.Get("Location"))
+	resp.Body.Close()
+}
+
 func TestRedirect3(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 	helpers := helpers.NewMockHelpers()
 	usecase := mocks.NewMockUsecase(ctrl)
 	logger := zap.L()
-	router := NewRouter(usecase, helpers, logger, "testUrl")
+	router, _ := NewRouter(usecase, helpers, logger, "testUrl", DefaultTheme(), static.Assets(""))
 	server := httptest.NewServer(router)
 
 	r, _ := http.NewRequest("GET", server.URL+"/r/testSmallUrl", nil)
 	client := server.Client()
 	usecase.EXPECT().FindURL(ctx, "testSmallUrl").Return(testSmurl2, nil)
-	usecase.EXPECT().UpdateStat(ctx, testSmurlUpd2).Return(err)
+	usecase.EXPECT().Route(gomock.Any(), gomock.Any()).Return(testSmurl2.LongURL, int64(0))
+	usecase.EXPECT().UpdateStat(ctx, testSmurlUpd2, testClick).Return(err)
 	resp, err := client.Do(r)
 	if err != nil {
 		t.Error(err)
@@ -193,13 +351,14 @@ func TestRedirect4(t *testing.T) {
 	helpers := helpers.NewMockHelpers()
 	usecase := mocks.NewMockUsecase(ctrl)
 	logger := zap.L()
-	router := NewRouter(usecase, helpers, logger, "testUrl")
+	router, _ := NewRouter(usecase, helpers, logger, "testUrl", DefaultTheme(), static.Assets(""))
 	server := httptest.NewServer(router)
 
 	r, _ := http.NewRequest("GET", server.URL+"/r/testSmallUrl", nil)
 	client := server.Client()
 	usecase.EXPECT().FindURL(ctx, "testSmallUrl").Return(testSmurlWithLongUrl, nil)
-	usecase.EXPECT().UpdateStat(ctx, *testSmurlUpd).Return(nil)
+	usecase.EXPECT().UpdateStat(ctx, *testSmurlUpd, testClick).Return(nil)
+	usecase.EXPECT().Route(gomock.Any(), gomock.Any()).Return(testSmurlWithLongUrl.LongURL, int64(0))
 	resp, err := client.Do(r)
 	if err != nil {
 		t.Error(err)
@@ -214,7 +373,7 @@ func TestGetStat(t *testing.T) {
 	helpers := helpers.NewMockHelpers()
 	usecase := mocks.NewMockUsecase(ctrl)
 	logger := zap.L()
-	router := NewRouter(usecase, helpers, logger, "testUrl")
+	router, _ := NewRouter(usecase, helpers, logger, "testUrl", DefaultTheme(), static.Assets(""))
 	server := httptest.NewServer(router)
 
 	r, _ := http.NewRequest("GET", server.URL+"/s/testAdminUrl", nil)
@@ -224,7 +383,7 @@ func TestGetStat(t *testing.T) {
 	if err != nil {
 		t.Error(err)
 	}
-	require.Equal(t, 400, resp.StatusCode)
+	require.Equal(t, 404, resp.StatusCode)
 	resp.Body.Close()
 }
 
@@ -234,7 +393,7 @@ func TestGetStat2(t *testing.T) {
 	helpers := helpers.NewMockHelpers()
 	usecase := mocks.NewMockUsecase(ctrl)
 	logger := zap.L()
-	router := NewRouter(usecase, helpers, logger, "testUrl")
+	router, _ := NewRouter(usecase, helpers, logger, "testUrl", DefaultTheme(), static.Assets(""))
 	server := httptest.NewServer(router)
 
 	r, _ := http.NewRequest("GET", server.URL+"/s/testAdminUrl", nil)
@@ -254,7 +413,7 @@ func TestGetStat3(t *testing.T) {
 	helpers := helpers.NewMockHelpers()
 	usecase := mocks.NewMockUsecase(ctrl)
 	logger := zap.L()
-	router := NewRouter(usecase, helpers, logger, "testUrl")
+	router, _ := NewRouter(usecase, helpers, logger, "testUrl", DefaultTheme(), static.Assets(""))
 	server := httptest.NewServer(router)
 
 	r, _ := http.NewRequest("GET", server.URL+"/s/testAdminUrl", nil)
@@ -267,3 +426,554 @@ func TestGetStat3(t *testing.T) {
 	require.Equal(t, 200, resp.StatusCode)
 	resp.Body.Close()
 }
+
+func TestTimeSeries(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(ctrl)
+	server := httptest.NewServer(s.router)
+	client := server.Client()
+
+	from := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)
+	buckets := []models.TimeBucket{{Start: from, Count: 3}, {Start: to, Count: 1}}
+	query := "?granularity=day&from=2023-01-01T00:00:00Z&to=2023-01-02T00:00:00Z"
+
+	s.usecase.EXPECT().TimeSeries(ctx, "testAdminUrl", models.Daily, from, to).Return(buckets, nil)
+	resp, err := client.Get(server.URL + "/api/stats/testAdminUrl/clicks" + query)
+	require.NoError(t, err)
+	require.Equal(t, 200, resp.StatusCode)
+	var series TimeSeries
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&series))
+	resp.Body.Close()
+	require.Equal(t, models.Daily, series.Granularity)
+	require.Len(t, series.Buckets, 2)
+	require.Equal(t, uint64(3), series.Buckets[0].Count)
+
+	resp, err = client.Get(server.URL + "/api/stats/testAdminUrl/clicks?from=yesterday")
+	require.NoError(t, err)
+	require.Equal(t, 400, resp.StatusCode)
+	resp.Body.Close()
+
+	s.usecase.EXPECT().TimeSeries(ctx, "testAdminUrl", models.Daily, from, to).Return(nil, models.ErrValidation)
+	resp, err = client.Get(server.URL + "/api/stats/testAdminUrl/clicks" + query)
+	require.NoError(t, err)
+	require.Equal(t, 400, resp.StatusCode)
+	resp.Body.Close()
+
+	s.usecase.EXPECT().TimeSeries(ctx, "testAdminUrl", models.Daily, from, to).Return(nil, models.ErrNotFound)
+	resp, err = client.Get(server.URL + "/api/stats/testAdminUrl/clicks" + query)
+	require.NoError(t, err)
+	require.Equal(t, 404, resp.StatusCode)
+	resp.Body.Close()
+}
+
+func TestForgetVisitors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(ctrl)
+	server := httptest.NewServer(s.router)
+	client := server.Client()
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	s.usecase.EXPECT().ForgetVisitors(ctx, "testAdminUrl").Return(nil)
+	resp, err := client.Post(server.URL+"/s/testAdminUrl/forget", "", nil)
+	require.NoError(t, err)
+	require.Equal(t, 303, resp.StatusCode)
+	require.True(t, strings.HasSuffix(resp.Header.Get("Location"), "testUrls/testAdminUrl"))
+	resp.Body.Close()
+
+	s.usecase.EXPECT().ForgetVisitors(ctx, "testAdminUrl").Return(models.ErrNotFound)
+	resp, err = client.Post(server.URL+"/s/testAdminUrl/forget", "", nil)
+	require.NoError(t, err)
+	require.Equal(t, 404, resp.StatusCode)
+	resp.Body.Close()
+
+	s.usecase.EXPECT().ForgetVisitors(ctx, "testAdminUrl").Return(errors.New("error"))
+	resp, err = client.Post(server.URL+"/s/testAdminUrl/forget", "", nil)
+	require.NoError(t, err)
+	require.Equal(t, 500, resp.StatusCode)
+	resp.Body.Close()
+}
+
+func TestRedirectVariant(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(ctrl)
+	server := httptest.NewServer(s.router)
+
+	r, _ := http.NewRequest("GET", server.URL+"/r/testSmallUrl", nil)
+	r.AddCookie(&http.Cookie{Name: "smurl_variant_testSmallUrl", Value: "3"})
+	client := server.Client()
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	smurl := models.Smurl{SmallURL: "test", LongURL: "http://mail.ru", Sticky: true,
+		Variants: []models.Variant{{ID: 3, URL: "http://mail.ru/b", Weight: 1}}}
+	click := models.Click{IP: "testIpInfo", UserAgent: "Go-http-client/1.1"}
+	s.usecase.EXPECT().FindURL(ctx, "testSmallUrl").Return(&smurl, nil)
+	s.helpers.EXPECT().GetIP(gomock.Any()).Return("testIpInfo")
+	s.helpers.EXPECT().IsBot(gomock.Any()).Return(false)
+	click.Variant = 3
+	s.usecase.EXPECT().Route(smurl, click).Return("http://mail.ru/b", int64(3))
+	s.usecase.EXPECT().UpdateStat(ctx, smurl, click).Return(nil)
+	resp, err := client.Do(r)
+	require.NoError(t, err)
+	require.Equal(t, 307, resp.StatusCode)
+	require.Equal(t, "http://mail.ru/b", resp.Header.Get("Location"))
+	cookies := resp.Cookies()
+	require.Len(t, cookies, 1)
+	require.Equal(t, "smurl_variant_testSmallUrl", cookies[0].Name)
+	require.Equal(t, "3", cookies[0].Value)
+	resp.Body.Close()
+}
+
+func TestAddVariant(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(ctrl)
+	server := httptest.NewServer(s.router)
+	client := server.Client()
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	s.usecase.EXPECT().AddVariant(ctx, "testAdminUrl", models.Variant{URL: "https://mail.ru/a", Weight: 1}).Return(nil)
+	resp, err := client.PostForm(server.URL+"/s/testAdminUrl/variants", url.Values{"url": {"https://mail.ru/a"}})
+	require.NoError(t, err)
+	require.Equal(t, 303, resp.StatusCode)
+	resp.Body.Close()
+
+	resp, err = client.PostForm(server.URL+"/s/testAdminUrl/variants", url.Values{"weight": {"heavy"}})
+	require.NoError(t, err)
+	require.Equal(t, 400, resp.StatusCode)
+	resp.Body.Close()
+
+	s.usecase.EXPECT().DeleteVariant(ctx, "testAdminUrl", int64(3)).Return(nil)
+	resp, err = client.Post(server.URL+"/s/testAdminUrl/variants/3/delete", "", nil)
+	require.NoError(t, err)
+	require.Equal(t, 303, resp.StatusCode)
+	resp.Body.Close()
+
+	s.usecase.EXPECT().SetPassthrough(ctx, "testAdminUrl", models.PassthroughOverride).Return(nil)
+	resp, err = client.PostForm(server.URL+"/s/testAdminUrl/passthrough", url.Values{"passthrough": {"override"}})
+	require.NoError(t, err)
+	require.Equal(t, 303, resp.StatusCode)
+	resp.Body.Close()
+
+	s.usecase.EXPECT().SetSticky(ctx, "testAdminUrl", true).Return(nil)
+	resp, err = client.PostForm(server.URL+"/s/testAdminUrl/variants/sticky", url.Values{"sticky": {"on"}})
+	require.NoError(t, err)
+	require.Equal(t, 303, resp.StatusCode)
+	resp.Body.Close()
+}
+
+func TestAddRule(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(ctrl)
+	server := httptest.NewServer(s.router)
+	client := server.Client()
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	form := url.Values{"platform": {"iOS"}, "url": {"https://apps.apple.com/app"}, "position": {"2"}}
+	s.usecase.EXPECT().AddRule(ctx, "testAdminUrl", models.Rule{
+		Position: 2,
+		Platform: "iOS",
+		URL:      "https://apps.apple.com/app",
+	}).Return(nil)
+	resp, err := client.PostForm(server.URL+"/s/testAdminUrl/rules", form)
+	require.NoError(t, err)
+	require.Equal(t, 303, resp.StatusCode)
+	require.True(t, strings.HasSuffix(resp.Header.Get("Location"), "testUrls/testAdminUrl"))
+	resp.Body.Close()
+
+	s.usecase.EXPECT().AddRule(ctx, "testAdminUrl", gomock.Any()).
+		Return(&models.ValidationError{Reason: "the rule must have at least one condition"})
+	resp, err = client.PostForm(server.URL+"/s/testAdminUrl/rules", url.Values{"url": {"https://mail.ru"}})
+	require.NoError(t, err)
+	require.Equal(t, 400, resp.StatusCode)
+	resp.Body.Close()
+
+	resp, err = client.PostForm(server.URL+"/s/testAdminUrl/rules", url.Values{"position": {"first"}})
+	require.NoError(t, err)
+	require.Equal(t, 400, resp.StatusCode)
+	resp.Body.Close()
+
+	s.usecase.EXPECT().AddRule(ctx, "testAdminUrl", gomock.Any()).Return(errors.New("error"))
+	resp, err = client.PostForm(server.URL+"/s/testAdminUrl/rules", form)
+	require.NoError(t, err)
+	require.Equal(t, 500, resp.StatusCode)
+	resp.Body.Close()
+}
+
+func TestDeleteRule(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(ctrl)
+	server := httptest.NewServer(s.router)
+	client := server.Client()
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	s.usecase.EXPECT().DeleteRule(ctx, "testAdminUrl", int64(7)).Return(nil)
+	resp, err := client.Post(server.URL+"/s/testAdminUrl/rules/7/delete", "", nil)
+	require.NoError(t, err)
+	require.Equal(t, 303, resp.StatusCode)
+	resp.Body.Close()
+
+	s.usecase.EXPECT().DeleteRule(ctx, "testAdminUrl", int64(7)).Return(models.ErrNotFound)
+	resp, err = client.Post(server.URL+"/s/testAdminUrl/rules/7/delete", "", nil)
+	require.NoError(t, err)
+	require.Equal(t, 404, resp.StatusCode)
+	resp.Body.Close()
+
+	resp, err = client.Post(server.URL+"/s/testAdminUrl/rules/seven/delete", "", nil)
+	require.NoError(t, err)
+	require.Equal(t, 404, resp.StatusCode)
+	resp.Body.Close()
+}
+
+func TestHomePage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(ctrl)
+	server := httptest.NewServer(s.router)
+	client := server.Client()
+
+	resp, err := client.Get(server.URL + "/")
+	require.NoError(t, err)
+	require.Equal(t, 200, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Contains(t, string(body), `name="long_url"`)
+	resp.Body.Close()
+
+	resp, err = client.Get(server.URL + "/static/images/gopher.png")
+	require.NoError(t, err)
+	require.Equal(t, 200, resp.StatusCode)
+	resp.Body.Close()
+
+	resp, err = client.Get(server.URL + "/static/css/smurl.css")
+	require.NoError(t, err)
+	require.Equal(t, 200, resp.StatusCode)
+	resp.Body.Close()
+}
+
+func TestTheme(t *testing.T) {
+	theme := DefaultTheme()
+	theme.Name = "ACME links"
+	theme.Logo = "/static/images/acme.png"
+	theme.Accent = "#123456"
+	theme.FooterText = "(c) ACME"
+	theme.FooterURL = ""
+	router, err := NewRouter(nil, nil, zap.L(), "testUrl", theme, static.Assets(""))
+	require.NoError(t, err)
+	home := httptest.NewRecorder()
+	router.HomePage(home, httptest.NewRequest("GET", "/", nil))
+	notFound := httptest.NewRecorder()
+	require.NoError(t, router.ErrorPage(notFound, httptest.NewRequest("GET", "/", nil), page404, http.StatusNotFound, ""))
+
+	for _, body := range []string{home.Body.String(), notFound.Body.String()} {
+		require.Contains(t, body, "ACME links")
+		require.Contains(t, body, `src="/static/images/acme.png"`)
+		require.Contains(t, body, "--accent: #123456")
+		require.Contains(t, body, "(c) ACME")
+		require.NotContains(t, body, "sanyarise")
+	}
+}
+
+func TestNewRouterTemplateDir(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "home.tmpl"), []byte(`{{template "layout" .}}{{define "content"}}custom{{end}}`), 0o644))
+	router, err := NewRouter(nil, nil, zap.L(), "testUrl", DefaultTheme(), static.Assets(dir))
+	require.NoError(t, err)
+	server := httptest.NewServer(router)
+	resp, err := server.Client().Get(server.URL + "/")
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Contains(t, string(body), "custom")
+	require.Contains(t, string(body), "/static/css/smurl.css")
+	resp.Body.Close()
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "home.tmpl"), []byte("{{.Broken"), 0o644))
+	_, err = NewRouter(nil, nil, zap.L(), "testUrl", DefaultTheme(), static.Assets(dir))
+	require.Error(t, err)
+}
+
+func TestLanguage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(ctrl)
+	server := httptest.NewServer(s.router)
+	client := server.Client()
+
+	get := func(path string, header http.Header) (*http.Response, string) {
+		r, _ := http.NewRequest("GET", server.URL+path, nil)
+		for key, values := range header {
+			r.Header[key] = values
+		}
+		resp, err := client.Do(r)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp, string(body)
+	}
+
+	_, body := get("/", nil)
+	require.Contains(t, body, `lang="en"`)
+	require.Contains(t, body, "Generate")
+
+	_, body = get("/", http.Header{"Accept-Language": {"ru-RU,ru;q=0.9,en;q=0.8"}})
+	require.Contains(t, body, `lang="ru"`)
+	require.Contains(t, body, "Сократить")
+
+	resp, body := get("/?lang=ru", nil)
+	require.Contains(t, body, "Сократить")
+	var cookie *http.Cookie
+	for _, c := range resp.Cookies() {
+		if c.Name == langCookie {
+			cookie = c
+		}
+	}
+	require.NotNil(t, cookie)
+	require.Equal(t, "ru", cookie.Value)
+
+	// The cookie wins over the header, an unknown language is ignored
+	header := http.Header{"Accept-Language": {"en"}, "Cookie": {langCookie + "=ru"}}
+	_, body = get("/?lang=xx", header)
+	require.Contains(t, body, "Сократить")
+
+	smurl := &models.Smurl{
+		SmallURL:   "test",
+		AdminURL:   "test",
+		CreatedAt:  time.Date(2023, time.March, 5, 14, 7, 0, 0, time.UTC),
+		ModifiedAt: time.Date(2023, time.March, 6, 9, 30, 0, 0, time.UTC),
+	}
+	s.usecase.EXPECT().ReadStat(ctx, "testAdminUrl").Return(smurl, nil)
+	_, body = get("/s/testAdminUrl?lang=ru", nil)
+	require.Contains(t, body, "5 марта 2023 г. 14:07 UTC")
+	require.Contains(t, body, "6 марта 2023 г. 09:30 UTC")
+	require.Contains(t, body, "Создана:")
+}
+
+func TestErrResponseFor(t *testing.T) {
+	tests := []struct {
+		err     error
+		status  int
+		appCode int64
+		text    string
+	}{
+		{&models.ValidationError{Reason: "bad weight"}, 400, AppCodeInvalidRequest, "bad weight"},
+		{fmt.Errorf("find: %w", models.ErrNotFound), 404, AppCodeNotFound, ""},
+		{models.ErrExpired, 410, AppCodeExpired, ""},
+		{models.ErrDeleted, 410, AppCodeDeleted, ""},
+		{errMethodNotAllowed, 405, AppCodeNotAllowed, ""},
+		{models.ErrBlocked, 403, AppCodeBlocked, reasonBlocked},
+		{fmt.Errorf("small url test: %w", models.ErrDisabled), 403, AppCodeDisabled, reasonDisabled},
+		{models.ErrConflict, 409, AppCodeConflict, ""},
+		{models.ErrRateLimited, 429, AppCodeRateLimited, ""},
+		{errors.New("connection refused"), 500, AppCodeInternal, ""},
+	}
+	for _, test := range tests {
+		resp := ErrResponseFor(test.err)
+		require.Equal(t, test.status, resp.HTTPStatusCode, test.err.Error())
+		require.Equal(t, test.appCode, resp.AppCode, test.err.Error())
+		require.Equal(t, test.text, resp.ErrorText, test.err.Error())
+		require.Equal(t, http.StatusText(test.status), resp.StatusText)
+	}
+}
+
+func TestErrorNegotiation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(ctrl)
+	server := httptest.NewServer(s.router)
+	client := server.Client()
+
+	s.usecase.EXPECT().FindURL(ctx, "testSmallUrl").Return(nil, models.ErrBlocked).Times(2)
+
+	r, _ := http.NewRequest("GET", server.URL+"/r/testSmallUrl", nil)
+	r.Header.Set("Accept", "application/json")
+	resp, err := client.Do(r)
+	require.NoError(t, err)
+	require.Equal(t, 403, resp.StatusCode)
+	require.Contains(t, resp.Header.Get("Content-Type"), "application/json")
+	var body ErrResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	resp.Body.Close()
+	require.Equal(t, AppCodeBlocked, body.AppCode)
+	require.Equal(t, reasonBlocked, body.ErrorText)
+
+	r, _ = http.NewRequest("GET", server.URL+"/r/testSmallUrl", nil)
+	r.Header.Set("Accept", "text/html,application/xhtml+xml,*/*;q=0.8")
+	resp, err = client.Do(r)
+	require.NoError(t, err)
+	require.Equal(t, 403, resp.StatusCode)
+	html, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Contains(t, string(html), "403 Forbidden")
+	require.Contains(t, string(html), reasonBlocked)
+}
+
+func TestRedirectGone(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(ctrl)
+	server := httptest.NewServer(s.router)
+	client := server.Client()
+
+	for _, gone := range []error{models.ErrExpired, models.ErrDeleted} {
+		s.usecase.EXPECT().FindURL(ctx, "testSmallUrl").Return(nil, fmt.Errorf("small url testSmallUrl: %w", gone))
+		resp, err := client.Get(server.URL + "/r/testSmallUrl")
+		require.NoError(t, err)
+		require.Equal(t, 410, resp.StatusCode)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Contains(t, string(body), "410 Gone")
+	}
+
+	s.usecase.EXPECT().ReadStat(ctx, "testAdminUrl").Return(nil, models.ErrDeleted)
+	resp, err := client.Get(server.URL + "/s/testAdminUrl")
+	require.NoError(t, err)
+	require.Equal(t, 410, resp.StatusCode)
+	resp.Body.Close()
+}
+
+func TestUnknownRoute(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(ctrl)
+	server := httptest.NewServer(s.router)
+	client := server.Client()
+
+	resp, err := client.Get(server.URL + "/no/such/page")
+	require.NoError(t, err)
+	require.Equal(t, 404, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Contains(t, string(body), "404 Page Not Found")
+
+	r, _ := http.NewRequest("GET", server.URL+"/no/such/page", nil)
+	r.Header.Set("Accept", "application/json")
+	resp, err = client.Do(r)
+	require.NoError(t, err)
+	require.Equal(t, 404, resp.StatusCode)
+	var errResp ErrResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+	resp.Body.Close()
+	require.Equal(t, AppCodeNotFound, errResp.AppCode)
+
+	resp, err = client.Get(server.URL + "/create")
+	require.NoError(t, err)
+	require.Equal(t, 405, resp.StatusCode)
+	body, err = io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Contains(t, string(body), "405 Method Not Allowed")
+
+	r, _ = http.NewRequest("DELETE", server.URL+"/r/testSmallUrl", nil)
+	resp, err = client.Do(r)
+	require.NoError(t, err)
+	require.Equal(t, 405, resp.StatusCode)
+	resp.Body.Close()
+}
+
+func TestOpenAPI(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(ctrl)
+	server := httptest.NewServer(s.router)
+	client := server.Client()
+
+	resp, err := client.Get(server.URL + "/api/openapi.json")
+	require.NoError(t, err)
+	require.Equal(t, 200, resp.StatusCode)
+	require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	var document map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&document))
+	resp.Body.Close()
+	require.Equal(t, "3.0.3", document["openapi"])
+
+	resp, err = client.Get(server.URL + "/api/docs")
+	require.NoError(t, err)
+	require.Equal(t, 200, resp.StatusCode)
+	html, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Contains(t, string(html), "smurl API")
+	require.Contains(t, string(html), "/api/links/{smallUrl}")
+	require.Contains(t, string(html), "testUrlapi/openapi.json")
+}
+
+// TestOpenAPIRoutes checks that the OpenAPI document and
+// the routes of the JSON API describe the same operations
+func TestOpenAPIRoutes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(ctrl)
+
+	_, operations, err := parseOpenAPI(openAPI)
+	require.NoError(t, err)
+	documented := make(map[string]bool)
+	for _, operation := range operations {
+		documented[operation.Method+" "+operation.Path] = true
+		require.NotEmpty(t, operation.Statuses, "%s %s", operation.Method, operation.Path)
+		for _, parameter := range operation.Parameters {
+			require.NotEmpty(t, parameter.Name, "%s %s", operation.Method, operation.Path)
+		}
+	}
+
+	routed := make(map[string]bool)
+	err = chi.Walk(s.router, func(method string, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		if strings.HasPrefix(route, "/api/") && route != "/api/docs" {
+			routed[method+" "+route] = true
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, documented, routed)
+}
+
+func TestCreateLink(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(ctrl)
+	server := httptest.NewServer(s.router)
+	client := server.Client()
+
+	s.helpers.EXPECT().CheckURL("http://mail.ru").Return("http://mail.ru", nil)
+	s.usecase.EXPECT().Create(ctx, "http://mail.ru", models.PassthroughOff).Return(testSmurlWithLongUrl, nil)
+	resp, err := client.Post(server.URL+"/api/links", "application/json", strings.NewReader(`{"long_url":"http://mail.ru"}`))
+	require.NoError(t, err)
+	require.Equal(t, 201, resp.StatusCode)
+	var link Link
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&link))
+	resp.Body.Close()
+	require.Equal(t, "testUrlr/test", link.ShortLink)
+	require.Equal(t, "testUrls/test", link.AdminLink)
+
+	resp, err = client.Post(server.URL+"/api/links", "application/json", strings.NewReader(`{"long_url":`))
+	require.NoError(t, err)
+	require.Equal(t, 400, resp.StatusCode)
+	resp.Body.Close()
+
+	links := strings.Repeat(`{"long_url":"http://mail.ru"},`, maxBulkLinks+1)
+	resp, err = client.Post(server.URL+"/api/links/bulk", "application/json", strings.NewReader(`{"links":[`+strings.TrimSuffix(links, ",")+`]}`))
+	require.NoError(t, err)
+	require.Equal(t, 400, resp.StatusCode)
+	var errResp ErrResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+	resp.Body.Close()
+	require.Equal(t, AppCodeInvalidRequest, errResp.AppCode)
+}
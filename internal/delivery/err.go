@@ -1,11 +1,31 @@
 package delivery
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/go-chi/render"
+	"github.com/sanyarise/smurl/internal/models"
 )
 
+// Application error codes of ErrResponse. The values are part
+// of the API and must not change
+const (
+	AppCodeInternal       int64 = 1
+	AppCodeInvalidRequest int64 = 2
+	AppCodeNotFound       int64 = 3
+	AppCodeExpired        int64 = 4
+	AppCodeBlocked        int64 = 5
+	AppCodeConflict       int64 = 6
+	AppCodeRateLimited    int64 = 7
+	AppCodeDeleted        int64 = 8
+	AppCodeNotAllowed     int64 = 9
+	AppCodeDisabled       int64 = 10
+)
+
+// errMethodNotAllowed reports a route requested with a method it does not serve
+var errMethodNotAllowed = errors.New("method not allowed")
+
 // Error response object structure
 type ErrResponse struct {
 	Err            error `json:"-"`
@@ -21,11 +41,73 @@ func (e *ErrResponse) Render(w http.ResponseWriter, r *http.Request) error {
 	return nil
 }
 
+// errorMapping describes how errors matching target are reported
+type errorMapping struct {
+	target  error
+	status  int
+	appCode int64
+	// Reason shown to the client, validation errors show their own
+	reason string
+}
+
+// Domain errors with the status codes reporting them,
+// any other error is an internal one
+var errorMappings = []errorMapping{
+	{target: models.ErrValidation, status: http.StatusBadRequest, appCode: AppCodeInvalidRequest},
+	{target: models.ErrNotFound, status: http.StatusNotFound, appCode: AppCodeNotFound},
+	{target: models.ErrExpired, status: http.StatusGone, appCode: AppCodeExpired},
+	{target: models.ErrDeleted, status: http.StatusGone, appCode: AppCodeDeleted},
+	{target: models.ErrBlocked, status: http.StatusForbidden, appCode: AppCodeBlocked, reason: reasonBlocked},
+	{target: models.ErrDisabled, status: http.StatusForbidden, appCode: AppCodeDisabled, reason: reasonDisabled},
+	{target: models.ErrConflict, status: http.StatusConflict, appCode: AppCodeConflict},
+	{target: models.ErrRateLimited, status: http.StatusTooManyRequests, appCode: AppCodeRateLimited},
+	{target: errMethodNotAllowed, status: http.StatusMethodNotAllowed, appCode: AppCodeNotAllowed},
+}
+
+// ErrResponseFor maps an error to the response reporting it. Details
+// of internal errors are not shown to the client
+func ErrResponseFor(err error) *ErrResponse {
+	for _, mapping := range errorMappings {
+		if !errors.Is(err, mapping.target) {
+			continue
+		}
+		reason := mapping.reason
+		var validationErr *models.ValidationError
+		if errors.As(err, &validationErr) {
+			reason = validationErr.Reason
+		}
+		return &ErrResponse{
+			Err:            err,
+			HTTPStatusCode: mapping.status,
+			StatusText:     http.StatusText(mapping.status),
+			AppCode:        mapping.appCode,
+			ErrorText:      reason,
+		}
+	}
+	return &ErrResponse{
+		Err:            err,
+		HTTPStatusCode: http.StatusInternalServerError,
+		StatusText:     http.StatusText(http.StatusInternalServerError),
+		AppCode:        AppCodeInternal,
+	}
+}
+
 func ErrInvalidRequest(err error) render.Renderer {
 	return &ErrResponse{
 		Err:            err,
 		HTTPStatusCode: 400,
 		StatusText:     "Invalid Request",
+		AppCode:        AppCodeInvalidRequest,
+		ErrorText:      err.Error(),
+	}
+}
+
+func ErrForbidden(err error) render.Renderer {
+	return &ErrResponse{
+		Err:            err,
+		HTTPStatusCode: 403,
+		StatusText:     "Forbidden",
+		AppCode:        AppCodeBlocked,
 		ErrorText:      err.Error(),
 	}
 }
@@ -35,6 +117,7 @@ func ErrRender(err error) render.Renderer {
 		Err:            err,
 		HTTPStatusCode: 500,
 		StatusText:     "Internal Error",
+		AppCode:        AppCodeInternal,
 		ErrorText:      err.Error(),
 	}
 }
@@ -42,4 +125,5 @@ func ErrRender(err error) render.Renderer {
 var ErrNotFound = &ErrResponse{
 	HTTPStatusCode: 404,
 	StatusText:     "Not Found",
+	AppCode:        AppCodeNotFound,
 }
@@ -0,0 +1,122 @@
+// Package i18n holds the message catalogs of the web interface
+package i18n
+
+import (
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"golang.org/x/text/language"
+)
+
+// Language used when no catalog matches the visitor
+const Default = "en"
+
+// Keys of a catalog that are not messages
+const (
+	keyDateFormat = "date_format"
+	keyMonths     = "months"
+)
+
+//go:embed locales/*.toml
+var locales embed.FS
+
+// Catalog maps message keys to the messages of one language
+type Catalog map[string]string
+
+// Bundle holds the catalogs of all supported languages
+type Bundle struct {
+	catalogs  map[string]Catalog
+	languages []string
+	matcher   language.Matcher
+}
+
+// New loads the embedded catalogs, one locales/<language>.toml
+// file per language
+func New() (*Bundle, error) {
+	files, err := locales.ReadDir("locales")
+	if err != nil {
+		return nil, err
+	}
+	bundle := &Bundle{catalogs: make(map[string]Catalog, len(files))}
+	for _, file := range files {
+		lang := strings.TrimSuffix(file.Name(), ".toml")
+		catalog := make(Catalog)
+		_, err := toml.DecodeFS(locales, path.Join("locales", file.Name()), &catalog)
+		if err != nil {
+			return nil, fmt.Errorf("load catalog %s: %w", lang, err)
+		}
+		if len(strings.Split(catalog[keyMonths], ",")) != 12 {
+			return nil, fmt.Errorf("catalog %s: %s must list 12 months", lang, keyMonths)
+		}
+		bundle.catalogs[lang] = catalog
+		bundle.languages = append(bundle.languages, lang)
+	}
+	if _, ok := bundle.catalogs[Default]; !ok {
+		return nil, fmt.Errorf("no catalog of the default language %s", Default)
+	}
+	sort.Strings(bundle.languages)
+
+	// The default language goes first, it is the fallback of the matcher
+	tags := []language.Tag{language.Make(Default)}
+	for _, lang := range bundle.languages {
+		if lang != Default {
+			tags = append(tags, language.Make(lang))
+		}
+	}
+	bundle.matcher = language.NewMatcher(tags)
+	return bundle, nil
+}
+
+// Languages returns the codes of the supported languages
+func (b *Bundle) Languages() []string {
+	return b.languages
+}
+
+// Supported reports whether there is a catalog of the language
+func (b *Bundle) Supported(lang string) bool {
+	_, ok := b.catalogs[lang]
+	return ok
+}
+
+// Match returns the supported language closest
+// to the ones of an Accept-Language header
+func (b *Bundle) Match(acceptLanguage string) string {
+	tag, _ := language.MatchStrings(b.matcher, acceptLanguage)
+	base, _ := tag.Base()
+	if !b.Supported(base.String()) {
+		return Default
+	}
+	return base.String()
+}
+
+// Translate returns the message of the key in the language, formatted
+// with args. Messages missing in the language are taken from the
+// default one, unknown keys are returned as is
+func (b *Bundle) Translate(lang, key string, args ...interface{}) string {
+	message, ok := b.catalogs[lang][key]
+	if !ok {
+		message, ok = b.catalogs[Default][key]
+	}
+	if !ok {
+		return key
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(message, args...)
+	}
+	return message
+}
+
+// FormatDate formats a time with the month names and date format of the language
+func (b *Bundle) FormatDate(lang string, t time.Time) string {
+	catalog, ok := b.catalogs[lang]
+	if !ok {
+		catalog = b.catalogs[Default]
+	}
+	months := strings.Split(catalog[keyMonths], ",")
+	return fmt.Sprintf(catalog[keyDateFormat], t.Day(), months[t.Month()-1], t.Year(), t.Format("15:04 MST"))
+}
@@ -0,0 +1,57 @@
+package i18n
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCatalogsHaveEveryKey(t *testing.T) {
+	bundle, err := New()
+	require.NoError(t, err)
+	require.Contains(t, bundle.Languages(), "en")
+	require.Contains(t, bundle.Languages(), "ru")
+
+	keys := make(map[string]bool)
+	for _, catalog := range bundle.catalogs {
+		for key := range catalog {
+			keys[key] = true
+		}
+	}
+	for lang, catalog := range bundle.catalogs {
+		for key := range keys {
+			require.NotEmpty(t, catalog[key], "key %s is missing in catalog %s", key, lang)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	bundle, err := New()
+	require.NoError(t, err)
+	require.Equal(t, "ru", bundle.Match("ru-RU,ru;q=0.9,en;q=0.8"))
+	require.Equal(t, "en", bundle.Match("en-GB"))
+	require.Equal(t, "ru", bundle.Match("de;q=0.9,ru;q=0.5"))
+	require.Equal(t, Default, bundle.Match("ja"))
+	require.Equal(t, Default, bundle.Match(""))
+	require.True(t, bundle.Supported("ru"))
+	require.False(t, bundle.Supported("de"))
+}
+
+func TestTranslate(t *testing.T) {
+	bundle, err := New()
+	require.NoError(t, err)
+	require.Equal(t, "Generate", bundle.Translate("en", "home_generate"))
+	require.Equal(t, "Сократить", bundle.Translate("ru", "home_generate"))
+	require.Equal(t, "Generate", bundle.Translate("de", "home_generate"))
+	require.Equal(t, "no_such_key", bundle.Translate("ru", "no_such_key"))
+}
+
+func TestFormatDate(t *testing.T) {
+	bundle, err := New()
+	require.NoError(t, err)
+	date := time.Date(2023, time.March, 5, 14, 7, 0, 0, time.UTC)
+	require.Equal(t, "March 5, 2023 14:07 UTC", bundle.FormatDate("en", date))
+	require.Equal(t, "5 марта 2023 г. 14:07 UTC", bundle.FormatDate("ru", date))
+	require.Equal(t, "March 5, 2023 14:07 UTC", bundle.FormatDate("de", date))
+}
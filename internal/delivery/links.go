@@ -0,0 +1,283 @@
+package delivery
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/sanyarise/smurl/internal/helpers"
+	"github.com/sanyarise/smurl/internal/models"
+)
+
+// Maximum number of links created by one bulk request
+const maxBulkLinks = 100
+
+// Link creation request of the JSON API
+type CreateRequest struct {
+	LongURL     string `json:"long_url"`
+	Passthrough string `json:"passthrough,omitempty"`
+	UTMSource   string `json:"utm_source,omitempty"`
+	UTMMedium   string `json:"utm_medium,omitempty"`
+	UTMCampaign string `json:"utm_campaign,omitempty"`
+	UTMTerm     string `json:"utm_term,omitempty"`
+	UTMContent  string `json:"utm_content,omitempty"`
+}
+
+func (CreateRequest) Bind(r *http.Request) error {
+	return nil
+}
+
+// Bulk link creation request of the JSON API
+type BulkCreateRequest struct {
+	Links []CreateRequest `json:"links"`
+}
+
+func (BulkCreateRequest) Bind(r *http.Request) error {
+	return nil
+}
+
+// Link edit request of the JSON API, omitted fields are kept
+type EditRequest struct {
+	LongURL   *string    `json:"long_url,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// Removes the expiry, ignored when expires_at is set
+	RemoveExpiry bool    `json:"remove_expiry,omitempty"`
+	Passthrough  *string `json:"passthrough,omitempty"`
+	Sticky       *bool   `json:"sticky,omitempty"`
+}
+
+func (EditRequest) Bind(r *http.Request) error {
+	return nil
+}
+
+// Link of the JSON API. The admin fields are only
+// returned to the owner of the admin url
+type Link struct {
+	SmallURL    string     `json:"small_url"`
+	ShortLink   string     `json:"short_link"`
+	AdminURL    string     `json:"admin_url,omitempty"`
+	AdminLink   string     `json:"admin_link,omitempty"`
+	LongURL     string     `json:"long_url"`
+	Passthrough string     `json:"passthrough,omitempty"`
+	Sticky      bool       `json:"sticky"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ModifiedAt  time.Time  `json:"modified_at"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+func (Link) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// Number of clicks with a value of a click property
+type StatItem struct {
+	Name  string `json:"name"`
+	Count uint64 `json:"count"`
+}
+
+// Link with its statistics
+type LinkStats struct {
+	Link
+	Count    uint64 `json:"count"`
+	Uniques  uint64 `json:"uniques"`
+	BotCount uint64 `json:"bot_count"`
+	// Clicks counted by the shortener the link was imported from
+	ImportedClicks uint64     `json:"imported_clicks,omitempty"`
+	Referrers      []StatItem `json:"referrers"`
+	Browsers       []StatItem `json:"browsers"`
+	OS             []StatItem `json:"os"`
+	Devices        []StatItem `json:"devices"`
+	Countries      []StatItem `json:"countries"`
+}
+
+func (LinkStats) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// Result of one link of a bulk request, either the link or the error
+type BulkResult struct {
+	Link  *Link        `json:"link,omitempty"`
+	Error *ErrResponse `json:"error,omitempty"`
+}
+
+// Results of a bulk request in the order of the requested links
+type BulkResponse struct {
+	Results []BulkResult `json:"results"`
+}
+
+func (BulkResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// link converts a small url to a link of the JSON API
+func (router *Router) link(smurl *models.Smurl) Link {
+	link := Link{
+		SmallURL:    smurl.SmallURL,
+		ShortLink:   router.url + "r/" + smurl.SmallURL,
+		LongURL:     smurl.LongURL,
+		Passthrough: string(smurl.Passthrough),
+		Sticky:      smurl.Sticky,
+		CreatedAt:   smurl.CreatedAt,
+		ModifiedAt:  smurl.ModifiedAt,
+	}
+	if smurl.AdminURL != "" {
+		link.AdminURL = smurl.AdminURL
+		link.AdminLink = router.url + "s/" + smurl.AdminURL
+	}
+	if !smurl.ExpiresAt.IsZero() {
+		expiresAt := smurl.ExpiresAt
+		link.ExpiresAt = &expiresAt
+	}
+	return link
+}
+
+// statItems converts a statistics breakdown for the JSON API
+func statItems(items []models.StatItem) []StatItem {
+	result := make([]StatItem, 0, len(items))
+	for _, item := range items {
+		result = append(result, StatItem{Name: item.Name, Count: item.Count})
+	}
+	return result
+}
+
+// apiError reports an error of the JSON API
+func (router *Router) apiError(w http.ResponseWriter, r *http.Request, err error) {
+	resp := ErrResponseFor(err)
+	if resp.HTTPStatusCode == status500 {
+		router.logger.Error(fmt.Sprintf("api error on %s: %s", r.URL.Path, err))
+	} else {
+		router.logger.Debug(fmt.Sprintf("api error on %s: %s", r.URL.Path, err))
+	}
+	render.Render(w, r, resp)
+}
+
+// createLink creates a small url from a request of the JSON API
+func (router *Router) createLink(ctx context.Context, req CreateRequest) (*models.Smurl, error) {
+	utm := helpers.UTM{
+		Source:   req.UTMSource,
+		Medium:   req.UTMMedium,
+		Campaign: req.UTMCampaign,
+		Term:     req.UTMTerm,
+		Content:  req.UTMContent,
+	}
+	return router.create(ctx, req.LongURL, utm, models.Passthrough(req.Passthrough))
+}
+
+// CreateLink creates a small url from a JSON request
+func (router *Router) CreateLink(w http.ResponseWriter, r *http.Request) {
+	router.logger.Debug("Enter in delivery CreateLink()")
+	var req CreateRequest
+	if err := render.Bind(r, &req); err != nil {
+		render.Render(w, r, ErrInvalidRequest(err))
+		return
+	}
+	smurl, err := router.createLink(context.Background(), req)
+	if err != nil {
+		router.apiError(w, r, err)
+		return
+	}
+	render.Status(r, http.StatusCreated)
+	render.Render(w, r, router.link(smurl))
+}
+
+// BulkCreateLinks creates up to maxBulkLinks small urls from a JSON
+// request. A failed link does not stop the others
+func (router *Router) BulkCreateLinks(w http.ResponseWriter, r *http.Request) {
+	router.logger.Debug("Enter in delivery BulkCreateLinks()")
+	var req BulkCreateRequest
+	if err := render.Bind(r, &req); err != nil {
+		render.Render(w, r, ErrInvalidRequest(err))
+		return
+	}
+	if len(req.Links) == 0 || len(req.Links) > maxBulkLinks {
+		router.apiError(w, r, &models.ValidationError{Reason: fmt.Sprintf("from 1 to %d links can be created at once", maxBulkLinks)})
+		return
+	}
+	resp := BulkResponse{Results: make([]BulkResult, 0, len(req.Links))}
+	for _, linkReq := range req.Links {
+		smurl, err := router.createLink(context.Background(), linkReq)
+		if err != nil {
+			router.logger.Debug(fmt.Sprintf("bulk create error: %s", err))
+			resp.Results = append(resp.Results, BulkResult{Error: ErrResponseFor(err)})
+			continue
+		}
+		link := router.link(smurl)
+		resp.Results = append(resp.Results, BulkResult{Link: &link})
+	}
+	render.Render(w, r, resp)
+}
+
+// LookupLink returns the destination of a small url without counting a click
+func (router *Router) LookupLink(w http.ResponseWriter, r *http.Request) {
+	router.logger.Debug("Enter in delivery LookupLink()")
+	smurl, err := router.usecase.FindURL(context.Background(), chi.URLParam(r, "smallUrl"))
+	if err != nil {
+		router.apiError(w, r, err)
+		return
+	}
+	render.Render(w, r, router.link(smurl))
+}
+
+// LinkStats returns a link with its statistics
+func (router *Router) LinkStats(w http.ResponseWriter, r *http.Request) {
+	router.logger.Debug("Enter in delivery LinkStats()")
+	smurl, err := router.usecase.ReadStat(context.Background(), chi.URLParam(r, "adminUrl"))
+	if err != nil {
+		router.apiError(w, r, err)
+		return
+	}
+	render.Render(w, r, LinkStats{
+		Link:           router.link(smurl),
+		Count:          smurl.Count,
+		Uniques:        smurl.Uniques,
+		BotCount:       smurl.BotCount,
+		ImportedClicks: smurl.ImportedClicks,
+		Referrers:      statItems(smurl.Stats.Referrers),
+		Browsers:       statItems(smurl.Stats.Browsers),
+		OS:             statItems(smurl.Stats.OS),
+		Devices:        statItems(smurl.Stats.Devices),
+		Countries:      statItems(smurl.Stats.Countries),
+	})
+}
+
+// EditLink changes a link from a JSON request
+func (router *Router) EditLink(w http.ResponseWriter, r *http.Request) {
+	router.logger.Debug("Enter in delivery EditLink()")
+	var req EditRequest
+	if err := render.Bind(r, &req); err != nil {
+		render.Render(w, r, ErrInvalidRequest(err))
+		return
+	}
+	edit := models.Edit{
+		LongURL:   req.LongURL,
+		ExpiresAt: req.ExpiresAt,
+		Sticky:    req.Sticky,
+	}
+	if req.ExpiresAt == nil && req.RemoveExpiry {
+		edit.ExpiresAt = &time.Time{}
+	}
+	if req.Passthrough != nil {
+		passthrough := models.Passthrough(*req.Passthrough)
+		edit.Passthrough = &passthrough
+	}
+	smurl, err := router.usecase.Edit(context.Background(), chi.URLParam(r, "adminUrl"), edit)
+	if err != nil {
+		router.apiError(w, r, err)
+		return
+	}
+	render.Render(w, r, router.link(smurl))
+}
+
+// DeleteLink deletes a link
+func (router *Router) DeleteLink(w http.ResponseWriter, r *http.Request) {
+	router.logger.Debug("Enter in delivery DeleteLink()")
+	err := router.usecase.Delete(context.Background(), chi.URLParam(r, "adminUrl"))
+	if err != nil {
+		router.apiError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
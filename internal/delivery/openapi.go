@@ -0,0 +1,132 @@
+package delivery
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// OpenAPI document of the JSON API
+//
+//go:embed openapi.json
+var openAPI []byte
+
+// Order of the methods of a path on the docs page
+var docsMethods = []string{"get", "post", "put", "patch", "delete"}
+
+// Parts of the OpenAPI document shown on the docs page
+type apiSpec struct {
+	Info struct {
+		Title       string `json:"title"`
+		Version     string `json:"version"`
+		Description string `json:"description"`
+	} `json:"info"`
+	Paths      map[string]map[string]apiOperation `json:"paths"`
+	Components struct {
+		Parameters map[string]apiParameter `json:"parameters"`
+	} `json:"components"`
+}
+
+type apiParameter struct {
+	Ref         string `json:"$ref"`
+	Name        string `json:"name"`
+	In          string `json:"in"`
+	Description string `json:"description"`
+	Required    bool   `json:"required"`
+}
+
+type apiOperation struct {
+	Method      string
+	Path        string
+	Summary     string                     `json:"summary"`
+	Description string                     `json:"description"`
+	Parameters  []apiParameter             `json:"parameters"`
+	Responses   map[string]json.RawMessage `json:"responses"`
+	// Status codes of the responses, in ascending order
+	Statuses []string
+}
+
+// Data for rendering the docs page
+type DocsData struct {
+	Page
+	Title       string
+	Version     string
+	Description string
+	SpecURL     string
+	Operations  []apiOperation
+}
+
+// parseOpenAPI lists the operations of the OpenAPI document
+// by path and method, with the parameters resolved
+func parseOpenAPI(document []byte) (*apiSpec, []apiOperation, error) {
+	var spec apiSpec
+	if err := json.Unmarshal(document, &spec); err != nil {
+		return nil, nil, fmt.Errorf("parse openapi document: %w", err)
+	}
+	paths := make([]string, 0, len(spec.Paths))
+	for path := range spec.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	var operations []apiOperation
+	for _, path := range paths {
+		for _, method := range docsMethods {
+			operation, ok := spec.Paths[path][method]
+			if !ok {
+				continue
+			}
+			operation.Method = strings.ToUpper(method)
+			operation.Path = path
+			for i, parameter := range operation.Parameters {
+				if parameter.Ref == "" {
+					continue
+				}
+				name := strings.TrimPrefix(parameter.Ref, "#/components/parameters/")
+				resolved, ok := spec.Components.Parameters[name]
+				if !ok {
+					return nil, nil, fmt.Errorf("unknown parameter %s of %s %s", parameter.Ref, operation.Method, path)
+				}
+				operation.Parameters[i] = resolved
+			}
+			for status := range operation.Responses {
+				operation.Statuses = append(operation.Statuses, status)
+			}
+			sort.Strings(operation.Statuses)
+			operations = append(operations, operation)
+		}
+	}
+	return &spec, operations, nil
+}
+
+// OpenAPI serves the OpenAPI document of the JSON API
+func (router *Router) OpenAPI(w http.ResponseWriter, r *http.Request) {
+	router.logger.Debug("Enter in delivery OpenAPI()")
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPI)
+}
+
+// Docs displays the operations of the JSON API
+func (router *Router) Docs(w http.ResponseWriter, r *http.Request) {
+	router.logger.Debug("Enter in delivery Docs()")
+	spec, operations, err := parseOpenAPI(openAPI)
+	if err != nil {
+		router.Error(w, r, err)
+		return
+	}
+	data := DocsData{
+		Page:        router.page(r),
+		Title:       spec.Info.Title,
+		Version:     spec.Info.Version,
+		Description: spec.Info.Description,
+		SpecURL:     router.url + "api/openapi.json",
+		Operations:  operations,
+	}
+	w.WriteHeader(status200)
+	err = router.templates[pageDocs].ExecuteTemplate(w, pageDocs, data)
+	if err != nil {
+		router.logger.Error(fmt.Sprintf("error on execute docs page: %s", err))
+	}
+}
@@ -0,0 +1,85 @@
+package delivery
+
+import (
+	"net/http"
+
+	"github.com/sanyarise/smurl/internal/delivery/i18n"
+)
+
+// Name of the query parameter and the cookie choosing the language
+const (
+	langParam  = "lang"
+	langCookie = "smurl_lang"
+)
+
+// Lifetime in seconds of the cookie keeping the language
+const langCookieAge = 365 * 24 * 60 * 60
+
+// Page holds the data shared by every page
+type Page struct {
+	URL   string
+	Theme Theme
+	// Language of the page
+	Lang string
+	// Supported languages, offered by the language switcher
+	Languages []string
+	// Address the language switcher links to, empty for the current page
+	SwitchURL string
+	bundle    *i18n.Bundle
+}
+
+// T returns the message of the key in the language of the page
+func (p Page) T(key string, args ...interface{}) string {
+	return p.bundle.Translate(p.Lang, key, args...)
+}
+
+// LanguageName returns the name of a language in that language
+func (p Page) LanguageName(lang string) string {
+	return p.bundle.Translate(lang, "language")
+}
+
+// page returns the shared data of a page
+func (router *Router) page(r *http.Request) Page {
+	page := Page{
+		URL:       router.url,
+		Theme:     router.theme,
+		Lang:      router.language(r),
+		Languages: router.i18n.Languages(),
+		bundle:    router.i18n,
+	}
+	// Pages answering a form post can't be requested again
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		page.SwitchURL = router.url
+	}
+	return page
+}
+
+// language returns the language of the page: the one chosen
+// with the query parameter or the cookie, otherwise the closest
+// to the Accept-Language header
+func (router *Router) language(r *http.Request) string {
+	if lang := r.URL.Query().Get(langParam); router.i18n.Supported(lang) {
+		return lang
+	}
+	if cookie, err := r.Cookie(langCookie); err == nil && router.i18n.Supported(cookie.Value) {
+		return cookie.Value
+	}
+	return router.i18n.Match(r.Header.Get("Accept-Language"))
+}
+
+// Language middleware keeps the language chosen with the query parameter in a cookie
+func (router *Router) Language(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if lang := r.URL.Query().Get(langParam); router.i18n.Supported(lang) {
+			http.SetCookie(w, &http.Cookie{
+				Name:     langCookie,
+				Value:    lang,
+				Path:     "/",
+				MaxAge:   langCookieAge,
+				HttpOnly: true,
+				SameSite: http.SameSiteLaxMode,
+			})
+		}
+		next.ServeHTTP(w, r)
+	})
+}
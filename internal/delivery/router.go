@@ -1,9 +1,12 @@
 package delivery
 
 import (
+	"html/template"
+	"io/fs"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/sanyarise/smurl/internal/delivery/i18n"
 	"github.com/sanyarise/smurl/internal/helpers"
 	"github.com/sanyarise/smurl/internal/usecase"
 	"go.uber.org/zap"
@@ -11,33 +14,74 @@ import (
 
 type Router struct {
 	*chi.Mux
-	usecase usecase.Usecase
-	helpers helpers.Helper
-	logger  *zap.Logger
-	url     string
+	usecase   usecase.Usecase
+	helpers   helpers.Helper
+	logger    *zap.Logger
+	url       string
+	theme     Theme
+	i18n      *i18n.Bundle
+	templates map[string]*template.Template
 }
 
-func NewRouter(usecase usecase.Usecase, helpers helpers.Helper, logger *zap.Logger, url string) *Router {
+// NewRouter creates the router. The page templates are parsed from
+// assets, which are also served under /static/, and rendered with theme
+func NewRouter(usecase usecase.Usecase, helpers helpers.Helper, logger *zap.Logger, url string, theme Theme, assets fs.FS) (*Router, error) {
 	r := chi.NewRouter()
 
+	templates, err := parseTemplates(assets)
+	if err != nil {
+		logger.Error("error on parse templates",
+			zap.Error(err))
+		return nil, err
+	}
+	bundle, err := i18n.New()
+	if err != nil {
+		logger.Error("error on load message catalogs",
+			zap.Error(err))
+		return nil, err
+	}
 	router := &Router{
-		usecase: usecase,
-		helpers: helpers,
-		logger:  logger,
-		url:     url,
+		usecase:   usecase,
+		helpers:   helpers,
+		logger:    logger,
+		url:       url,
+		theme:     theme,
+		i18n:      bundle,
+		templates: templates,
 	}
 
-	fs := http.FileServer(http.Dir("static"))
-	r.Handle("/static/*", http.StripPrefix("/static/", fs))
+	r.Use(router.Language)
+	r.NotFound(router.NotFound)
+	r.MethodNotAllowed(router.MethodNotAllowed)
+
+	fileServer := http.FileServer(http.FS(assets))
+	r.Handle("/static/*", http.StripPrefix("/static/", fileServer))
 
 	r.Group(func(r chi.Router) {
 		r.Post("/create", router.Create)
 		r.Get("/r/{smallUrl}", router.Redirect)
+		r.Head("/r/{smallUrl}", router.Redirect)
 		r.Get("/s/{adminUrl}", router.GetStat)
+		r.Post("/s/{adminUrl}/forget", router.ForgetVisitors)
+		r.Post("/s/{adminUrl}/rules", router.AddRule)
+		r.Post("/s/{adminUrl}/rules/{ruleID}/delete", router.DeleteRule)
+		r.Post("/s/{adminUrl}/variants", router.AddVariant)
+		r.Post("/s/{adminUrl}/variants/{variantID}/delete", router.DeleteVariant)
+		r.Post("/s/{adminUrl}/variants/sticky", router.SetSticky)
+		r.Post("/s/{adminUrl}/passthrough", router.SetPassthrough)
+		r.Get("/api/stats/{adminUrl}/clicks", router.TimeSeries)
+		r.Post("/api/links", router.CreateLink)
+		r.Post("/api/links/bulk", router.BulkCreateLinks)
+		r.Get("/api/links/{smallUrl}", router.LookupLink)
+		r.Get("/api/admin/{adminUrl}", router.LinkStats)
+		r.Patch("/api/admin/{adminUrl}", router.EditLink)
+		r.Delete("/api/admin/{adminUrl}", router.DeleteLink)
+		r.Get("/api/openapi.json", router.OpenAPI)
+		r.Get("/api/docs", router.Docs)
 		r.Get("/", router.HomePage)
 	})
 	router.Mux = r
-	return router
+	return router, nil
 }
 
 func (Smurl) Bind(r *http.Request) error {
@@ -0,0 +1,47 @@
+package rpc
+
+import (
+	"errors"
+
+	"github.com/sanyarise/smurl/internal/models"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errorMapping describes how errors matching target are reported
+type errorMapping struct {
+	target error
+	code   codes.Code
+	// Message shown to the caller, validation errors show their own
+	message string
+}
+
+// Domain errors with the status codes reporting them,
+// any other error is an internal one
+var errorMappings = []errorMapping{
+	{target: models.ErrValidation, code: codes.InvalidArgument},
+	{target: models.ErrNotFound, code: codes.NotFound, message: "link not found"},
+	{target: models.ErrExpired, code: codes.NotFound, message: "link expired"},
+	{target: models.ErrDeleted, code: codes.NotFound, message: "link deleted"},
+	{target: models.ErrBlocked, code: codes.PermissionDenied, message: "the destination domain is blocked"},
+	{target: models.ErrDisabled, code: codes.PermissionDenied, message: "the link was disabled"},
+	{target: models.ErrConflict, code: codes.AlreadyExists, message: "conflicting change"},
+	{target: models.ErrRateLimited, code: codes.ResourceExhausted, message: "too many requests"},
+}
+
+// statusFor maps an error to the status reporting it. Details
+// of internal errors are not shown to the caller
+func statusFor(err error) *status.Status {
+	for _, mapping := range errorMappings {
+		if !errors.Is(err, mapping.target) {
+			continue
+		}
+		message := mapping.message
+		var validationErr *models.ValidationError
+		if errors.As(err, &validationErr) {
+			message = validationErr.Reason
+		}
+		return status.New(mapping.code, message)
+	}
+	return status.New(codes.Internal, "internal error")
+}
@@ -0,0 +1,207 @@
+package rpc
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"strings"
+	"time"
+
+	"github.com/sanyarise/smurl/internal/helpers"
+	"github.com/sanyarise/smurl/internal/models"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// ErrUnauthenticated is returned by an Authenticator for unknown keys
+var ErrUnauthenticated = errors.New("unknown api key")
+
+// Authenticator checks the API key of a call and returns the
+// owner of the links created with it, zero for none
+type Authenticator interface {
+	Authenticate(ctx context.Context, key string) (int64, error)
+}
+
+// Keys authenticates the API keys of the configuration
+type Keys [][sha256.Size]byte
+
+func NewKeys(keys []string) Keys {
+	hashes := make(Keys, 0, len(keys))
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		hashes = append(hashes, sha256.Sum256([]byte(key)))
+	}
+	return hashes
+}
+
+// Authenticate compares the hashes of the keys in constant
+// time, so that the time doesn't tell how much of a key matched.
+// The keys of the configuration own no links
+func (k Keys) Authenticate(ctx context.Context, key string) (int64, error) {
+	hash := sha256.Sum256([]byte(key))
+	for _, known := range k {
+		if subtle.ConstantTimeCompare(hash[:], known[:]) == 1 {
+			return 0, nil
+		}
+	}
+	return 0, ErrUnauthenticated
+}
+
+// KeyStore finds the API keys managed with smurlctl
+type KeyStore interface {
+	FindAPIKey(ctx context.Context, hash string) (*models.APIKey, error)
+}
+
+// StoredKeys authenticates the API keys of the store
+type StoredKeys struct {
+	store KeyStore
+}
+
+func NewStoredKeys(store KeyStore) *StoredKeys {
+	return &StoredKeys{store: store}
+}
+
+// Authenticate finds the key by its hash, revoked keys are unknown.
+// A stored key owns the links created with it
+func (k *StoredKeys) Authenticate(ctx context.Context, key string) (int64, error) {
+	apiKey, err := k.store.FindAPIKey(ctx, helpers.HashToken(key))
+	if errors.Is(err, models.ErrNotFound) {
+		return 0, ErrUnauthenticated
+	}
+	if err != nil {
+		return 0, err
+	}
+	if apiKey.Revoked() {
+		return 0, ErrUnauthenticated
+	}
+	return apiKey.ID, nil
+}
+
+// Authenticators accepts the keys known to any of its authenticators
+type Authenticators []Authenticator
+
+func (a Authenticators) Authenticate(ctx context.Context, key string) (int64, error) {
+	for _, auth := range a {
+		owner, err := auth.Authenticate(ctx, key)
+		if !errors.Is(err, ErrUnauthenticated) {
+			return owner, err
+		}
+	}
+	return 0, ErrUnauthenticated
+}
+
+// apiKey returns the key of the "authorization: Bearer <key>" metadata
+func apiKey(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	for _, value := range md.Get("authorization") {
+		if strings.HasPrefix(value, "Bearer ") {
+			return strings.TrimSpace(strings.TrimPrefix(value, "Bearer "))
+		}
+	}
+	return ""
+}
+
+// AuthInterceptor rejects the calls without a known API key. The owner
+// of the key is in the context of the call, see models.OwnerFrom
+func AuthInterceptor(auth Authenticator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		key := apiKey(ctx)
+		if key == "" {
+			return nil, status.Error(codes.Unauthenticated, "missing api key")
+		}
+		owner, err := auth.Authenticate(ctx, key)
+		if errors.Is(err, ErrUnauthenticated) {
+			return nil, status.Error(codes.Unauthenticated, "unknown api key")
+		}
+		if err != nil {
+			return nil, status.Error(codes.Internal, "internal error")
+		}
+		if owner != 0 {
+			ctx = models.WithOwner(ctx, owner)
+		}
+		return handler(ctx, req)
+	}
+}
+
+// LoggingInterceptor logs every call with its status and duration,
+// failed calls at Error level when the failure is internal
+func LoggingInterceptor(logger *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		code := status.Code(err)
+		fields := []zap.Field{
+			zap.String("method", info.FullMethod),
+			zap.String("code", code.String()),
+			zap.Duration("duration", time.Since(start)),
+		}
+		switch {
+		case code == codes.Internal || code == codes.Unknown:
+			logger.Error("grpc call failed", append(fields, zap.Error(err))...)
+		case err != nil:
+			logger.Info("grpc call failed", append(fields, zap.Error(err))...)
+		default:
+			logger.Debug("grpc call", fields...)
+		}
+		return resp, err
+	}
+}
+
+// Metrics counts the calls of the gRPC API by method. It is an
+// expvar.Var, published with expvar.Publish to be served
+// by expvar.Handler
+type Metrics struct {
+	// Calls by method
+	calls expvar.Map
+	// Failed calls by method and status code
+	errors expvar.Map
+	// Total duration of the calls by method, in microseconds
+	durations expvar.Map
+}
+
+func NewMetrics() *Metrics {
+	metrics := &Metrics{}
+	metrics.calls.Init()
+	metrics.errors.Init()
+	metrics.durations.Init()
+	return metrics
+}
+
+// Observe counts a call of the method
+func (m *Metrics) Observe(method string, code codes.Code, duration time.Duration) {
+	m.calls.Add(method, 1)
+	m.durations.Add(method, duration.Microseconds())
+	if code != codes.OK {
+		m.errors.Add(method+" "+code.String(), 1)
+	}
+}
+
+func (m *Metrics) String() string {
+	data, _ := json.Marshal(map[string]json.RawMessage{
+		"calls":       json.RawMessage(m.calls.String()),
+		"errors":      json.RawMessage(m.errors.String()),
+		"duration_us": json.RawMessage(m.durations.String()),
+	})
+	return string(data)
+}
+
+// MetricsInterceptor counts every call in metrics
+func MetricsInterceptor(metrics *Metrics) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		metrics.Observe(info.FullMethod, status.Code(err), time.Since(start))
+		return resp, err
+	}
+}
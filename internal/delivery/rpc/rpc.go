@@ -0,0 +1,190 @@
+// Package rpc serves the gRPC API of api/smurl/v1 for internal callers
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	smurlv1 "github.com/sanyarise/smurl/api/smurl/v1"
+	"github.com/sanyarise/smurl/internal/helpers"
+	"github.com/sanyarise/smurl/internal/models"
+	"github.com/sanyarise/smurl/internal/usecase"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Maximum number of links created by one batch request
+const maxBatchLinks = 1000
+
+// Passthrough modes of the API and of the links
+var (
+	passthroughs = map[smurlv1.Passthrough]models.Passthrough{
+		smurlv1.Passthrough_PASSTHROUGH_OFF:      models.PassthroughOff,
+		smurlv1.Passthrough_PASSTHROUGH_KEEP:     models.PassthroughKeep,
+		smurlv1.Passthrough_PASSTHROUGH_OVERRIDE: models.PassthroughOverride,
+	}
+	apiPassthroughs = map[models.Passthrough]smurlv1.Passthrough{
+		models.PassthroughOff:      smurlv1.Passthrough_PASSTHROUGH_OFF,
+		models.PassthroughKeep:     smurlv1.Passthrough_PASSTHROUGH_KEEP,
+		models.PassthroughOverride: smurlv1.Passthrough_PASSTHROUGH_OVERRIDE,
+	}
+)
+
+// Service implements the Smurl gRPC service on top of the usecase
+type Service struct {
+	smurlv1.UnimplementedSmurlServer
+	usecase usecase.Usecase
+	helpers helpers.Helper
+	logger  *zap.Logger
+	url     string
+}
+
+func NewService(usecase usecase.Usecase, helpers helpers.Helper, logger *zap.Logger, url string) *Service {
+	logger.Debug("Enter in rpc NewService()")
+	return &Service{
+		usecase: usecase,
+		helpers: helpers,
+		logger:  logger,
+		url:     url,
+	}
+}
+
+// NewServer creates a gRPC server of the service whose calls are
+// counted in metrics, logged and authenticated with auth
+func NewServer(service *Service, auth Authenticator, metrics *Metrics, logger *zap.Logger) *grpc.Server {
+	logger.Debug("Enter in rpc NewServer()")
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		MetricsInterceptor(metrics),
+		LoggingInterceptor(logger),
+		AuthInterceptor(auth),
+	))
+	smurlv1.RegisterSmurlServer(server, service)
+	return server
+}
+
+// link converts a small url to a link of the API
+func (s *Service) link(smurl *models.Smurl) *smurlv1.Link {
+	link := &smurlv1.Link{
+		SmallUrl:    smurl.SmallURL,
+		ShortLink:   s.url + "r/" + smurl.SmallURL,
+		LongUrl:     smurl.LongURL,
+		Passthrough: apiPassthroughs[smurl.Passthrough],
+		Sticky:      smurl.Sticky,
+		CreatedAt:   timestamppb.New(smurl.CreatedAt),
+		ModifiedAt:  timestamppb.New(smurl.ModifiedAt),
+	}
+	if smurl.AdminURL != "" {
+		link.AdminUrl = smurl.AdminURL
+		link.AdminLink = s.url + "s/" + smurl.AdminURL
+	}
+	if !smurl.ExpiresAt.IsZero() {
+		link.ExpiresAt = timestamppb.New(smurl.ExpiresAt)
+	}
+	return link
+}
+
+// statItems converts a statistics breakdown for the API
+func statItems(items []models.StatItem) []*smurlv1.StatItem {
+	result := make([]*smurlv1.StatItem, 0, len(items))
+	for _, item := range items {
+		result = append(result, &smurlv1.StatItem{Name: item.Name, Count: item.Count})
+	}
+	return result
+}
+
+// create checks the long url of the request with its
+// UTM tags added and creates a small url of it
+func (s *Service) create(ctx context.Context, req *smurlv1.CreateRequest) (*models.Smurl, error) {
+	passthrough, ok := passthroughs[req.GetPassthrough()]
+	if !ok {
+		return nil, &models.ValidationError{Reason: fmt.Sprintf("unknown passthrough mode %d", req.GetPassthrough())}
+	}
+	longURL := helpers.AddUTM(req.GetLongUrl(), helpers.UTM{
+		Source:   req.GetUtmSource(),
+		Medium:   req.GetUtmMedium(),
+		Campaign: req.GetUtmCampaign(),
+		Term:     req.GetUtmTerm(),
+		Content:  req.GetUtmContent(),
+	})
+	longURL, err := s.helpers.CheckURL(longURL)
+	if err != nil {
+		reason := "incorrect long url"
+		var urlErr *helpers.URLError
+		if errors.As(err, &urlErr) {
+			reason = string(urlErr.Reason)
+		}
+		return nil, &models.ValidationError{Reason: reason}
+	}
+	return s.usecase.Create(ctx, longURL, passthrough)
+}
+
+// Create creates a small url
+func (s *Service) Create(ctx context.Context, req *smurlv1.CreateRequest) (*smurlv1.Link, error) {
+	s.logger.Debug("Enter in rpc Create()")
+	smurl, err := s.create(ctx, req)
+	if err != nil {
+		return nil, statusFor(err).Err()
+	}
+	return s.link(smurl), nil
+}
+
+// BatchCreate creates up to maxBatchLinks small urls.
+// A failed link does not stop the others
+func (s *Service) BatchCreate(ctx context.Context, req *smurlv1.BatchCreateRequest) (*smurlv1.BatchCreateResponse, error) {
+	s.logger.Debug("Enter in rpc BatchCreate()")
+	links := req.GetLinks()
+	if len(links) == 0 || len(links) > maxBatchLinks {
+		return nil, statusFor(&models.ValidationError{Reason: fmt.Sprintf("from 1 to %d links can be created at once", maxBatchLinks)}).Err()
+	}
+	resp := &smurlv1.BatchCreateResponse{Results: make([]*smurlv1.BatchResult, 0, len(links))}
+	for _, linkReq := range links {
+		if err := ctx.Err(); err != nil {
+			return nil, status.FromContextError(err).Err()
+		}
+		smurl, err := s.create(ctx, linkReq)
+		if err != nil {
+			s.logger.Debug(fmt.Sprintf("batch create error: %s", err))
+			st := statusFor(err)
+			resp.Results = append(resp.Results, &smurlv1.BatchResult{Result: &smurlv1.BatchResult_Error{
+				Error: &smurlv1.Error{Code: int32(st.Code()), Message: st.Message()},
+			}})
+			continue
+		}
+		resp.Results = append(resp.Results, &smurlv1.BatchResult{Result: &smurlv1.BatchResult_Link{Link: s.link(smurl)}})
+	}
+	return resp, nil
+}
+
+// Resolve returns the link of a small url without counting a click
+func (s *Service) Resolve(ctx context.Context, req *smurlv1.ResolveRequest) (*smurlv1.Link, error) {
+	s.logger.Debug("Enter in rpc Resolve()")
+	smurl, err := s.usecase.FindURL(ctx, req.GetSmallUrl())
+	if err != nil {
+		return nil, statusFor(err).Err()
+	}
+	return s.link(smurl), nil
+}
+
+// GetStats returns a link with its statistics
+func (s *Service) GetStats(ctx context.Context, req *smurlv1.GetStatsRequest) (*smurlv1.LinkStats, error) {
+	s.logger.Debug("Enter in rpc GetStats()")
+	smurl, err := s.usecase.ReadStat(ctx, req.GetAdminUrl())
+	if err != nil {
+		return nil, statusFor(err).Err()
+	}
+	return &smurlv1.LinkStats{
+		Link:           s.link(smurl),
+		Count:          smurl.Count,
+		Uniques:        smurl.Uniques,
+		BotCount:       smurl.BotCount,
+		ImportedClicks: smurl.ImportedClicks,
+		Referrers:      statItems(smurl.Stats.Referrers),
+		Browsers:       statItems(smurl.Stats.Browsers),
+		Os:             statItems(smurl.Stats.OS),
+		Devices:        statItems(smurl.Stats.Devices),
+		Countries:      statItems(smurl.Stats.Countries),
+	}, nil
+}
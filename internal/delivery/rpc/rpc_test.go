@@ -0,0 +1,250 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	smurlv1 "github.com/sanyarise/smurl/api/smurl/v1"
+	helper "github.com/sanyarise/smurl/internal/helpers"
+	helpers "github.com/sanyarise/smurl/internal/helpers/mocks"
+	"github.com/sanyarise/smurl/internal/models"
+	"github.com/sanyarise/smurl/internal/usecase/mocks"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const testKey = "test-key"
+
+type TestStatement struct {
+	helpers *helpers.MockHelper
+	usecase *mocks.MockUsecase
+	metrics *Metrics
+	client  smurlv1.SmurlClient
+	// Context of the calls, with the API key
+	ctx context.Context
+}
+
+// NewTestStatement serves the service with mocked usecase
+// and helpers over an in-memory connection
+func NewTestStatement(t *testing.T, ctrl *gomock.Controller) *TestStatement {
+	helpers := helpers.NewMockHelper(ctrl)
+	usecase := mocks.NewMockUsecase(ctrl)
+	metrics := NewMetrics()
+	server := NewServer(NewService(usecase, helpers, zap.L(), "http://sm.url/"), NewKeys([]string{testKey}), metrics, zap.L())
+	lis := bufconn.Listen(1 << 20)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return &TestStatement{
+		helpers: helpers,
+		usecase: usecase,
+		metrics: metrics,
+		client:  smurlv1.NewSmurlClient(conn),
+		ctx:     metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+testKey),
+	}
+}
+
+func TestAuth(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(t, ctrl)
+
+	_, err := s.client.Resolve(context.Background(), &smurlv1.ResolveRequest{SmallUrl: "abc"})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer wrong")
+	_, err = s.client.Resolve(ctx, &smurlv1.ResolveRequest{SmallUrl: "abc"})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	s.usecase.EXPECT().FindURL(gomock.Any(), "abc").Return(&models.Smurl{SmallURL: "abc"}, nil)
+	_, err = s.client.Resolve(s.ctx, &smurlv1.ResolveRequest{SmallUrl: "abc"})
+	require.NoError(t, err)
+}
+
+func TestCreate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(t, ctrl)
+
+	created := time.Date(2023, 3, 5, 14, 7, 0, 0, time.UTC)
+	tagged := "http://mail.ru?utm_source=push"
+	s.helpers.EXPECT().CheckURL(tagged).Return(tagged, nil)
+	s.usecase.EXPECT().Create(gomock.Any(), tagged, models.PassthroughKeep).Return(&models.Smurl{
+		SmallURL: "abc", AdminURL: "admin", LongURL: tagged, Passthrough: models.PassthroughKeep, CreatedAt: created,
+	}, nil)
+	link, err := s.client.Create(s.ctx, &smurlv1.CreateRequest{
+		LongUrl: "http://mail.ru", UtmSource: "push", Passthrough: smurlv1.Passthrough_PASSTHROUGH_KEEP,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "http://sm.url/r/abc", link.ShortLink)
+	require.Equal(t, "http://sm.url/s/admin", link.AdminLink)
+	require.Equal(t, smurlv1.Passthrough_PASSTHROUGH_KEEP, link.Passthrough)
+	require.True(t, created.Equal(link.CreatedAt.AsTime()))
+	require.Nil(t, link.ExpiresAt)
+
+	_, err = s.client.Create(s.ctx, &smurlv1.CreateRequest{LongUrl: "http://mail.ru", Passthrough: 7})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	s.helpers.EXPECT().CheckURL("http://blocked.com").Return("http://blocked.com", nil)
+	s.usecase.EXPECT().Create(gomock.Any(), "http://blocked.com", models.PassthroughOff).Return(nil, models.ErrBlocked)
+	_, err = s.client.Create(s.ctx, &smurlv1.CreateRequest{LongUrl: "http://blocked.com"})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	s.helpers.EXPECT().CheckURL("http://mail.ru").Return("http://mail.ru", nil)
+	s.usecase.EXPECT().Create(gomock.Any(), "http://mail.ru", models.PassthroughOff).Return(nil, errors.New("connection refused"))
+	_, err = s.client.Create(s.ctx, &smurlv1.CreateRequest{LongUrl: "http://mail.ru"})
+	require.Equal(t, codes.Internal, status.Code(err))
+	require.NotContains(t, err.Error(), "connection refused")
+}
+
+func TestBatchCreate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(t, ctrl)
+
+	s.helpers.EXPECT().CheckURL("http://a.com").Return("http://a.com", nil)
+	s.helpers.EXPECT().CheckURL("http://b.com").Return("http://b.com", nil)
+	s.usecase.EXPECT().Create(gomock.Any(), "http://a.com", models.PassthroughOff).Return(&models.Smurl{SmallURL: "a", AdminURL: "aa"}, nil)
+	s.usecase.EXPECT().Create(gomock.Any(), "http://b.com", models.PassthroughOff).Return(nil, &models.ValidationError{Reason: "too long"})
+	resp, err := s.client.BatchCreate(s.ctx, &smurlv1.BatchCreateRequest{Links: []*smurlv1.CreateRequest{
+		{LongUrl: "http://a.com"}, {LongUrl: "http://b.com"},
+	}})
+	require.NoError(t, err)
+	require.Len(t, resp.Results, 2)
+	require.Equal(t, "a", resp.Results[0].GetLink().GetSmallUrl())
+	require.Nil(t, resp.Results[0].GetError())
+	require.Nil(t, resp.Results[1].GetLink())
+	require.Equal(t, int32(codes.InvalidArgument), resp.Results[1].GetError().GetCode())
+	require.Equal(t, "too long", resp.Results[1].GetError().GetMessage())
+
+	_, err = s.client.BatchCreate(s.ctx, &smurlv1.BatchCreateRequest{})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = s.client.BatchCreate(s.ctx, &smurlv1.BatchCreateRequest{Links: make([]*smurlv1.CreateRequest, maxBatchLinks+1)})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestResolve(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(t, ctrl)
+
+	expires := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.usecase.EXPECT().FindURL(gomock.Any(), "abc").Return(&models.Smurl{SmallURL: "abc", LongURL: "http://mail.ru", ExpiresAt: expires}, nil)
+	link, err := s.client.Resolve(s.ctx, &smurlv1.ResolveRequest{SmallUrl: "abc"})
+	require.NoError(t, err)
+	require.Equal(t, "http://mail.ru", link.LongUrl)
+	require.Empty(t, link.AdminUrl)
+	require.True(t, expires.Equal(link.ExpiresAt.AsTime()))
+
+	for _, gone := range []error{models.ErrNotFound, models.ErrExpired, models.ErrDeleted} {
+		s.usecase.EXPECT().FindURL(gomock.Any(), "gone").Return(nil, gone)
+		_, err = s.client.Resolve(s.ctx, &smurlv1.ResolveRequest{SmallUrl: "gone"})
+		require.Equal(t, codes.NotFound, status.Code(err))
+	}
+}
+
+func TestGetStats(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(t, ctrl)
+
+	s.usecase.EXPECT().ReadStat(gomock.Any(), "admin").Return(&models.Smurl{
+		SmallURL: "abc", AdminURL: "admin", Count: 5, Uniques: 3, BotCount: 1, ImportedClicks: 40,
+		Stats: models.ClickStats{Countries: []models.StatItem{{Name: "DE", Count: 5}}},
+	}, nil)
+	stats, err := s.client.GetStats(s.ctx, &smurlv1.GetStatsRequest{AdminUrl: "admin"})
+	require.NoError(t, err)
+	require.Equal(t, "abc", stats.Link.SmallUrl)
+	require.Equal(t, uint64(5), stats.Count)
+	require.Equal(t, uint64(3), stats.Uniques)
+	require.Equal(t, uint64(1), stats.BotCount)
+	require.Equal(t, uint64(40), stats.ImportedClicks)
+	require.Len(t, stats.Countries, 1)
+	require.Equal(t, "DE", stats.Countries[0].Name)
+	require.Empty(t, stats.Browsers)
+
+	s.usecase.EXPECT().ReadStat(gomock.Any(), "nope").Return(nil, models.ErrNotFound)
+	_, err = s.client.GetStats(s.ctx, &smurlv1.GetStatsRequest{AdminUrl: "nope"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestMetrics(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(t, ctrl)
+
+	s.usecase.EXPECT().FindURL(gomock.Any(), "abc").Return(&models.Smurl{SmallURL: "abc"}, nil)
+	_, err := s.client.Resolve(s.ctx, &smurlv1.ResolveRequest{SmallUrl: "abc"})
+	require.NoError(t, err)
+	_, err = s.client.Resolve(context.Background(), &smurlv1.ResolveRequest{SmallUrl: "abc"})
+	require.Error(t, err)
+
+	var metrics struct {
+		Calls  map[string]int64 `json:"calls"`
+		Errors map[string]int64 `json:"errors"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(s.metrics.String()), &metrics))
+	require.Equal(t, int64(2), metrics.Calls[smurlv1.Smurl_Resolve_FullMethodName])
+	require.Equal(t, int64(1), metrics.Errors[smurlv1.Smurl_Resolve_FullMethodName+" Unauthenticated"])
+}
+
+// keyStore is a KeyStore of the keys by hash
+type keyStore map[string]models.APIKey
+
+func (s keyStore) FindAPIKey(ctx context.Context, hash string) (*models.APIKey, error) {
+	key, ok := s[hash]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	return &key, nil
+}
+
+func TestStoredKeys(t *testing.T) {
+	store := keyStore{
+		helper.HashToken("stored"):  {ID: 1, Name: "push"},
+		helper.HashToken("revoked"): {ID: 2, Name: "old", RevokedAt: time.Now()},
+	}
+	auth := Authenticators{NewKeys([]string{"configured", ""}), NewStoredKeys(store)}
+	ctx := context.Background()
+
+	owner, err := auth.Authenticate(ctx, "configured")
+	require.NoError(t, err)
+	require.Zero(t, owner)
+	// Links created with a stored key are owned by the key
+	owner, err = auth.Authenticate(ctx, "stored")
+	require.NoError(t, err)
+	require.Equal(t, int64(1), owner)
+	for _, key := range []string{"revoked", "unknown", ""} {
+		_, err := auth.Authenticate(ctx, key)
+		require.ErrorIs(t, err, ErrUnauthenticated)
+	}
+
+	// The owner of the key is in the context of the call
+	var called bool
+	ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer stored"))
+	_, err = AuthInterceptor(auth)(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		called = true
+		require.Equal(t, int64(1), models.OwnerFrom(ctx))
+		return nil, nil
+	})
+	require.NoError(t, err)
+	require.True(t, called)
+}
@@ -0,0 +1,27 @@
+package delivery
+
+import (
+	"fmt"
+	"html/template"
+	"io/fs"
+)
+
+// Layout shared by the pages, which fill in its blocks
+const layout = "layout.tmpl"
+
+// Pages of the web interface
+var pages = []string{pageHome, page200, pageStat, pageDocs, page400, page403, page404, page405, page409, page410, page429, page500}
+
+// parseTemplates parses every page with the layout once, when the
+// router is created
+func parseTemplates(assets fs.FS) (map[string]*template.Template, error) {
+	templates := make(map[string]*template.Template, len(pages))
+	for _, page := range pages {
+		tmpl, err := template.ParseFS(assets, layout, page)
+		if err != nil {
+			return nil, fmt.Errorf("parse template %s: %w", page, err)
+		}
+		templates[page] = tmpl
+	}
+	return templates, nil
+}
@@ -0,0 +1,36 @@
+package delivery
+
+// Theme of the web interface, injected into every page
+type Theme struct {
+	// Brand name shown in the heading and the title
+	Name    string
+	Tagline string
+	// Optional url of a logo shown before the name
+	Logo       string
+	Background string
+	Text       string
+	// Color of the buttons
+	Accent string
+	// Color of the labels, errors and pressed buttons
+	Alert string
+	// Color of the links and values
+	Highlight  string
+	FooterText string
+	// Optional link shown in the footer
+	FooterURL string
+}
+
+// DefaultTheme returns the stock look of the service
+func DefaultTheme() Theme {
+	return Theme{
+		Name:       "SMURL",
+		Tagline:    "service to shortify long urls",
+		Background: "#000000",
+		Text:       "#ffffff",
+		Accent:     "#5f1b00",
+		Alert:      "#ff0000",
+		Highlight:  "#ffff00",
+		FooterText: "(c) sanyarise",
+		FooterURL:  "https://github.com/sanyarise",
+	}
+}
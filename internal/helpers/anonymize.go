@@ -0,0 +1,47 @@
+package helpers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+)
+
+// Ways of storing client IP addresses
+const (
+	// IPFull stores addresses as they are
+	IPFull = "full"
+	// IPTruncate zeroes the host part: /24 for IPv4 and /48 for IPv6
+	IPTruncate = "truncate"
+	// IPHash stores a keyed hash of the address
+	IPHash = "hash"
+)
+
+var (
+	ipv4Mask = net.CIDRMask(24, 32)
+	ipv6Mask = net.CIDRMask(48, 128)
+)
+
+// AnonymizeIP converts a client IP address to the form it is stored in,
+// according to the configured mode. Strings that are not IP addresses
+// are returned unchanged
+func (helpers *Helpers) AnonymizeIP(ip string) string {
+	helpers.logger.Debug("Enter in func AnonymizeIP()")
+	netIP := net.ParseIP(ip)
+	if netIP == nil {
+		return ip
+	}
+	switch helpers.ipMode {
+	case IPFull:
+		return ip
+	case IPHash:
+		mac := hmac.New(sha256.New, helpers.ipHashKey)
+		mac.Write(netIP.To16())
+		return hex.EncodeToString(mac.Sum(nil)[:16])
+	default:
+		if v4 := netIP.To4(); v4 != nil {
+			return v4.Mask(ipv4Mask).String()
+		}
+		return netIP.Mask(ipv6Mask).String()
+	}
+}
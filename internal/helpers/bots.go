@@ -0,0 +1,90 @@
+package helpers
+
+import (
+	"bufio"
+	_ "embed"
+	"net/http"
+	"strings"
+)
+
+// The list of user agent signatures, one per line
+//
+//go:embed bots.txt
+var botsFile string
+
+var botSignatures = parseSignatures(botsFile)
+
+// Headers sent by browsers when a page is prefetched or prerendered
+// rather than opened by the user
+var prefetchHeaders = []string{"Purpose", "Sec-Purpose", "X-Purpose", "X-Moz"}
+
+// IsBot reports whether the request was made by a bot, a crawler, a link
+// preview service or a health checker rather than by a person
+func (helpers *Helpers) IsBot(r *http.Request) bool {
+	helpers.logger.Debug("Enter in func IsBot()")
+	// Unfurlers and monitors often only check that the link exists
+	if r.Method == http.MethodHead {
+		return true
+	}
+	for _, header := range prefetchHeaders {
+		value := strings.ToLower(r.Header.Get(header))
+		if strings.Contains(value, "prefetch") || strings.Contains(value, "preview") {
+			return true
+		}
+	}
+	ua := strings.ToLower(strings.TrimSpace(r.UserAgent()))
+	if ua == "" {
+		return true
+	}
+	for _, signature := range botSignatures {
+		if signature.match(ua) {
+			return true
+		}
+	}
+	return false
+}
+
+// signature of a bot, the terms of a line of the list
+type signature struct {
+	// Substrings the user agent must contain
+	include []string
+	// Substrings the user agent must not contain
+	exclude []string
+}
+
+// match reports whether the lowercase user agent has the signature
+func (s signature) match(ua string) bool {
+	for _, term := range s.include {
+		if !strings.Contains(ua, term) {
+			return false
+		}
+	}
+	for _, term := range s.exclude {
+		if strings.Contains(ua, term) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseSignatures reads the signatures skipping comments and empty lines
+func parseSignatures(file string) []signature {
+	var signatures []signature
+	scanner := bufio.NewScanner(strings.NewReader(file))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var s signature
+		for _, term := range strings.Split(strings.ToLower(line), " & ") {
+			if excluded := strings.TrimPrefix(term, "!"); excluded != term {
+				s.exclude = append(s.exclude, excluded)
+				continue
+			}
+			s.include = append(s.include, term)
+		}
+		signatures = append(signatures, s)
+	}
+	return signatures
+}
@@ -0,0 +1,118 @@
+// Package codegen generates the codes of small and admin urls
+package codegen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Names of the code generation strategies
+const (
+	Random     = "random"
+	Sequential = "sequential"
+	Snowflake  = "snowflake"
+)
+
+// Default alphabet without look-alike characters,
+// in ascending order so that snowflake codes sort by time
+const DefaultAlphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// Longest accepted code
+const maxLength = 64
+
+// Entropy of admin tokens
+const AdminTokenBits = 128
+
+var ErrInvalidAlphabet = errors.New("the alphabet must have at least 16 distinct printable ASCII characters and no url delimiters")
+
+// CodeGenerator generates unique codes for urls
+type CodeGenerator interface {
+	Generate(ctx context.Context) (string, error)
+}
+
+// Counter returns the next value of a persistent sequence
+type Counter interface {
+	NextSequence(ctx context.Context) (uint64, error)
+}
+
+// New creates the generator of the strategy. Length is the length of
+// random codes and the minimum length of sequential ones, salt shuffles
+// the alphabet of sequential codes and node distinguishes the instances
+// generating snowflake codes
+func New(strategy string, alphabet string, length int, salt string, node int64, counter Counter) (CodeGenerator, error) {
+	if err := checkAlphabet(alphabet); err != nil {
+		return nil, err
+	}
+	if length < 1 || length > maxLength {
+		return nil, fmt.Errorf("the code length must be between 1 and %d", maxLength)
+	}
+	switch strategy {
+	case Random:
+		return NewRandom(alphabet, length), nil
+	case Sequential:
+		return NewSequential(counter, alphabet, length, salt), nil
+	case Snowflake:
+		if node < 0 || node > maxNode {
+			return nil, fmt.Errorf("the snowflake node must be between 0 and %d", maxNode)
+		}
+		return NewSnowflake(alphabet, node), nil
+	}
+	return nil, fmt.Errorf("unknown code strategy %q", strategy)
+}
+
+// NewAdminGenerator creates the generator of admin tokens. They are always
+// random, with at least AdminTokenBits bits so that they can't be guessed:
+// a length too short for the alphabet is raised to the minimum. It
+// returns the length of the tokens
+func NewAdminGenerator(alphabet string, length int) (CodeGenerator, int, error) {
+	if err := checkAlphabet(alphabet); err != nil {
+		return nil, 0, err
+	}
+	if min := MinLength(alphabet, AdminTokenBits); length < min {
+		length = min
+	}
+	generator, err := New(Random, alphabet, length, "", 0, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	return generator, length, nil
+}
+
+// MinLength returns the shortest length of random codes
+// of the alphabet that have at least the given bits of entropy
+func MinLength(alphabet string, bits int) int {
+	return int(math.Ceil(float64(bits) / math.Log2(float64(len(alphabet)))))
+}
+
+// checkAlphabet rejects alphabets that are too small, repeat
+// characters or contain characters that need escaping in urls
+func checkAlphabet(alphabet string) error {
+	seen := make(map[rune]bool, len(alphabet))
+	for _, c := range alphabet {
+		if c <= ' ' || c > '~' || strings.ContainsRune("/?#%&+", c) || seen[c] {
+			return ErrInvalidAlphabet
+		}
+		seen[c] = true
+	}
+	if len(seen) < 16 {
+		return ErrInvalidAlphabet
+	}
+	return nil
+}
+
+// encode writes n in the base of the alphabet, padded to width digits
+func encode(n uint64, alphabet string, width int) string {
+	base := uint64(len(alphabet))
+	var digits []byte
+	for n > 0 || len(digits) < width {
+		digits = append(digits, alphabet[n%base])
+		n /= base
+	}
+	for i, j := 0, len(digits)-1; i < j; i, j = i+1, j-1 {
+		digits[i], digits[j] = digits[j], digits[i]
+	}
+	return string(digits)
+}
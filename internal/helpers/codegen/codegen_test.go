@@ -0,0 +1,135 @@
+package codegen
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type counter struct {
+	n   uint64
+	err error
+}
+
+func (c *counter) NextSequence(ctx context.Context) (uint64, error) {
+	c.n++
+	return c.n, c.err
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(Random, DefaultAlphabet, 8, "", 0, nil)
+	assert.NoError(t, err)
+	_, err = New(Sequential, DefaultAlphabet, 6, "salt", 0, &counter{})
+	assert.NoError(t, err)
+	_, err = New(Snowflake, DefaultAlphabet, 8, "", 1023, nil)
+	assert.NoError(t, err)
+
+	_, err = New("uuid", DefaultAlphabet, 8, "", 0, nil)
+	assert.Error(t, err)
+	_, err = New(Random, DefaultAlphabet, 0, "", 0, nil)
+	assert.Error(t, err)
+	_, err = New(Snowflake, DefaultAlphabet, 8, "", 1024, nil)
+	assert.Error(t, err)
+	_, err = New(Random, "abcabcabcabcabcabc", 8, "", 0, nil)
+	assert.ErrorIs(t, err, ErrInvalidAlphabet)
+	_, err = New(Random, "abcdefghijklmno/", 8, "", 0, nil)
+	assert.ErrorIs(t, err, ErrInvalidAlphabet)
+	_, err = New(Random, "abcdef", 8, "", 0, nil)
+	assert.ErrorIs(t, err, ErrInvalidAlphabet)
+}
+
+func TestRandom(t *testing.T) {
+	g := NewRandom(DefaultAlphabet, 10)
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		code, err := g.Generate(context.Background())
+		require.NoError(t, err)
+		require.Len(t, code, 10)
+		for _, c := range code {
+			require.True(t, strings.ContainsRune(DefaultAlphabet, c))
+		}
+		require.False(t, seen[code])
+		seen[code] = true
+	}
+}
+
+func TestSequential(t *testing.T) {
+	c := &counter{}
+	g := NewSequential(c, DefaultAlphabet, 6, "salt")
+	seen := make(map[string]bool)
+	for i := 0; i < 100000; i++ {
+		code, err := g.Generate(context.Background())
+		require.NoError(t, err)
+		require.GreaterOrEqual(t, len(code), 6)
+		require.False(t, seen[code], code)
+		seen[code] = true
+	}
+
+	// The codes depend only on the value and the salt
+	same := NewSequential(&counter{}, DefaultAlphabet, 6, "salt").(*sequential)
+	other := NewSequential(&counter{}, DefaultAlphabet, 6, "pepper").(*sequential)
+	assert.Equal(t, g.(*sequential).encode(42), same.encode(42))
+	assert.NotEqual(t, same.encode(42), other.encode(42))
+
+	_, err := NewSequential(&counter{err: errors.New("error")}, DefaultAlphabet, 6, "").Generate(context.Background())
+	assert.Error(t, err)
+	_, err = NewSequential(nil, DefaultAlphabet, 6, "").Generate(context.Background())
+	assert.ErrorIs(t, err, ErrNoCounter)
+}
+
+func TestSnowflake(t *testing.T) {
+	g := NewSnowflake(DefaultAlphabet, 5).(*snowflake)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	g.now = func() time.Time { return now }
+
+	var codes []string
+	for i := 0; i < 10000; i++ {
+		if i%3000 == 0 {
+			now = now.Add(time.Millisecond)
+		}
+		code, err := g.Generate(context.Background())
+		require.NoError(t, err)
+		require.Len(t, code, 11)
+		codes = append(codes, code)
+	}
+	// The clock going back does not break the order
+	now = now.Add(-time.Second)
+	code, err := g.Generate(context.Background())
+	require.NoError(t, err)
+	codes = append(codes, code)
+
+	assert.True(t, sort.StringsAreSorted(codes))
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		require.False(t, seen[code])
+		seen[code] = true
+	}
+}
+
+func TestNewAdminGenerator(t *testing.T) {
+	generator, length, err := NewAdminGenerator(DefaultAlphabet, 8)
+	require.NoError(t, err)
+	require.Equal(t, 22, length)
+	code, err := generator.Generate(context.Background())
+	require.NoError(t, err)
+	require.Len(t, code, 22)
+
+	_, length, err = NewAdminGenerator(DefaultAlphabet, 30)
+	require.NoError(t, err)
+	require.Equal(t, 30, length)
+
+	_, _, err = NewAdminGenerator("abc", 30)
+	require.ErrorIs(t, err, ErrInvalidAlphabet)
+}
+
+func TestMinLength(t *testing.T) {
+	assert.Equal(t, 22, MinLength(DefaultAlphabet, 128))
+	assert.Equal(t, 32, MinLength("0123456789abcdef", 128))
+	assert.Equal(t, 2, MinLength("0123456789abcdef", 8))
+}
@@ -0,0 +1,36 @@
+package codegen
+
+import (
+	"context"
+	"crypto/rand"
+)
+
+type random struct {
+	alphabet string
+	length   int
+}
+
+// NewRandom creates a generator of fixed length codes with
+// characters chosen uniformly by a cryptographic random source
+func NewRandom(alphabet string, length int) CodeGenerator {
+	return &random{alphabet: alphabet, length: length}
+}
+
+func (g *random) Generate(ctx context.Context) (string, error) {
+	// Bytes above the largest multiple of the alphabet
+	// size are dropped to keep the distribution uniform
+	limit := 256 - 256%len(g.alphabet)
+	code := make([]byte, 0, g.length)
+	buf := make([]byte, g.length*2)
+	for len(code) < g.length {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		for _, b := range buf {
+			if int(b) < limit && len(code) < g.length {
+				code = append(code, g.alphabet[int(b)%len(g.alphabet)])
+			}
+		}
+	}
+	return string(code), nil
+}
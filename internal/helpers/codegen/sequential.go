@@ -0,0 +1,63 @@
+package codegen
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrNoCounter = errors.New("the sequential strategy needs a counter")
+
+type sequential struct {
+	counter  Counter
+	alphabet string
+	length   int
+}
+
+// NewSequential creates a generator encoding the values of the counter
+// in the hashids style: the alphabet is shuffled with the salt and
+// shuffled again for every value with a lottery character, so that
+// consecutive values give unrelated codes. The codes are never shorter
+// than length. The salt must not change once codes have been issued
+func NewSequential(counter Counter, alphabet string, length int, salt string) CodeGenerator {
+	return &sequential{
+		counter:  counter,
+		alphabet: shuffle(alphabet, salt),
+		length:   length,
+	}
+}
+
+func (g *sequential) Generate(ctx context.Context) (string, error) {
+	if g.counter == nil {
+		return "", ErrNoCounter
+	}
+	n, err := g.counter.NextSequence(ctx)
+	if err != nil {
+		return "", err
+	}
+	return g.encode(n), nil
+}
+
+// encode returns the lottery character followed by the value in the
+// alphabet shuffled with it. The lottery character determines the
+// alphabet, so different values never give the same code
+func (g *sequential) encode(n uint64) string {
+	lottery := g.alphabet[n%uint64(len(g.alphabet))]
+	alphabet := shuffle(g.alphabet, string(lottery)+g.alphabet)
+	return string(lottery) + encode(n, alphabet, g.length-1)
+}
+
+// shuffle is the consistent shuffle of hashids: a permutation
+// of the alphabet that depends only on the salt
+func shuffle(alphabet string, salt string) string {
+	result := []byte(alphabet)
+	if salt == "" {
+		return alphabet
+	}
+	for i, v, p := len(result)-1, 0, 0; i > 0; i-- {
+		p += int(salt[v])
+		j := (int(salt[v]) + v + p) % i
+		result[i], result[j] = result[j], result[i]
+		v = (v + 1) % len(salt)
+	}
+	return string(result)
+}
@@ -0,0 +1,62 @@
+package codegen
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	nodeBits     = 10
+	sequenceBits = 12
+	maxNode      = 1<<nodeBits - 1
+	maxSequence  = 1<<sequenceBits - 1
+)
+
+// Start of the snowflake time, 2023-01-01 UTC
+var epoch = time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+type snowflake struct {
+	mu       sync.Mutex
+	alphabet string
+	width    int
+	node     int64
+	last     int64
+	sequence int64
+	now      func() time.Time
+}
+
+// NewSnowflake creates a generator of time-ordered codes: 41 bits of
+// milliseconds since 2023, 10 bits of the node and a 12-bit sequence
+// within the millisecond. The codes have a fixed width, so they sort
+// by time when the alphabet is in ascending order
+func NewSnowflake(alphabet string, node int64) CodeGenerator {
+	return &snowflake{
+		alphabet: alphabet,
+		width:    len(encode(1<<63-1, alphabet, 0)),
+		node:     node,
+		now:      time.Now,
+	}
+}
+
+func (g *snowflake) Generate(ctx context.Context) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	ms := g.now().Sub(epoch).Milliseconds()
+	// The clock going back must not repeat ids
+	if ms < g.last {
+		ms = g.last
+	}
+	if ms == g.last {
+		g.sequence = (g.sequence + 1) & maxSequence
+		if g.sequence == 0 {
+			// The sequence is exhausted, borrow the next millisecond
+			ms++
+		}
+	} else {
+		g.sequence = 0
+	}
+	g.last = ms
+	id := ms<<(nodeBits+sequenceBits) | g.node<<sequenceBits | g.sequence
+	return encode(uint64(id), g.alphabet, g.width), nil
+}
@@ -1,59 +1,70 @@
 package helpers
 
 import (
+	"crypto/rand"
 	"net"
 	"net/http"
-	"net/url"
 	"strings"
+	"time"
 
-	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
 type Helper interface {
-	CheckURL(longURL string) bool
+	CheckURL(longURL string) (string, error)
 	GetIP(r *http.Request) string
-	RandString() string
+	AnonymizeIP(ip string) string
+	IsBot(r *http.Request) bool
+	VisitorID(ip string, userAgent string, t time.Time) string
 }
 type Helpers struct {
-	logger *zap.Logger
+	logger        *zap.Logger
+	schemes       map[string]bool
+	serverHost    string
+	visitorSecret []byte
+	ipMode        string
+	ipHashKey     []byte
 }
 
-func NewHelpers(logger *zap.Logger) Helper {
-	return &Helpers{logger: logger}
-}
-
-const alphabet = "ynAJfoSgdXHB5VasEMtcbPCr1uNZ4LG723ehWkvwYR6KpxjTm8iQUFqz9D"
-
-var alphabetLen = uint32(len(alphabet))
-
-// CheckURL check the validity of a long url
-func (helpers *Helpers) CheckURL(longURL string) bool {
-	helpers.logger.Debug("Enter in func CheckURL()")
-	url, err := url.ParseRequestURI(longURL)
-	return err == nil && url.Scheme != "" && url.Host != "" && strings.Contains(url.Host, ".")
+// NewHelpers creates helpers. Long urls are accepted only with one of the
+// allowed schemes and must not point to the host of serverURL. The visitor
+// secret keys visitor identifiers; ipMode (full, truncate or hash) and
+// ipHashKey set how client IP addresses are stored. Empty secrets are
+// replaced with random ones, which change after a restart
+func NewHelpers(logger *zap.Logger, serverURL string, allowedSchemes []string, visitorSecret string, ipMode string, ipHashKey string) Helper {
+	schemes := make(map[string]bool, len(allowedSchemes))
+	for _, scheme := range allowedSchemes {
+		schemes[strings.ToLower(strings.TrimSpace(scheme))] = true
+	}
+	switch ipMode {
+	case IPFull, IPTruncate, IPHash:
+	default:
+		logger.Warn("unknown ip mode, client addresses will be truncated",
+			zap.String("ip_mode", ipMode))
+		ipMode = IPTruncate
+	}
+	return &Helpers{
+		logger:        logger,
+		schemes:       schemes,
+		serverHost:    serverHostname(serverURL),
+		visitorSecret: secretOrRandom(logger, "visitor secret", visitorSecret),
+		ipMode:        ipMode,
+		ipHashKey:     secretOrRandom(logger, "ip hash key", ipHashKey),
+	}
 }
 
-// RandString generate a random string,
-// used for minified and admin url
-func (helpers *Helpers) RandString() string {
-	helpers.logger.Debug("Enter in func RandString()")
-	var (
-		digits  []uint32
-		num     = uuid.New().ID()
-		builder strings.Builder
-	)
-
-	for num > 0 {
-		digits = append(digits, num%alphabetLen)
-		num /= alphabetLen
+// secretOrRandom returns the secret or a random one when it is empty
+func secretOrRandom(logger *zap.Logger, name string, secret string) []byte {
+	if secret != "" {
+		return []byte(secret)
 	}
-	digits = Reverse(digits)
-
-	for _, digit := range digits {
-		builder.WriteString(string(alphabet[digit]))
+	logger.Warn(name + " is not configured, using a random one")
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		logger.Fatal("can't generate "+name,
+			zap.Error(err))
 	}
-	return builder.String()
+	return random
 }
 
 // Revers reverse slice of uint32
@@ -0,0 +1,85 @@
+// Package hll implements the HyperLogLog cardinality estimator
+// used to count unique visitors of links with many clicks
+package hll
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/bits"
+)
+
+const (
+	// Number of bits of the hash used to select a register
+	precision = 14
+	registers = 1 << precision
+	version   = 1
+)
+
+var ErrInvalidSketch = errors.New("invalid sketch")
+
+// Sketch estimates the number of distinct 64-bit hashes added to it
+// with a standard error of about 0.8%. The zero value is not usable,
+// create sketches with New
+type Sketch struct {
+	registers []uint8
+}
+
+func New() *Sketch {
+	return &Sketch{registers: make([]uint8, registers)}
+}
+
+// Add adds a hash to the sketch. Hashes must be uniformly distributed
+func (s *Sketch) Add(hash uint64) {
+	index := hash >> (64 - precision)
+	// Position of the first set bit in the remaining bits, starting at 1
+	rank := uint8(bits.LeadingZeros64(hash<<precision|1<<(precision-1)) + 1)
+	if rank > s.registers[index] {
+		s.registers[index] = rank
+	}
+}
+
+// AddBytes adds the first 8 bytes of a hash digest to the sketch
+func (s *Sketch) AddBytes(digest []byte) {
+	var buf [8]byte
+	copy(buf[:], digest)
+	s.Add(binary.BigEndian.Uint64(buf[:]))
+}
+
+// Count returns the estimated number of distinct hashes
+func (s *Sketch) Count() uint64 {
+	m := float64(registers)
+	sum := 0.0
+	zeros := 0
+	for _, r := range s.registers {
+		sum += 1 / float64(uint64(1)<<r)
+		if r == 0 {
+			zeros++
+		}
+	}
+	alpha := 0.7213 / (1 + 1.079/m)
+	estimate := alpha * m * m / sum
+	// Linear counting is more accurate for small cardinalities
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return uint64(estimate + 0.5)
+}
+
+// MarshalBinary encodes the sketch as a version byte followed by the registers
+func (s *Sketch) MarshalBinary() ([]byte, error) {
+	data := make([]byte, 1+registers)
+	data[0] = version
+	copy(data[1:], s.registers)
+	return data, nil
+}
+
+// UnmarshalBinary decodes a sketch encoded by MarshalBinary
+func (s *Sketch) UnmarshalBinary(data []byte) error {
+	if len(data) != 1+registers || data[0] != version {
+		return ErrInvalidSketch
+	}
+	s.registers = make([]uint8, registers)
+	copy(s.registers, data[1:])
+	return nil
+}
@@ -0,0 +1,44 @@
+package hll
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func hash(i int) []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(i))
+	digest := sha256.Sum256(buf[:])
+	return digest[:]
+}
+
+func TestCount(t *testing.T) {
+	for _, n := range []int{0, 1, 100, 10000, 200000} {
+		s := New()
+		for i := 0; i < n; i++ {
+			s.AddBytes(hash(i))
+			// Duplicates must not change the estimate
+			s.AddBytes(hash(i))
+		}
+		count := float64(s.Count())
+		require.InDelta(t, float64(n), count, float64(n)*0.03+1, "n = %d", n)
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	s := New()
+	for i := 0; i < 5000; i++ {
+		s.AddBytes(hash(i))
+	}
+	data, err := s.MarshalBinary()
+	require.NoError(t, err)
+
+	restored := &Sketch{}
+	require.NoError(t, restored.UnmarshalBinary(data))
+	require.Equal(t, s.Count(), restored.Count())
+
+	require.ErrorIs(t, restored.UnmarshalBinary(data[:10]), ErrInvalidSketch)
+}
@@ -1,6 +1,10 @@
 package mocks
 
-import "net/http"
+import (
+	"errors"
+	"net/http"
+	"time"
+)
 
 type MockHelpers struct {
 }
@@ -9,18 +13,28 @@ func NewMockHelpers() *MockHelpers {
 	return &MockHelpers{}
 }
 
-/*CheckURL(longURL string) bool
+/*CheckURL(longURL string) (string, error)
 GetIP(r *http.Request) string
-RandString() string*/
+AnonymizeIP(ip string) string
+IsBot(r *http.Request) bool
+VisitorID(ip string, userAgent string, t time.Time) string*/
 
-func (m *MockHelpers) CheckURL(longURL string) bool {
-	return false
+func (m *MockHelpers) CheckURL(longURL string) (string, error) {
+	return "", errors.New("invalid url")
 }
 
 func (m *MockHelpers) GetIP(r *http.Request) string {
 	return "testIpInfo"
 }
 
-func (m *MockHelpers) RandString() string {
-	return "testString"
-}
\ No newline at end of file
+func (m *MockHelpers) AnonymizeIP(ip string) string {
+	return ip
+}
+
+func (m *MockHelpers) IsBot(r *http.Request) bool {
+	return false
+}
+
+func (m *MockHelpers) VisitorID(ip string, userAgent string, t time.Time) string {
+	return "testVisitorID"
+}
@@ -7,6 +7,7 @@ package mocks
 import (
 	http "net/http"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 )
@@ -34,12 +35,27 @@ func (m *MockHelper) EXPECT() *MockHelperMockRecorder {
 	return m.recorder
 }
 
+// AnonymizeIP mocks base method.
+func (m *MockHelper) AnonymizeIP(ip string) string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AnonymizeIP", ip)
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// AnonymizeIP indicates an expected call of AnonymizeIP.
+func (mr *MockHelperMockRecorder) AnonymizeIP(ip interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AnonymizeIP", reflect.TypeOf((*MockHelper)(nil).AnonymizeIP), ip)
+}
+
 // CheckURL mocks base method.
-func (m *MockHelper) CheckURL(longURL string) bool {
+func (m *MockHelper) CheckURL(longURL string) (string, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "CheckURL", longURL)
-	ret0, _ := ret[0].(bool)
-	return ret0
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // CheckURL indicates an expected call of CheckURL.
@@ -62,16 +78,30 @@ func (mr *MockHelperMockRecorder) GetIP(r interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetIP", reflect.TypeOf((*MockHelper)(nil).GetIP), r)
 }
 
-// RandString mocks base method.
-func (m *MockHelper) RandString() string {
+// IsBot mocks base method.
+func (m *MockHelper) IsBot(r *http.Request) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsBot", r)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// IsBot indicates an expected call of IsBot.
+func (mr *MockHelperMockRecorder) IsBot(r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsBot", reflect.TypeOf((*MockHelper)(nil).IsBot), r)
+}
+
+// VisitorID mocks base method.
+func (m *MockHelper) VisitorID(ip, userAgent string, t time.Time) string {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "RandString")
+	ret := m.ctrl.Call(m, "VisitorID", ip, userAgent, t)
 	ret0, _ := ret[0].(string)
 	return ret0
 }
 
-// RandString indicates an expected call of RandString.
-func (mr *MockHelperMockRecorder) RandString() *gomock.Call {
+// VisitorID indicates an expected call of VisitorID.
+func (mr *MockHelperMockRecorder) VisitorID(ip, userAgent, t interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RandString", reflect.TypeOf((*MockHelper)(nil).RandString))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VisitorID", reflect.TypeOf((*MockHelper)(nil).VisitorID), ip, userAgent, t)
 }
@@ -0,0 +1,141 @@
+package helpers
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/sanyarise/smurl/internal/models"
+)
+
+// UTM tags added to a long url
+type UTM struct {
+	Source   string
+	Medium   string
+	Campaign string
+	Term     string
+	Content  string
+}
+
+// AddUTM sets the non-empty UTM tags in the query of the long url,
+// replacing the tags already there. The other parameters are kept as
+// they are. An unparsable url is returned as is
+func AddUTM(longURL string, utm UTM) string {
+	tags := []struct {
+		key   string
+		value string
+	}{
+		{"utm_source", utm.Source},
+		{"utm_medium", utm.Medium},
+		{"utm_campaign", utm.Campaign},
+		{"utm_term", utm.Term},
+		{"utm_content", utm.Content},
+	}
+	u, err := url.Parse(longURL)
+	if err != nil {
+		return longURL
+	}
+	pairs := splitQuery(u.RawQuery)
+	changed := false
+	for _, tag := range tags {
+		if tag.value != "" {
+			pairs = setPair(pairs, queryPair{key: tag.key, raw: url.QueryEscape(tag.key) + "=" + url.QueryEscape(tag.value)})
+			changed = true
+		}
+	}
+	if !changed {
+		return longURL
+	}
+	u.RawQuery = joinQuery(pairs)
+	return u.String()
+}
+
+// PassQuery forwards the raw query parameters of a click onto the
+// destination. With PassthroughKeep the parameters of the destination
+// win on conflict, with PassthroughOverride those of the click do.
+// The parameters are not decoded, so they are forwarded as they are
+func PassQuery(destination string, rawQuery string, mode models.Passthrough) string {
+	passed := splitQuery(rawQuery)
+	if mode == models.PassthroughOff || len(passed) == 0 {
+		return destination
+	}
+	u, err := url.Parse(destination)
+	if err != nil {
+		return destination
+	}
+	pairs := splitQuery(u.RawQuery)
+	existing := make(map[string]bool, len(pairs))
+	for _, pair := range pairs {
+		existing[pair.key] = true
+	}
+	if mode == models.PassthroughOverride {
+		clicked := make(map[string]bool, len(passed))
+		for _, pair := range passed {
+			clicked[pair.key] = true
+		}
+		kept := pairs[:0]
+		for _, pair := range pairs {
+			if !clicked[pair.key] {
+				kept = append(kept, pair)
+			}
+		}
+		pairs = kept
+	}
+	for _, pair := range passed {
+		if mode == models.PassthroughKeep && existing[pair.key] {
+			continue
+		}
+		pairs = append(pairs, pair)
+	}
+	u.RawQuery = joinQuery(pairs)
+	return u.String()
+}
+
+// queryPair is a parameter of a raw query with its decoded key
+type queryPair struct {
+	key string
+	raw string
+}
+
+// splitQuery returns the parameters of a raw query without decoding them
+func splitQuery(rawQuery string) []queryPair {
+	var pairs []queryPair
+	for _, raw := range strings.Split(rawQuery, "&") {
+		if raw == "" {
+			continue
+		}
+		key := queryKey(raw)
+		if decoded, err := url.QueryUnescape(key); err == nil {
+			key = decoded
+		}
+		pairs = append(pairs, queryPair{key: key, raw: raw})
+	}
+	return pairs
+}
+
+// setPair replaces the first parameter with the key of the pair and
+// removes the others, or appends the pair when the key is missing
+func setPair(pairs []queryPair, pair queryPair) []queryPair {
+	set := false
+	result := pairs[:0]
+	for _, p := range pairs {
+		switch {
+		case p.key != pair.key:
+			result = append(result, p)
+		case !set:
+			result = append(result, pair)
+			set = true
+		}
+	}
+	if !set {
+		result = append(result, pair)
+	}
+	return result
+}
+
+func joinQuery(pairs []queryPair) string {
+	raw := make([]string, 0, len(pairs))
+	for _, pair := range pairs {
+		raw = append(raw, pair.raw)
+	}
+	return strings.Join(raw, "&")
+}
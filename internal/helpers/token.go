@@ -0,0 +1,47 @@
+package helpers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// Prefix of the API keys, which makes leaked keys easy to find
+const apiKeyPrefix = "smurl_"
+
+// HashToken returns the hash of an admin token stored instead of the
+// token, so that the database does not give access to the statistics
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// TokenMatches reports in constant time whether the token has the hash
+func TokenMatches(token string, hash string) bool {
+	return subtle.ConstantTimeCompare([]byte(HashToken(token)), []byte(hash)) == 1
+}
+
+// NewAPIKey returns a random API key with 256 bits of entropy. Like
+// admin tokens, only its HashToken is stored
+func NewAPIKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return apiKeyPrefix + base64.RawURLEncoding.EncodeToString(key), nil
+}
+
+// Prefix of the secrets of the webhooks
+const webhookSecretPrefix = "whsec_"
+
+// NewWebhookSecret returns a random key for the signatures of the
+// payloads of a webhook. It is stored as is, the receiver needs it too
+func NewWebhookSecret() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return webhookSecretPrefix + base64.RawURLEncoding.EncodeToString(key), nil
+}
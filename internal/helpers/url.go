@@ -0,0 +1,238 @@
+package helpers
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// URLReason describes why a long url was rejected
+type URLReason string
+
+const (
+	ReasonMalformed   URLReason = "the url is malformed"
+	ReasonScheme      URLReason = "the url scheme is not allowed"
+	ReasonCredentials URLReason = "the url must not contain a user name or password"
+	ReasonHost        URLReason = "the url host is invalid"
+	ReasonPrivate     URLReason = "the url points to a private network address"
+	ReasonSelf        URLReason = "the url points back to this service"
+)
+
+// URLError is returned by CheckURL when a long url
+// does not pass validation
+type URLError struct {
+	URL    string
+	Reason URLReason
+}
+
+func (e *URLError) Error() string {
+	return fmt.Sprintf("invalid url %q: %s", e.URL, e.Reason)
+}
+
+// Default ports which are removed from the normalized url
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// Address ranges that are not covered by the net.IP helpers
+var privateNets = mustParseCIDRs(
+	"0.0.0.0/8",     // this network
+	"100.64.0.0/10", // carrier-grade NAT
+	"192.0.0.0/24",  // IETF protocol assignments
+	"198.18.0.0/15", // benchmarking
+	"64:ff9b::/96",  // NAT64 to IPv4 addresses
+)
+
+// 6to4 addresses, which embed an IPv4 address after the prefix
+var sixToFour = mustParseCIDRs("2002::/16")[0]
+
+// CheckURL validates a long url and returns its normalized form.
+// On failure the returned error is a *URLError with the reason
+func (helpers *Helpers) CheckURL(longURL string) (string, error) {
+	helpers.logger.Debug("Enter in func CheckURL()")
+	u, reason := helpers.normalizeURL(strings.TrimSpace(longURL))
+	if reason != "" {
+		return "", &URLError{URL: longURL, Reason: reason}
+	}
+	return u.String(), nil
+}
+
+// normalizeURL runs the validation pipeline: parse, check scheme and
+// credentials, normalize the host, reject private and self-referencing
+// targets, strip the default port and sort the query
+func (helpers *Helpers) normalizeURL(longURL string) (*url.URL, URLReason) {
+	u, err := url.ParseRequestURI(longURL)
+	if err != nil || u.Host == "" {
+		return nil, ReasonMalformed
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if !helpers.schemes[u.Scheme] {
+		return nil, ReasonScheme
+	}
+	if u.User != nil {
+		return nil, ReasonCredentials
+	}
+
+	host, reason := normalizeHost(u.Hostname())
+	if reason != "" {
+		return nil, reason
+	}
+	if isPrivateHost(host) {
+		return nil, ReasonPrivate
+	}
+	if helpers.serverHost != "" && host == helpers.serverHost {
+		return nil, ReasonSelf
+	}
+
+	port := u.Port()
+	if port == defaultPorts[u.Scheme] {
+		port = ""
+	}
+	switch {
+	case port != "":
+		u.Host = net.JoinHostPort(host, port)
+	case strings.Contains(host, ":"):
+		u.Host = "[" + host + "]"
+	default:
+		u.Host = host
+	}
+
+	if u.RawQuery != "" {
+		u.RawQuery = sortQuery(u.RawQuery)
+	}
+	return u, ""
+}
+
+// sortQuery sorts the parameters of a raw query by key and keeps the
+// order of values for repeated keys. The pairs are not decoded, so
+// that the target server receives the same parameters
+func sortQuery(rawQuery string) string {
+	pairs := strings.Split(rawQuery, "&")
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return queryKey(pairs[i]) < queryKey(pairs[j])
+	})
+	return strings.Join(pairs, "&")
+}
+
+func queryKey(pair string) string {
+	key, _, _ := strings.Cut(pair, "=")
+	return key
+}
+
+// normalizeHost lowercases the host and converts
+// internationalized domain names to punycode
+func normalizeHost(host string) (string, URLReason) {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "" {
+		return "", ReasonHost
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.String(), ""
+	}
+	// Browsers and resolvers also accept short, octal and
+	// hex IPv4 forms such as 127.1 or 0x7f.0.0.1
+	if ip := parseNumericIPv4(host); ip != nil {
+		return ip.String(), ""
+	}
+	ascii, err := idna.Lookup.ToASCII(host)
+	if err != nil || !strings.Contains(ascii, ".") {
+		return "", ReasonHost
+	}
+	return ascii, ""
+}
+
+// parseNumericIPv4 parses an IPv4 address in the forms of inet_aton:
+// two to four decimal, octal (leading 0) or hex (leading 0x) numbers,
+// the last one filling the remaining bytes. A single number is not taken
+// as an address, like other hosts without a dot. It returns nil for other hosts
+func parseNumericIPv4(host string) net.IP {
+	parts := strings.Split(host, ".")
+	if len(parts) < 2 || len(parts) > 4 {
+		return nil
+	}
+	numbers := make([]uint64, 0, len(parts))
+	for _, part := range parts {
+		// With base 0 ParseUint also takes 0o and 0b
+		// prefixes and underscores, which are not IPv4 numbers
+		if strings.HasPrefix(part, "0o") || strings.HasPrefix(part, "0b") || strings.Contains(part, "_") {
+			return nil
+		}
+		number, err := strconv.ParseUint(part, 0, 32)
+		if err != nil {
+			return nil
+		}
+		numbers = append(numbers, number)
+	}
+	last := len(numbers) - 1
+	var address uint64
+	for _, number := range numbers[:last] {
+		if number > 255 {
+			return nil
+		}
+		address = address<<8 | number
+	}
+	rest := uint(4 - last)
+	if numbers[last] >= 1<<(8*rest) {
+		return nil
+	}
+	address = address<<(8*rest) | numbers[last]
+	return net.IPv4(byte(address>>24), byte(address>>16), byte(address>>8), byte(address))
+}
+
+// isPrivateHost reports whether the host is a loopback, private,
+// link-local or otherwise non-public address
+func isPrivateHost(host string) bool {
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return true
+	}
+	for _, network := range privateNets {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	if sixToFour.Contains(ip) {
+		return isPrivateHost(net.IP(ip[2:6]).String())
+	}
+	return false
+}
+
+// serverHostname returns the normalized host of the service url,
+// used to detect urls pointing back to the service
+func serverHostname(serverURL string) string {
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		return ""
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if ascii, err := idna.Lookup.ToASCII(host); err == nil {
+		return ascii
+	}
+	return host
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		nets = append(nets, network)
+	}
+	return nets
+}
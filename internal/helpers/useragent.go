@@ -0,0 +1,144 @@
+package helpers
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// Device classes of a user agent
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceUnknown = "unknown"
+)
+
+// Name used when the browser or the operating system is not recognized
+const unknown = "Other"
+
+// Information parsed from the User-Agent header
+type UserAgent struct {
+	Browser string
+	OS      string
+	Device  string
+}
+
+// Browser signatures in the order they are checked: many browsers
+// also mention the engine of another one, e.g. Edge contains "Chrome"
+// and Chrome contains "Safari"
+var browsers = []struct {
+	token string
+	name  string
+}{
+	{"YaBrowser/", "Yandex Browser"},
+	{"SamsungBrowser/", "Samsung Internet"},
+	{"Edg/", "Edge"},
+	{"EdgA/", "Edge"},
+	{"EdgiOS/", "Edge"},
+	{"Edge/", "Edge"},
+	{"OPR/", "Opera"},
+	{"Opera", "Opera"},
+	{"Vivaldi/", "Vivaldi"},
+	{"FxiOS/", "Firefox"},
+	{"Firefox/", "Firefox"},
+	{"CriOS/", "Chrome"},
+	{"Chrome/", "Chrome"},
+	{"Chromium/", "Chromium"},
+	{"Safari/", "Safari"},
+	{"MSIE ", "Internet Explorer"},
+	{"Trident/", "Internet Explorer"},
+	{"curl/", "curl"},
+	{"Wget/", "Wget"},
+}
+
+// Operating system signatures in the order they are checked
+var systems = []struct {
+	token string
+	name  string
+}{
+	{"iPhone", "iOS"},
+	{"iPad", "iOS"},
+	{"iPod", "iOS"},
+	{"Android", "Android"},
+	{"CrOS", "Chrome OS"},
+	{"Windows Phone", "Windows Phone"},
+	{"Windows", "Windows"},
+	{"Mac OS X", "macOS"},
+	{"Macintosh", "macOS"},
+	{"Linux", "Linux"},
+	{"FreeBSD", "FreeBSD"},
+}
+
+// ParseUserAgent recognizes the browser, the operating
+// system and the device class from a User-Agent header
+func ParseUserAgent(userAgent string) UserAgent {
+	ua := UserAgent{
+		Browser: unknown,
+		OS:      unknown,
+		Device:  DeviceUnknown,
+	}
+	if userAgent == "" {
+		return ua
+	}
+	for _, browser := range browsers {
+		if strings.Contains(userAgent, browser.token) {
+			ua.Browser = browser.name
+			break
+		}
+	}
+	for _, system := range systems {
+		if strings.Contains(userAgent, system.token) {
+			ua.OS = system.name
+			break
+		}
+	}
+	lower := strings.ToLower(userAgent)
+	switch {
+	case strings.Contains(lower, "ipad") || strings.Contains(lower, "tablet") ||
+		(ua.OS == "Android" && !strings.Contains(lower, "mobile")):
+		ua.Device = DeviceTablet
+	case strings.Contains(lower, "mobi") || strings.Contains(lower, "iphone") ||
+		strings.Contains(lower, "ipod") || ua.OS == "Windows Phone":
+		ua.Device = DeviceMobile
+	case ua.OS == "Windows" || ua.OS == "macOS" || ua.OS == "Linux" ||
+		ua.OS == "Chrome OS" || ua.OS == "FreeBSD":
+		ua.Device = DeviceDesktop
+	}
+	return ua
+}
+
+// RefererDomain returns the host of the Referer header
+// or an empty string for direct visits
+func RefererDomain(referer string) string {
+	u, err := url.Parse(referer)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+// PreferredLanguage returns the primary subtag of the language
+// with the highest weight in an Accept-Language header
+func PreferredLanguage(acceptLanguage string) string {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return ""
+	}
+	base, _ := tags[0].Base()
+	return base.String()
+}
+
+// Platforms returns the names of the operating systems recognized by ParseUserAgent
+func Platforms() []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, system := range systems {
+		if !seen[system.name] {
+			seen[system.name] = true
+			names = append(names, system.name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,24 @@
+package helpers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// VisitorID returns a privacy-preserving identifier of a visitor: a keyed
+// hash of the IP address and the user agent. The key is a salt derived
+// from the secret and the UTC date, so identifiers rotate every day and
+// can't be linked across days or reversed to the IP address
+func (helpers *Helpers) VisitorID(ip string, userAgent string, t time.Time) string {
+	helpers.logger.Debug("Enter in func VisitorID()")
+	salt := hmac.New(sha256.New, helpers.visitorSecret)
+	salt.Write([]byte(t.UTC().Format("2006-01-02")))
+
+	mac := hmac.New(sha256.New, salt.Sum(nil))
+	mac.Write([]byte(ip))
+	mac.Write([]byte{0})
+	mac.Write([]byte(userAgent))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,207 @@
+package clicksink
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"time"
+
+	"github.com/sanyarise/smurl/internal/models"
+	"go.uber.org/zap"
+)
+
+const (
+	// Most events written to the sinks at once
+	batchSize = 500
+	// Time a sink has to write a batch
+	writeTimeout = 10 * time.Second
+	// How often the sinks close their expired output
+	expireInterval = time.Second
+)
+
+// Event is a click as written to the sinks, with the details
+// saved in the database. The address is anonymized as configured
+type Event struct {
+	SmallURL       string    `json:"small_url"`
+	CreatedAt      time.Time `json:"created_at"`
+	IP             string    `json:"ip,omitempty"`
+	VisitorID      string    `json:"visitor_id,omitempty"`
+	Referer        string    `json:"referer,omitempty"`
+	RefererDomain  string    `json:"referer_domain,omitempty"`
+	UserAgent      string    `json:"user_agent,omitempty"`
+	Browser        string    `json:"browser,omitempty"`
+	OS             string    `json:"os,omitempty"`
+	Device         string    `json:"device,omitempty"`
+	AcceptLanguage string    `json:"accept_language,omitempty"`
+	Country        string    `json:"country,omitempty"`
+	Region         string    `json:"region,omitempty"`
+	City           string    `json:"city,omitempty"`
+	Bot            bool      `json:"bot"`
+	Variant        int64     `json:"variant,omitempty"`
+}
+
+// NewEvent returns the event of a saved click
+func NewEvent(click models.Click) Event {
+	return Event{
+		SmallURL:       click.SmallURL,
+		CreatedAt:      click.CreatedAt,
+		IP:             click.IP,
+		VisitorID:      click.VisitorID,
+		Referer:        click.Referer,
+		RefererDomain:  click.RefererDomain,
+		UserAgent:      click.UserAgent,
+		Browser:        click.Browser,
+		OS:             click.OS,
+		Device:         click.Device,
+		AcceptLanguage: click.AcceptLanguage,
+		Country:        click.Country,
+		Region:         click.Region,
+		City:           click.City,
+		Bot:            click.Bot,
+		Variant:        click.Variant,
+	}
+}
+
+// Sink writes click events to a destination
+type Sink interface {
+	Write(ctx context.Context, events []Event) error
+	Close() error
+}
+
+// Expirer is a sink whose output expires with time, such as a file
+// rotated by age. Expire is called regularly, also while no clicks arrive
+type Expirer interface {
+	Expire() error
+}
+
+// Stream passes the clicks of the redirects to the sinks in the
+// background. Publish never blocks: when the buffer is full because
+// the sinks are too slow, the click is dropped and counted. It is an
+// expvar.Var, published with expvar.Publish to be served by expvar.Handler
+type Stream struct {
+	sinks  []Sink
+	events chan Event
+	// Clicks buffered, dropped and not written by a sink
+	published expvar.Int
+	dropped   expvar.Int
+	failed    expvar.Int
+	// How often the expirers are called
+	expireEvery time.Duration
+	logger      *zap.Logger
+}
+
+// NewStream creates the stream, which buffers up to buffer clicks.
+// Without sinks the clicks are ignored
+func NewStream(sinks []Sink, buffer int, logger *zap.Logger) *Stream {
+	logger.Debug("Enter in clicksink NewStream()")
+	return &Stream{
+		sinks:       sinks,
+		events:      make(chan Event, buffer),
+		expireEvery: expireInterval,
+		logger:      logger,
+	}
+}
+
+// Publish queues a click for the sinks, or drops it if the buffer is full
+func (s *Stream) Publish(click models.Click) {
+	if len(s.sinks) == 0 {
+		return
+	}
+	select {
+	case s.events <- NewEvent(click):
+		s.published.Add(1)
+	default:
+		s.dropped.Add(1)
+	}
+}
+
+// Run writes the queued clicks to the sinks in batches until the
+// context is done, then writes the clicks left and closes the sinks.
+// Meanwhile the expired outputs of the sinks are closed
+func (s *Stream) Run(ctx context.Context) {
+	if len(s.sinks) == 0 {
+		s.logger.Info("Click streaming is disabled")
+		return
+	}
+	defer s.close()
+	ticker := time.NewTicker(s.expireEvery)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			for {
+				if batch := s.next(); len(batch) > 0 {
+					s.write(batch)
+					continue
+				}
+				return
+			}
+		case event := <-s.events:
+			s.write(append([]Event{event}, s.next()...))
+		case <-ticker.C:
+			s.expire()
+		}
+	}
+}
+
+// next returns the queued clicks without waiting, at most a batch
+func (s *Stream) next() []Event {
+	var batch []Event
+	for len(batch) < batchSize {
+		select {
+		case event := <-s.events:
+			batch = append(batch, event)
+		default:
+			return batch
+		}
+	}
+	return batch
+}
+
+// write writes a batch to every sink, a failing sink does not stop the others
+func (s *Stream) write(batch []Event) {
+	for _, sink := range s.sinks {
+		ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+		err := sink.Write(ctx, batch)
+		cancel()
+		if err != nil {
+			s.failed.Add(int64(len(batch)))
+			s.logger.Error("error on write clicks",
+				zap.Int("count", len(batch)),
+				zap.Error(err))
+		}
+	}
+}
+
+// expire lets the sinks close their expired output
+func (s *Stream) expire() {
+	for _, sink := range s.sinks {
+		expirer, ok := sink.(Expirer)
+		if !ok {
+			continue
+		}
+		if err := expirer.Expire(); err != nil {
+			s.logger.Error("error on expire click sink",
+				zap.Error(err))
+		}
+	}
+}
+
+func (s *Stream) close() {
+	for _, sink := range s.sinks {
+		if err := sink.Close(); err != nil {
+			s.logger.Error("error on close click sink",
+				zap.Error(err))
+		}
+	}
+}
+
+func (s *Stream) String() string {
+	data, _ := json.Marshal(map[string]int64{
+		"published": s.published.Value(),
+		"dropped":   s.dropped.Value(),
+		"failed":    s.failed.Value(),
+		"queued":    int64(len(s.events)),
+	})
+	return string(data)
+}
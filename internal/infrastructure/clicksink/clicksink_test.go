@@ -0,0 +1,148 @@
+package clicksink
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sanyarise/smurl/internal/models"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+var ctx = context.Background()
+
+const testEventJSON = `{"small_url":"abc","created_at":"2023-03-05T14:07:00Z","ip":"1.2.3.0","browser":"Firefox","country":"GB","bot":false}`
+
+func testEvent(smallURL string) Event {
+	return Event{
+		SmallURL:  smallURL,
+		CreatedAt: time.Date(2023, 3, 5, 14, 7, 0, 0, time.UTC),
+		IP:        "1.2.3.0",
+		Browser:   "Firefox",
+		Country:   "GB",
+	}
+}
+
+type testSink struct {
+	mu      sync.Mutex
+	batches [][]Event
+	err     error
+	closed  bool
+	expired int
+}
+
+func (s *testSink) Write(ctx context.Context, events []Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batches = append(s.batches, events)
+	return s.err
+}
+
+func (s *testSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+func (s *testSink) Expire() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expired++
+	return nil
+}
+
+func (s *testSink) expires() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.expired
+}
+
+func (s *testSink) events() []Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var events []Event
+	for _, batch := range s.batches {
+		events = append(events, batch...)
+	}
+	return events
+}
+
+func TestNewEvent(t *testing.T) {
+	click := models.Click{
+		SmallURL:  "abc",
+		CreatedAt: time.Date(2023, 3, 5, 14, 7, 0, 0, time.UTC),
+		IP:        "1.2.3.0",
+		Browser:   "Firefox",
+		Location:  models.Location{Country: "GB"},
+	}
+	data, err := json.Marshal(NewEvent(click))
+	require.NoError(t, err)
+	require.JSONEq(t, testEventJSON, string(data))
+}
+
+func TestStream(t *testing.T) {
+	sink := &testSink{}
+	failing := &testSink{err: errors.New("test error")}
+	stream := NewStream([]Sink{sink, failing}, 2, zap.L())
+
+	// Clicks over the buffer are dropped while nothing reads it
+	for _, smallURL := range []string{"a", "b", "c"} {
+		stream.Publish(models.Click{SmallURL: smallURL})
+	}
+	require.JSONEq(t, `{"published":2,"dropped":1,"failed":0,"queued":2}`, stream.String())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		stream.Run(ctx)
+		close(done)
+	}()
+	require.Eventually(t, func() bool {
+		return len(sink.events()) == 2
+	}, time.Second, 5*time.Millisecond)
+
+	// The clicks left are written before the sinks are closed
+	stream.Publish(models.Click{SmallURL: "d"})
+	cancel()
+	<-done
+	var smallURLs []string
+	for _, event := range sink.events() {
+		smallURLs = append(smallURLs, event.SmallURL)
+	}
+	require.Equal(t, []string{"a", "b", "d"}, smallURLs)
+	require.True(t, sink.closed)
+	require.True(t, failing.closed)
+	require.Contains(t, stream.String(), `"failed":3`)
+}
+
+func TestStreamExpire(t *testing.T) {
+	sink := &testSink{}
+	stream := NewStream([]Sink{sink}, 10, zap.L())
+	stream.expireEvery = time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		stream.Run(ctx)
+		close(done)
+	}()
+	// Expired while no clicks arrive
+	require.Eventually(t, func() bool {
+		return sink.expires() >= 2
+	}, time.Second, time.Millisecond)
+	cancel()
+	<-done
+	require.Empty(t, sink.events())
+}
+
+func TestStreamDisabled(t *testing.T) {
+	stream := NewStream(nil, 10, zap.L())
+	stream.Publish(models.Click{SmallURL: "a"})
+	stream.Run(ctx)
+	require.JSONEq(t, `{"published":0,"dropped":0,"failed":0,"queued":0}`, stream.String())
+}
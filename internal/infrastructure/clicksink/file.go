@@ -0,0 +1,142 @@
+package clicksink
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Suffix of the file being written, renamed to .jsonl when it is closed
+const partSuffix = ".part"
+
+// File writes the clicks as JSON Lines to files of a directory,
+// named clicks-<UTC time the file was started>.jsonl. A file is closed
+// and a new one started when it would grow over maxSize bytes or
+// is older than maxAge, so that closed files can be collected.
+// The file being written ends with .jsonl.part
+type File struct {
+	dir     string
+	maxSize int64
+	maxAge  time.Duration
+	file    *os.File
+	w       *bufio.Writer
+	size    int64
+	opened  time.Time
+	now     func() time.Time
+}
+
+// NewFile creates the sink and its directory. Zero maxSize
+// or maxAge disable the rotation by size or by age
+func NewFile(dir string, maxSize int64, maxAge time.Duration) (*File, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &File{
+		dir:     dir,
+		maxSize: maxSize,
+		maxAge:  maxAge,
+		now:     time.Now,
+	}, nil
+}
+
+func (f *File) Write(ctx context.Context, events []Event) error {
+	for _, event := range events {
+		line, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		line = append(line, '\n')
+		if f.full(int64(len(line))) {
+			if err := f.rotate(); err != nil {
+				return err
+			}
+		}
+		n, err := f.w.Write(line)
+		f.size += int64(n)
+		if err != nil {
+			return err
+		}
+	}
+	if f.w == nil {
+		return nil
+	}
+	return f.w.Flush()
+}
+
+// full reports whether a new file is needed for a line of n bytes
+func (f *File) full(n int64) bool {
+	switch {
+	case f.file == nil:
+		return true
+	case f.maxSize > 0 && f.size > 0 && f.size+n > f.maxSize:
+		return true
+	case f.maxAge > 0 && f.now().Sub(f.opened) >= f.maxAge:
+		return true
+	}
+	return false
+}
+
+// Expire closes the current file when it is older than maxAge, so that
+// it can be collected while no clicks arrive. The next write starts a new one
+func (f *File) Expire() error {
+	if f.file == nil || f.maxAge <= 0 || f.now().Sub(f.opened) < f.maxAge {
+		return nil
+	}
+	return f.Close()
+}
+
+// rotate closes the current file and starts a new one
+func (f *File) rotate() error {
+	if err := f.Close(); err != nil {
+		return err
+	}
+	f.opened = f.now()
+	base := filepath.Join(f.dir, "clicks-"+f.opened.UTC().Format("20060102T150405.000000000Z"))
+	// Files started at the same time are numbered
+	var file *os.File
+	for i := 0; file == nil; i++ {
+		name := base + ".jsonl"
+		if i > 0 {
+			name = fmt.Sprintf("%s-%d.jsonl", base, i)
+		}
+		if _, err := os.Stat(name); err == nil {
+			continue
+		}
+		var err error
+		file, err = os.OpenFile(name+partSuffix, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil && !os.IsExist(err) {
+			return err
+		}
+	}
+	f.file = file
+	f.w = bufio.NewWriter(file)
+	f.size = 0
+	return nil
+}
+
+// Close closes the current file and gives it its final name
+func (f *File) Close() error {
+	if f.file == nil {
+		return nil
+	}
+	file := f.file
+	f.file = nil
+	if err := f.w.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), strings.TrimSuffix(file.Name(), partSuffix))
+}
+
+var (
+	_ Sink    = &File{}
+	_ Expirer = &File{}
+)
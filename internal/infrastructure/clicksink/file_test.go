@@ -0,0 +1,85 @@
+package clicksink
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// readLines returns the lines of the files of the directory by name
+func readLines(t *testing.T, dir string) map[string][]string {
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	files := make(map[string][]string)
+	for _, entry := range entries {
+		file, err := os.Open(filepath.Join(dir, entry.Name()))
+		require.NoError(t, err)
+		scanner := bufio.NewScanner(file)
+		files[entry.Name()] = []string{}
+		for scanner.Scan() {
+			files[entry.Name()] = append(files[entry.Name()], scanner.Text())
+		}
+		file.Close()
+	}
+	return files
+}
+
+func TestFile(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "clicks")
+	// Two events fit in a file
+	sink, err := NewFile(dir, int64(2*(len(testEventJSON)+1)), time.Hour)
+	require.NoError(t, err)
+	now := time.Date(2023, 3, 5, 14, 7, 0, 0, time.UTC)
+	sink.now = func() time.Time { return now }
+
+	require.NoError(t, sink.Write(ctx, []Event{testEvent("abc"), testEvent("abc"), testEvent("abc")}))
+	files := readLines(t, dir)
+	require.Len(t, files, 2)
+	require.Len(t, files["clicks-20230305T140700.000000000Z.jsonl"], 2)
+	require.JSONEq(t, testEventJSON, files["clicks-20230305T140700.000000000Z.jsonl"][0])
+	// The file being written is complete up to the last batch
+	require.Len(t, files["clicks-20230305T140700.000000000Z-1.jsonl.part"], 1)
+
+	// Rotated by age
+	now = now.Add(time.Hour)
+	require.NoError(t, sink.Write(ctx, []Event{testEvent("abc")}))
+	require.NoError(t, sink.Close())
+	files = readLines(t, dir)
+	require.Len(t, files, 3)
+	require.Len(t, files["clicks-20230305T140700.000000000Z-1.jsonl"], 1)
+	require.Len(t, files["clicks-20230305T150700.000000000Z.jsonl"], 1)
+}
+
+func TestFileExpire(t *testing.T) {
+	dir := t.TempDir()
+	sink, err := NewFile(dir, 0, time.Hour)
+	require.NoError(t, err)
+	now := time.Date(2023, 3, 5, 14, 7, 0, 0, time.UTC)
+	sink.now = func() time.Time { return now }
+
+	// Nothing to close before the first click
+	require.NoError(t, sink.Expire())
+	require.Empty(t, readLines(t, dir))
+
+	require.NoError(t, sink.Write(ctx, []Event{testEvent("abc")}))
+	now = now.Add(time.Hour - time.Second)
+	require.NoError(t, sink.Expire())
+	require.Contains(t, readLines(t, dir), "clicks-20230305T140700.000000000Z.jsonl.part")
+
+	// Closed without waiting for the next click
+	now = now.Add(time.Second)
+	require.NoError(t, sink.Expire())
+	files := readLines(t, dir)
+	require.Len(t, files, 1)
+	require.Len(t, files["clicks-20230305T140700.000000000Z.jsonl"], 1)
+
+	require.NoError(t, sink.Write(ctx, []Event{testEvent("abc")}))
+	require.NoError(t, sink.Close())
+	files = readLines(t, dir)
+	require.Len(t, files, 2)
+	require.Len(t, files["clicks-20230305T150700.000000000Z.jsonl"], 1)
+}
@@ -0,0 +1,57 @@
+package clicksink
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+// Kafka produces every click as a JSON message to a Kafka topic. The
+// small url is the key, so the clicks of a link stay in one partition
+type Kafka struct {
+	writer *kafka.Writer
+}
+
+// NewKafka creates the producer, it connects to the brokers when writing
+func NewKafka(brokers []string, topic string, logger *zap.Logger) *Kafka {
+	logger.Debug("Enter in clicksink NewKafka()")
+	return &Kafka{
+		writer: &kafka.Writer{
+			Addr:     kafka.TCP(brokers...),
+			Topic:    topic,
+			Balancer: &kafka.Hash{},
+			// The stream already batches the clicks
+			BatchSize:    batchSize,
+			BatchTimeout: 10 * time.Millisecond,
+			RequiredAcks: kafka.RequireOne,
+			ErrorLogger: kafka.LoggerFunc(func(msg string, args ...interface{}) {
+				logger.Sugar().Warnf("kafka: "+msg, args...)
+			}),
+		},
+	}
+}
+
+func (k *Kafka) Write(ctx context.Context, events []Event) error {
+	messages := make([]kafka.Message, 0, len(events))
+	for _, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		messages = append(messages, kafka.Message{
+			Key:   []byte(event.SmallURL),
+			Value: data,
+			Time:  event.CreatedAt,
+		})
+	}
+	return k.writer.WriteMessages(ctx, messages...)
+}
+
+func (k *Kafka) Close() error {
+	return k.writer.Close()
+}
+
+var _ Sink = &Kafka{}
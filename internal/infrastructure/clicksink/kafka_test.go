@@ -0,0 +1,125 @@
+package clicksink
+
+import (
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/segmentio/kafka-go/protocol"
+	"github.com/segmentio/kafka-go/protocol/apiversions"
+	"github.com/segmentio/kafka-go/protocol/metadata"
+	"github.com/segmentio/kafka-go/protocol/produce"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// testBroker is a single Kafka broker with a topic of one partition,
+// which answers the requests a producer makes and keeps the records
+type testBroker struct {
+	listener net.Listener
+	topic    string
+	mu       sync.Mutex
+	keys     []string
+	values   [][]byte
+}
+
+func newTestBroker(t *testing.T, topic string) *testBroker {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	b := &testBroker{listener: listener, topic: topic}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go b.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { listener.Close() })
+	return b
+}
+
+func (b *testBroker) serve(conn net.Conn) {
+	defer conn.Close()
+	host, port, _ := net.SplitHostPort(b.listener.Addr().String())
+	portNumber, _ := strconv.Atoi(port)
+	for {
+		apiVersion, correlationID, _, msg, err := protocol.ReadRequest(conn)
+		if err != nil {
+			return
+		}
+		var resp protocol.Message
+		switch req := msg.(type) {
+		case *apiversions.Request:
+			resp = &apiversions.Response{ApiKeys: []apiversions.ApiKeyResponse{
+				{ApiKey: int16(protocol.ApiVersions), MinVersion: 0, MaxVersion: 2},
+				{ApiKey: int16(protocol.Metadata), MinVersion: 1, MaxVersion: 1},
+				{ApiKey: int16(protocol.Produce), MinVersion: 3, MaxVersion: 3},
+			}}
+		case *metadata.Request:
+			resp = &metadata.Response{
+				Brokers: []metadata.ResponseBroker{{NodeID: 1, Host: host, Port: int32(portNumber)}},
+				Topics: []metadata.ResponseTopic{{Name: b.topic, Partitions: []metadata.ResponsePartition{
+					{PartitionIndex: 0, LeaderID: 1, ReplicaNodes: []int32{1}, IsrNodes: []int32{1}},
+				}}},
+				ControllerID: 1,
+			}
+		case *produce.Request:
+			response := &produce.Response{}
+			for _, topic := range req.Topics {
+				responseTopic := produce.ResponseTopic{Topic: topic.Topic}
+				for _, partition := range topic.Partitions {
+					offset := b.append(partition.RecordSet)
+					responseTopic.Partitions = append(responseTopic.Partitions,
+						produce.ResponsePartition{Partition: partition.Partition, BaseOffset: offset})
+				}
+				response.Topics = append(response.Topics, responseTopic)
+			}
+			resp = response
+		default:
+			return
+		}
+		if err := protocol.WriteResponse(conn, apiVersion, correlationID, resp); err != nil {
+			return
+		}
+	}
+}
+
+// append keeps the records and returns the offset of the first one
+func (b *testBroker) append(records protocol.RecordSet) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	offset := int64(len(b.values))
+	for {
+		record, err := records.Records.ReadRecord()
+		if errors.Is(err, io.EOF) || err != nil {
+			return offset
+		}
+		key, _ := protocol.ReadAll(record.Key)
+		value, _ := protocol.ReadAll(record.Value)
+		b.keys = append(b.keys, string(key))
+		b.values = append(b.values, value)
+	}
+}
+
+func (b *testBroker) records() ([]string, [][]byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]string(nil), b.keys...), append([][]byte(nil), b.values...)
+}
+
+func TestKafka(t *testing.T) {
+	broker := newTestBroker(t, "clicks")
+	sink := NewKafka([]string{broker.listener.Addr().String()}, "clicks", zap.L())
+	defer sink.Close()
+
+	err := sink.Write(ctx, []Event{testEvent("abc"), testEvent("def")})
+	require.NoError(t, err)
+	keys, values := broker.records()
+	require.Equal(t, []string{"abc", "def"}, keys)
+	require.JSONEq(t, testEventJSON, string(values[0]))
+}
@@ -0,0 +1,73 @@
+package clicksink
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/nats-io/nats.go"
+	"go.uber.org/zap"
+)
+
+// NATS publishes every click as a JSON message on a subject of a NATS
+// server. While the server is unreachable the client keeps reconnecting
+// and buffers the messages, up to the reconnect buffer of the client
+type NATS struct {
+	conn    *nats.Conn
+	subject string
+}
+
+// NewNATS connects to the NATS servers of the url, a comma separated list
+func NewNATS(url string, subject string, logger *zap.Logger) (*NATS, error) {
+	logger.Debug("Enter in clicksink NewNATS()")
+	conn, err := nats.Connect(url,
+		nats.Name("smurl"),
+		nats.MaxReconnects(-1),
+		nats.RetryOnFailedConnect(true),
+		nats.DisconnectErrHandler(func(conn *nats.Conn, err error) {
+			logger.Warn("disconnected from nats",
+				zap.Error(err))
+		}),
+		nats.ReconnectHandler(func(conn *nats.Conn) {
+			logger.Info("Reconnected to nats",
+				zap.String("url", conn.ConnectedUrl()))
+		}))
+	if err != nil {
+		return nil, err
+	}
+	return &NATS{
+		conn:    conn,
+		subject: subject,
+	}, nil
+}
+
+func (n *NATS) Write(ctx context.Context, events []Event) error {
+	for _, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		if err := n.conn.Publish(n.subject, data); err != nil {
+			return err
+		}
+	}
+	// Waits for the server to receive the batch, unless
+	// the messages are buffered until the client reconnects
+	if !n.conn.IsConnected() {
+		return nil
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		return n.conn.FlushTimeout(writeTimeout)
+	}
+	return n.conn.FlushWithContext(ctx)
+}
+
+// Close sends the buffered messages and closes the connection
+func (n *NATS) Close() error {
+	defer n.conn.Close()
+	if !n.conn.IsConnected() {
+		return nil
+	}
+	return n.conn.FlushTimeout(writeTimeout)
+}
+
+var _ Sink = &NATS{}
@@ -0,0 +1,41 @@
+package clicksink
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	natsserver "github.com/nats-io/nats-server/v2/test"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestNATS(t *testing.T) {
+	opts := natsserver.DefaultTestOptions
+	opts.Port = server.RANDOM_PORT
+	srv := natsserver.RunServer(&opts)
+	defer srv.Shutdown()
+
+	conn, err := nats.Connect(srv.ClientURL())
+	require.NoError(t, err)
+	defer conn.Close()
+	messages := make(chan *nats.Msg, 10)
+	_, err = conn.ChanSubscribe("smurl.clicks", messages)
+	require.NoError(t, err)
+	require.NoError(t, conn.Flush())
+
+	sink, err := NewNATS(srv.ClientURL(), "smurl.clicks", zap.L())
+	require.NoError(t, err)
+	require.NoError(t, sink.Write(ctx, []Event{testEvent("abc"), testEvent("def")}))
+	require.NoError(t, sink.Close())
+
+	for _, smallURL := range []string{"abc", "def"} {
+		select {
+		case msg := <-messages:
+			require.Contains(t, string(msg.Data), `"small_url":"`+smallURL+`"`)
+		case <-time.After(time.Second):
+			t.Fatalf("no message for %s", smallURL)
+		}
+	}
+}
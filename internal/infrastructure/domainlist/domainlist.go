@@ -0,0 +1,204 @@
+package domainlist
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/sanyarise/smurl/internal/models"
+	"github.com/sanyarise/smurl/internal/usecase"
+	"go.uber.org/zap"
+	"golang.org/x/net/idna"
+)
+
+var _ usecase.DomainPolicy = &DomainList{}
+
+// Structure of the domain list file:
+//
+//	[allow]
+//	domains = ["example.com", "*.example.com"]
+//	regexps = ['^intranet-\d+\.example\.org$']
+//
+//	[deny]
+//	domains = ["phishing.example"]
+//
+// Entries of domains are exact host names or wildcards
+// of the form "*.example.com" matching any subdomain. Internationalized
+// names are converted to punycode, as the hosts of the long urls are
+type file struct {
+	Allow section `toml:"allow"`
+	Deny  section `toml:"deny"`
+}
+
+type section struct {
+	Domains []string `toml:"domains"`
+	Regexps []string `toml:"regexps"`
+}
+
+// Compiled set of rules of one section
+type rules struct {
+	exact     map[string]bool
+	wildcards []string
+	regexps   []*regexp.Regexp
+}
+
+// DomainList checks destination hosts against allow and deny lists
+// loaded from a file. If the allow list is empty, every host that
+// is not denied is allowed
+type DomainList struct {
+	mu      sync.RWMutex
+	allow   rules
+	deny    rules
+	path    string
+	modTime time.Time
+	logger  *zap.Logger
+}
+
+// NewDomainList loads the lists from the file at path.
+// With an empty path all destinations are allowed
+func NewDomainList(path string, logger *zap.Logger) (*DomainList, error) {
+	logger.Debug("Enter in domainlist NewDomainList()")
+	list := &DomainList{
+		path:   path,
+		logger: logger,
+	}
+	if path == "" {
+		return list, nil
+	}
+	if err := list.load(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Check returns models.ErrBlocked if host is denied
+// or is missing from a non-empty allow list
+func (list *DomainList) Check(host string) error {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	list.mu.RLock()
+	defer list.mu.RUnlock()
+	if list.deny.match(host) {
+		return fmt.Errorf("domain %s is in the deny list: %w", host, models.ErrBlocked)
+	}
+	if !list.allow.empty() && !list.allow.match(host) {
+		return fmt.Errorf("domain %s is not in the allow list: %w", host, models.ErrBlocked)
+	}
+	return nil
+}
+
+// Watch reloads the lists every interval when the file was modified,
+// until the context is done. A file that fails to load is reported
+// and the previous lists stay in effect
+func (list *DomainList) Watch(ctx context.Context, interval time.Duration) {
+	if list.path == "" || interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			info, err := os.Stat(list.path)
+			if err != nil {
+				list.logger.Error("error on stat domain list file",
+					zap.Error(err))
+				continue
+			}
+			list.mu.RLock()
+			modified := !info.ModTime().Equal(list.modTime)
+			list.mu.RUnlock()
+			if !modified {
+				continue
+			}
+			if err := list.load(); err != nil {
+				list.logger.Error("error on reload domain list",
+					zap.Error(err))
+				continue
+			}
+			list.logger.Info("Domain list reloaded",
+				zap.String("path", list.path))
+		}
+	}
+}
+
+// load reads and compiles the lists, replacing the current ones
+func (list *DomainList) load() error {
+	info, err := os.Stat(list.path)
+	if err != nil {
+		return fmt.Errorf("can't load domain list: %w", err)
+	}
+	var f file
+	if _, err := toml.DecodeFile(list.path, &f); err != nil {
+		return fmt.Errorf("can't decode domain list: %w", err)
+	}
+	allow, err := compile(f.Allow)
+	if err != nil {
+		return fmt.Errorf("can't compile allow list: %w", err)
+	}
+	deny, err := compile(f.Deny)
+	if err != nil {
+		return fmt.Errorf("can't compile deny list: %w", err)
+	}
+	list.mu.Lock()
+	list.allow = allow
+	list.deny = deny
+	list.modTime = info.ModTime()
+	list.mu.Unlock()
+	return nil
+}
+
+func compile(s section) (rules, error) {
+	r := rules{exact: make(map[string]bool)}
+	for _, domain := range s.Domains {
+		domain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+		if domain == "" {
+			continue
+		}
+		wildcard := strings.HasPrefix(domain, "*.")
+		ascii, err := idna.Lookup.ToASCII(strings.TrimPrefix(domain, "*."))
+		if err != nil {
+			return rules{}, fmt.Errorf("domain %q: %w", domain, err)
+		}
+		if wildcard {
+			r.wildcards = append(r.wildcards, "."+ascii)
+			continue
+		}
+		r.exact[ascii] = true
+	}
+	for _, expr := range s.Regexps {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return rules{}, err
+		}
+		r.regexps = append(r.regexps, re)
+	}
+	return r, nil
+}
+
+func (r rules) empty() bool {
+	return len(r.exact) == 0 && len(r.wildcards) == 0 && len(r.regexps) == 0
+}
+
+func (r rules) match(host string) bool {
+	if r.exact[host] {
+		return true
+	}
+	for _, suffix := range r.wildcards {
+		if strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	for _, re := range r.regexps {
+		if re.MatchString(host) {
+			return true
+		}
+	}
+	return false
+}
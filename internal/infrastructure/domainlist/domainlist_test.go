@@ -0,0 +1,111 @@
+package domainlist
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sanyarise/smurl/internal/models"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const testList = `
+[allow]
+domains = ["example.com", "*.example.com"]
+regexps = ['^intranet-\d+\.example\.org$']
+
+[deny]
+domains = ["bad.example.com", "*.evil.example.com"]
+`
+
+func writeList(t *testing.T, path string, content string, modTime time.Time) {
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func TestCheck(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "domains.toml")
+	writeList(t, path, testList, time.Now())
+	list, err := NewDomainList(path, zap.L())
+	require.NoError(t, err)
+
+	var tests = []struct {
+		host    string
+		blocked bool
+	}{
+		{"example.com", false},
+		{"EXAMPLE.com.", false},
+		{"www.example.com", false},
+		{"intranet-12.example.org", false},
+		{"bad.example.com", true},
+		{"a.evil.example.com", true},
+		{"example.org", true},
+		{"notexample.com", true},
+	}
+	for _, test := range tests {
+		err := list.Check(test.host)
+		if test.blocked {
+			require.ErrorIs(t, err, models.ErrBlocked, test.host)
+		} else {
+			require.NoError(t, err, test.host)
+		}
+	}
+}
+
+func TestCheckUnicode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "domains.toml")
+	writeList(t, path, "[deny]\ndomains = [\"Пример.рф\", \"*.плохой.рф\"]\n", time.Now())
+	list, err := NewDomainList(path, zap.L())
+	require.NoError(t, err)
+
+	// Hosts are checked in punycode
+	require.ErrorIs(t, list.Check("xn--e1afmkfd.xn--p1ai"), models.ErrBlocked)
+	require.ErrorIs(t, list.Check("www.xn--i1adjac2b.xn--p1ai"), models.ErrBlocked)
+	require.NoError(t, list.Check("xn--i1adjac2b.xn--p1ai"))
+	require.NoError(t, list.Check("example.com"))
+
+	writeList(t, path, "[deny]\ndomains = [\"bad_name.example\"]\n", time.Now())
+	_, err = NewDomainList(path, zap.L())
+	require.Error(t, err)
+}
+
+func TestCheckWithoutFile(t *testing.T) {
+	list, err := NewDomainList("", zap.L())
+	require.NoError(t, err)
+	require.NoError(t, list.Check("anything.example"))
+}
+
+func TestInvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "domains.toml")
+	writeList(t, path, "[deny]\nregexps = ['(']\n", time.Now())
+	_, err := NewDomainList(path, zap.L())
+	require.Error(t, err)
+
+	_, err = NewDomainList(filepath.Join(t.TempDir(), "missing.toml"), zap.L())
+	require.Error(t, err)
+}
+
+func TestWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "domains.toml")
+	writeList(t, path, "", time.Now().Add(-time.Hour))
+	list, err := NewDomainList(path, zap.L())
+	require.NoError(t, err)
+	require.NoError(t, list.Check("bad.example.com"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go list.Watch(ctx, 10*time.Millisecond)
+
+	writeList(t, path, testList, time.Now())
+	require.Eventually(t, func() bool {
+		return list.Check("bad.example.com") != nil
+	}, time.Second, 10*time.Millisecond)
+
+	// A broken file keeps the previous lists
+	writeList(t, path, "[deny", time.Now().Add(time.Minute))
+	time.Sleep(50 * time.Millisecond)
+	require.ErrorIs(t, list.Check("bad.example.com"), models.ErrBlocked)
+}
@@ -0,0 +1,80 @@
+package geoip
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/oschwald/maxminddb-golang"
+	"github.com/sanyarise/smurl/internal/models"
+	"github.com/sanyarise/smurl/internal/usecase"
+	"go.uber.org/zap"
+)
+
+var _ usecase.GeoLocator = &GeoIP{}
+
+// Fields of a GeoIP2/GeoLite2 City or Country database record
+type record struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	Subdivisions []struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"subdivisions"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+}
+
+// GeoIP locates IP addresses using a local MaxMind-format database
+type GeoIP struct {
+	db     *maxminddb.Reader
+	logger *zap.Logger
+}
+
+// NewGeoIP opens the database at path. With an empty path
+// no database is used and every address has an empty location
+func NewGeoIP(path string, logger *zap.Logger) (*GeoIP, error) {
+	logger.Debug("Enter in geoip NewGeoIP()")
+	geo := &GeoIP{logger: logger}
+	if path == "" {
+		logger.Info("GeoIP database is not configured")
+		return geo, nil
+	}
+	db, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("can't open geoip database: %w", err)
+	}
+	logger.Info("GeoIP database loaded",
+		zap.String("type", db.Metadata.DatabaseType))
+	geo.db = db
+	return geo, nil
+}
+
+// Locate returns the country, region and city of an IP address.
+// Unknown fields are empty
+func (geo *GeoIP) Locate(ip string) models.Location {
+	var location models.Location
+	netIP := net.ParseIP(ip)
+	if geo.db == nil || netIP == nil {
+		return location
+	}
+	var r record
+	if err := geo.db.Lookup(netIP, &r); err != nil {
+		geo.logger.Debug("error on geoip lookup",
+			zap.Error(err))
+		return location
+	}
+	location.Country = r.Country.ISOCode
+	if len(r.Subdivisions) > 0 {
+		location.Region = r.Subdivisions[0].Names["en"]
+	}
+	location.City = r.City.Names["en"]
+	return location
+}
+
+func (geo *GeoIP) Close() {
+	geo.logger.Debug("Enter in geoip Close()")
+	if geo.db != nil {
+		geo.db.Close()
+	}
+}
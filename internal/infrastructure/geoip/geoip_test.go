@@ -0,0 +1,62 @@
+package geoip
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/maxmind/mmdbwriter"
+	"github.com/maxmind/mmdbwriter/mmdbtype"
+	"github.com/sanyarise/smurl/internal/models"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// writeTestDatabase creates a city database with two networks
+func writeTestDatabase(t *testing.T) string {
+	tree, err := mmdbwriter.New(mmdbwriter.Options{DatabaseType: "GeoIP2-City", RecordSize: 24})
+	require.NoError(t, err)
+
+	_, london, _ := net.ParseCIDR("81.2.69.0/24")
+	require.NoError(t, tree.Insert(london, mmdbtype.Map{
+		"country": mmdbtype.Map{"iso_code": mmdbtype.String("GB")},
+		"subdivisions": mmdbtype.Slice{
+			mmdbtype.Map{"names": mmdbtype.Map{"en": mmdbtype.String("England")}},
+		},
+		"city": mmdbtype.Map{"names": mmdbtype.Map{"en": mmdbtype.String("London")}},
+	}))
+	_, germany, _ := net.ParseCIDR("2a02:d800::/32")
+	require.NoError(t, tree.Insert(germany, mmdbtype.Map{
+		"country": mmdbtype.Map{"iso_code": mmdbtype.String("DE")},
+	}))
+
+	path := filepath.Join(t.TempDir(), "test.mmdb")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	_, err = tree.WriteTo(f)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	return path
+}
+
+func TestLocate(t *testing.T) {
+	geo, err := NewGeoIP(writeTestDatabase(t), zap.L())
+	require.NoError(t, err)
+	defer geo.Close()
+
+	require.Equal(t, models.Location{Country: "GB", Region: "England", City: "London"}, geo.Locate("81.2.69.142"))
+	require.Equal(t, models.Location{Country: "DE"}, geo.Locate("2a02:d800::1"))
+	require.Equal(t, models.Location{}, geo.Locate("8.8.8.8"))
+	require.Equal(t, models.Location{}, geo.Locate("unknown ip"))
+}
+
+func TestLocateWithoutDatabase(t *testing.T) {
+	geo, err := NewGeoIP("", zap.L())
+	require.NoError(t, err)
+	defer geo.Close()
+	require.Equal(t, models.Location{}, geo.Locate("81.2.69.142"))
+
+	_, err = NewGeoIP(filepath.Join(t.TempDir(), "missing.mmdb"), zap.L())
+	require.Error(t, err)
+}
@@ -0,0 +1,61 @@
+package retention
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Interface for deleting click details older than a moment
+type Purger interface {
+	PurgeClicks(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Retention periodically deletes click details
+// that are older than the retention period
+type Retention struct {
+	purger Purger
+	period time.Duration
+	logger *zap.Logger
+}
+
+// NewRetention creates the job, a period of zero keeps clicks forever
+func NewRetention(purger Purger, period time.Duration, logger *zap.Logger) *Retention {
+	logger.Debug("Enter in retention NewRetention()")
+	return &Retention{
+		purger: purger,
+		period: period,
+		logger: logger,
+	}
+}
+
+// Run purges the clicks at once and then every interval,
+// until the context is done
+func (r *Retention) Run(ctx context.Context, interval time.Duration) {
+	if r.period <= 0 || interval <= 0 {
+		r.logger.Info("Click retention is disabled")
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		r.purge(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Retention) purge(ctx context.Context) {
+	purged, err := r.purger.PurgeClicks(ctx, time.Now().Add(-r.period))
+	if err != nil {
+		r.logger.Error("error on purge clicks",
+			zap.Error(err))
+		return
+	}
+	r.logger.Info("Expired clicks purged",
+		zap.Int64("count", purged))
+}
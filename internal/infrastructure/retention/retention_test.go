@@ -0,0 +1,58 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type testPurger struct {
+	mu     sync.Mutex
+	before []time.Time
+	err    error
+}
+
+func (p *testPurger) PurgeClicks(ctx context.Context, before time.Time) (int64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.before = append(p.before, before)
+	return 1, p.err
+}
+
+func (p *testPurger) calls() []time.Time {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]time.Time(nil), p.before...)
+}
+
+func TestRun(t *testing.T) {
+	purger := &testPurger{err: errors.New("test error")}
+	r := NewRetention(purger, 24*time.Hour, zap.L())
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		r.Run(ctx, 10*time.Millisecond)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool {
+		return len(purger.calls()) >= 2
+	}, time.Second, 5*time.Millisecond)
+	cancel()
+	<-done
+
+	before := purger.calls()[0]
+	require.WithinDuration(t, time.Now().Add(-24*time.Hour), before, time.Second)
+}
+
+func TestRunDisabled(t *testing.T) {
+	purger := &testPurger{}
+	r := NewRetention(purger, 0, zap.L())
+	r.Run(context.Background(), time.Millisecond)
+	require.Empty(t, purger.calls())
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"net"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+// GRPCServer serves a gRPC server on its own port
+type GRPCServer struct {
+	srv    *grpc.Server
+	addr   string
+	logger *zap.Logger
+}
+
+func NewGRPCServer(addr string, srv *grpc.Server, l *zap.Logger) *GRPCServer {
+	l.Debug("Enter in server func NewGRPCServer()")
+	return &GRPCServer{
+		srv:    srv,
+		addr:   addr,
+		logger: l,
+	}
+}
+
+// Start listens on the address and serves the calls in the background
+func (s *GRPCServer) Start() error {
+	lis, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	go func() {
+		if err := s.srv.Serve(lis); err != nil {
+			s.logger.Error("error on serve grpc",
+				zap.Error(err))
+		}
+	}()
+	return nil
+}
+
+// Stop waits for the running calls for up to 2 seconds, then closes them
+func (s *GRPCServer) Stop() {
+	s.logger.Debug("Enter in server func GRPCServer Stop()")
+	stopped := make(chan struct{})
+	go func() {
+		s.srv.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(2 * time.Second):
+		s.srv.Stop()
+	}
+}
@@ -0,0 +1,201 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/sanyarise/smurl/internal/models"
+	"go.uber.org/zap"
+)
+
+const (
+	// Events and deliveries handled per query
+	batchSize = 100
+	// Longest wait between two attempts
+	maxBackoff = 24 * time.Hour
+	// Bytes of the responses read, the rest is ignored
+	maxResponse = 64 << 10
+)
+
+// Interface for the outbox and the deliveries of the events
+type Store interface {
+	QueueExpired(ctx context.Context, now time.Time) (int64, error)
+	FanOutEvents(ctx context.Context, now time.Time, limit int) (int64, error)
+	ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error)
+	RecordAttempt(ctx context.Context, attempt models.WebhookAttempt, delivery models.WebhookDelivery) error
+}
+
+// Payload is the body of the requests sent to webhooks
+type Payload struct {
+	ID        int64            `json:"id"`
+	Type      models.EventType `json:"type"`
+	CreatedAt time.Time        `json:"created_at"`
+	Data      json.RawMessage  `json:"data"`
+}
+
+// Dispatcher periodically sends the events of the outbox to the
+// webhooks, and retries failed deliveries with exponential backoff
+type Dispatcher struct {
+	store       Store
+	client      *http.Client
+	maxAttempts int
+	backoff     time.Duration
+	logger      *zap.Logger
+}
+
+// NewDispatcher creates the job, the first retry of a delivery
+// is after backoff, and it fails after maxAttempts attempts
+func NewDispatcher(store Store, client *http.Client, maxAttempts int, backoff time.Duration, logger *zap.Logger) *Dispatcher {
+	logger.Debug("Enter in webhook NewDispatcher()")
+	return &Dispatcher{
+		store:       store,
+		client:      client,
+		maxAttempts: maxAttempts,
+		backoff:     backoff,
+		logger:      logger,
+	}
+}
+
+// Run dispatches the events at once and then every interval,
+// until the context is done
+func (d *Dispatcher) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		d.logger.Info("Webhooks are disabled")
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		d.Dispatch(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Dispatch queues the events of the links expired since the last call,
+// creates the deliveries of the new events and sends the due deliveries
+func (d *Dispatcher) Dispatch(ctx context.Context) {
+	if _, err := d.store.QueueExpired(ctx, time.Now()); err != nil {
+		d.logger.Error("error on queue expired events",
+			zap.Error(err))
+	}
+	for {
+		events, err := d.store.FanOutEvents(ctx, time.Now(), batchSize)
+		if err != nil {
+			d.logger.Error("error on fan out events",
+				zap.Error(err))
+			break
+		}
+		if events < batchSize {
+			break
+		}
+	}
+	for ctx.Err() == nil {
+		// A delivery is sent again by another dispatcher if
+		// this one stops before the end of the request
+		deliveries, err := d.store.ClaimDeliveries(ctx, time.Now(), d.client.Timeout+time.Minute, batchSize)
+		if err != nil {
+			d.logger.Error("error on claim deliveries",
+				zap.Error(err))
+			return
+		}
+		for _, delivery := range deliveries {
+			d.deliver(ctx, delivery)
+		}
+		if len(deliveries) < batchSize {
+			return
+		}
+	}
+}
+
+// deliver sends a delivery once and saves the attempt
+func (d *Dispatcher) deliver(ctx context.Context, delivery models.WebhookDelivery) {
+	start := time.Now()
+	status, err := d.send(ctx, delivery, start)
+	delivery.Attempts++
+	attempt := models.WebhookAttempt{
+		DeliveryID: delivery.ID,
+		WebhookID:  delivery.Webhook.ID,
+		EventType:  delivery.Event.Type,
+		SmallURL:   delivery.Event.SmallURL,
+		Attempt:    delivery.Attempts,
+		At:         start,
+		StatusCode: status,
+		Duration:   time.Since(start),
+	}
+	switch {
+	case err == nil:
+		delivery.Status = models.DeliveryDelivered
+		delivery.DeliveredAt = start
+	case delivery.Attempts >= d.maxAttempts:
+		attempt.Error = err.Error()
+		delivery.Status = models.DeliveryFailed
+	default:
+		attempt.Error = err.Error()
+		delivery.NextAttemptAt = start.Add(d.retryAfter(delivery.Attempts))
+	}
+	if err != nil {
+		d.logger.Warn("webhook delivery failed",
+			zap.Int64("webhook", delivery.Webhook.ID),
+			zap.Int64("delivery", delivery.ID),
+			zap.Int("attempt", delivery.Attempts),
+			zap.Error(err))
+	}
+	if err := d.store.RecordAttempt(ctx, attempt, delivery); err != nil {
+		d.logger.Error("error on record attempt",
+			zap.Error(err))
+	}
+}
+
+// send posts the signed payload of a delivery, any 2xx status is a success
+func (d *Dispatcher) send(ctx context.Context, delivery models.WebhookDelivery, at time.Time) (int, error) {
+	body, err := json.Marshal(Payload{
+		ID:        delivery.Event.ID,
+		Type:      delivery.Event.Type,
+		CreatedAt: delivery.Event.CreatedAt,
+		Data:      delivery.Event.Data,
+	})
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "smurl-webhooks")
+	req.Header.Set(EventHeader, string(delivery.Event.Type))
+	req.Header.Set(DeliveryHeader, fmt.Sprint(delivery.ID))
+	req.Header.Set(SignatureHeader, Sign(delivery.Webhook.Secret, at, body))
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// Reading the response lets the connection be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponse))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// retryAfter returns the wait after the attempts, doubled by attempt
+func (d *Dispatcher) retryAfter(attempts int) time.Duration {
+	wait := d.backoff
+	for i := 1; i < attempts && wait < maxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > maxBackoff {
+		wait = maxBackoff
+	}
+	return wait
+}
@@ -0,0 +1,178 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sanyarise/smurl/internal/models"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type testStore struct {
+	mu         sync.Mutex
+	deliveries []models.WebhookDelivery
+	attempts   []models.WebhookAttempt
+	expired    int
+	fanOuts    int
+}
+
+func (s *testStore) QueueExpired(ctx context.Context, now time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expired++
+	return 0, nil
+}
+
+func (s *testStore) FanOutEvents(ctx context.Context, now time.Time, limit int) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fanOuts++
+	return 0, errors.New("test error")
+}
+
+// ClaimDeliveries returns the pending deliveries that are due
+func (s *testStore) ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var due []models.WebhookDelivery
+	for i, d := range s.deliveries {
+		if d.Status == models.DeliveryPending && !d.NextAttemptAt.After(now) {
+			s.deliveries[i].NextAttemptAt = now.Add(lease)
+			due = append(due, d)
+		}
+	}
+	return due, nil
+}
+
+func (s *testStore) RecordAttempt(ctx context.Context, attempt models.WebhookAttempt, delivery models.WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts = append(s.attempts, attempt)
+	for i, d := range s.deliveries {
+		if d.ID == delivery.ID {
+			s.deliveries[i] = delivery
+		}
+	}
+	return nil
+}
+
+func newDelivery(id int64, url string) models.WebhookDelivery {
+	return models.WebhookDelivery{
+		ID:      id,
+		Webhook: models.Webhook{ID: 1, URL: url, Secret: "whsec_test"},
+		Event: models.Event{
+			ID:        id * 10,
+			Type:      models.EventCreated,
+			SmallURL:  "abc",
+			Data:      json.RawMessage(`{"small_url":"abc","long_url":"http://mail.ru"}`),
+			CreatedAt: time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC),
+		},
+		Status: models.DeliveryPending,
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	var mu sync.Mutex
+	var bodies [][]byte
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		require.NoError(t, Verify("whsec_test", r.Header.Get(SignatureHeader), body, time.Minute, time.Now()))
+		require.Equal(t, "link.created", r.Header.Get(EventHeader))
+		require.Equal(t, "1", r.Header.Get(DeliveryHeader))
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		bodies = append(bodies, body)
+		// The first attempt fails
+		if calls == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	store := &testStore{deliveries: []models.WebhookDelivery{newDelivery(1, srv.URL)}}
+	d := NewDispatcher(store, &http.Client{Timeout: time.Second}, 3, time.Hour, zap.L())
+	d.Dispatch(context.Background())
+	require.Equal(t, 1, store.expired)
+	require.Equal(t, 1, store.fanOuts)
+	require.Len(t, store.attempts, 1)
+	require.Equal(t, 500, store.attempts[0].StatusCode)
+	require.Equal(t, "unexpected status 500", store.attempts[0].Error)
+	delivery := store.deliveries[0]
+	require.Equal(t, models.DeliveryPending, delivery.Status)
+	require.WithinDuration(t, time.Now().Add(time.Hour), delivery.NextAttemptAt, time.Second)
+
+	// Not retried before the backoff
+	d.Dispatch(context.Background())
+	require.Len(t, store.attempts, 1)
+
+	store.deliveries[0].NextAttemptAt = time.Now()
+	d.Dispatch(context.Background())
+	require.Len(t, store.attempts, 2)
+	require.Equal(t, 2, store.attempts[1].Attempt)
+	require.Empty(t, store.attempts[1].Error)
+	delivery = store.deliveries[0]
+	require.Equal(t, models.DeliveryDelivered, delivery.Status)
+	require.Equal(t, 2, delivery.Attempts)
+	require.False(t, delivery.DeliveredAt.IsZero())
+
+	var payload Payload
+	require.NoError(t, json.Unmarshal(bodies[1], &payload))
+	require.Equal(t, int64(10), payload.ID)
+	require.Equal(t, models.EventCreated, payload.Type)
+	require.JSONEq(t, `{"small_url":"abc","long_url":"http://mail.ru"}`, string(payload.Data))
+}
+
+func TestDispatchFails(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer srv.Close()
+
+	store := &testStore{deliveries: []models.WebhookDelivery{newDelivery(1, srv.URL)}}
+	d := NewDispatcher(store, &http.Client{Timeout: time.Second}, 2, time.Millisecond, zap.L())
+	d.Dispatch(context.Background())
+	time.Sleep(5 * time.Millisecond)
+	d.Dispatch(context.Background())
+	require.Len(t, store.attempts, 2)
+	require.Equal(t, models.DeliveryFailed, store.deliveries[0].Status)
+
+	// Failed deliveries are not sent again
+	d.Dispatch(context.Background())
+	require.Len(t, store.attempts, 2)
+}
+
+func TestRetryAfter(t *testing.T) {
+	d := NewDispatcher(&testStore{}, http.DefaultClient, 10, time.Minute, zap.L())
+	require.Equal(t, time.Minute, d.retryAfter(1))
+	require.Equal(t, 2*time.Minute, d.retryAfter(2))
+	require.Equal(t, 8*time.Minute, d.retryAfter(4))
+	require.Equal(t, maxBackoff, d.retryAfter(100))
+}
+
+func TestRunDisabled(t *testing.T) {
+	store := &testStore{}
+	NewDispatcher(store, http.DefaultClient, 10, time.Minute, zap.L()).Run(context.Background(), 0)
+	require.Zero(t, store.expired)
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1650000000, 0)
+	body := []byte(`{"id":1}`)
+	header := Sign("secret", now, body)
+	require.Regexp(t, `^t=1650000000,v1=[0-9a-f]{64}$`, header)
+	require.NoError(t, Verify("secret", header, body, time.Minute, now.Add(time.Second)))
+	require.ErrorIs(t, Verify("other", header, body, time.Minute, now), ErrBadSignature)
+	require.ErrorIs(t, Verify("secret", header, []byte(`{"id":2}`), time.Minute, now), ErrBadSignature)
+	require.ErrorIs(t, Verify("secret", header, body, time.Minute, now.Add(time.Hour)), ErrExpired)
+	require.Error(t, Verify("secret", "v1=00", body, time.Minute, now))
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// Filter of the small urls listed by an operator
+type ListFilter struct {
+	// Part of the small or the long url, empty for all
+	Query string
+	// Whether deleted small urls are listed
+	Deleted bool
+	Limit   int
+	Offset  int
+}
+
+// APIKey allows a service to call the gRPC API. Only
+// a SHA-256 hash of the key is stored
+type APIKey struct {
+	ID        int64
+	Name      string
+	Hash      string
+	CreatedAt time.Time
+	// Time the key was revoked, zero while it is valid
+	RevokedAt time.Time
+}
+
+// Revoked reports whether the key was revoked
+func (k APIKey) Revoked() bool {
+	return !k.RevokedAt.IsZero()
+}
@@ -0,0 +1,79 @@
+package models
+
+import "time"
+
+// Information about a single click on a small url
+type Click struct {
+	SmallURL       string
+	CreatedAt      time.Time
+	IP             string
+	VisitorID      string
+	Referer        string
+	RefererDomain  string
+	UserAgent      string
+	Browser        string
+	OS             string
+	Device         string
+	AcceptLanguage string
+	Bot            bool
+	// ID of the variant the click was redirected to, 0 if none
+	Variant int64
+	Location
+}
+
+// Geographic location of a client address
+type Location struct {
+	Country string
+	Region  string
+	City    string
+}
+
+// Number of clicks for one value of a statistics dimension
+type StatItem struct {
+	Name  string
+	Count uint64
+}
+
+// Breakdown of clicks by referring domain, by browser,
+// operating system and device class and by country
+type ClickStats struct {
+	Referrers []StatItem
+	Browsers  []StatItem
+	OS        []StatItem
+	Devices   []StatItem
+	Countries []StatItem
+}
+
+// Size of a time series bucket
+type Granularity string
+
+const (
+	Hourly Granularity = "hour"
+	Daily  Granularity = "day"
+)
+
+// Duration returns the length of one bucket
+func (g Granularity) Duration() time.Duration {
+	if g == Hourly {
+		return time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// Truncate returns the start of the bucket containing t, in UTC
+func (g Granularity) Truncate(t time.Time) time.Time {
+	return t.UTC().Truncate(g.Duration())
+}
+
+// Number of clicks in the bucket starting at Start
+type TimeBucket struct {
+	Start time.Time `json:"start"`
+	Count uint64    `json:"count"`
+}
+
+// Rollup is a bucket of the hourly or the daily time series of a small url
+type Rollup struct {
+	Granularity Granularity
+	Start       time.Time
+	Count       uint64
+}
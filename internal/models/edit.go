@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// Changes of a small url, nil fields are kept
+type Edit struct {
+	LongURL *string
+	// A zero time removes the expiry
+	ExpiresAt   *time.Time
+	Passthrough *Passthrough
+	Sticky      *bool
+}
+
+// Empty reports whether the edit changes nothing
+func (e Edit) Empty() bool {
+	return e.LongURL == nil && e.ExpiresAt == nil && e.Passthrough == nil && e.Sticky == nil
+}
@@ -5,5 +5,25 @@ import (
 )
 
 var (
-	ErrNotFound = errors.New("not found")
+	ErrNotFound    = errors.New("not found")
+	ErrExpired     = errors.New("expired")
+	ErrDeleted     = errors.New("deleted")
+	ErrDisabled    = errors.New("disabled")
+	ErrBlocked     = errors.New("blocked")
+	ErrValidation  = errors.New("validation error")
+	ErrConflict    = errors.New("conflict")
+	ErrRateLimited = errors.New("rate limited")
 )
+
+// ValidationError describes invalid input, it matches ErrValidation
+type ValidationError struct {
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Reason
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrValidation
+}
@@ -0,0 +1,16 @@
+package models
+
+// Routing rule of a small url. A click matching all non-empty
+// conditions of a rule is redirected to its URL. Rules are
+// evaluated in order of Position before falling back to LongURL
+type Rule struct {
+	ID       int64
+	Position int
+	// Operating system of the user agent, e.g. iOS or Android
+	Platform string
+	// ISO 3166-1 country code of the client address
+	Country string
+	// Primary language subtag of the preferred language, e.g. ru
+	Language string
+	URL      string
+}
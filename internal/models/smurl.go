@@ -4,11 +4,63 @@ import "time"
 
 // The internal structure of the Smurl object
 type Smurl struct {
-	CreatedAt time.Time
+	CreatedAt  time.Time
 	ModifiedAt time.Time
-	SmallURL string 
-	LongURL  string
-	AdminURL string
-	IPInfo   []string
-	Count    uint64
+	SmallURL   string
+	LongURL    string
+	AdminURL   string
+	IPInfo     []string
+	Count      uint64
+	Uniques    uint64
+	BotCount   uint64
+	// Clicks counted by the shortener the small url was imported from
+	ImportedClicks uint64
+	Stats          ClickStats
+	Rules          []Rule
+	Variants       []Variant
+	// Buckets of the time series, only read by exports
+	Rollups []Rollup
+	// Whether a visitor keeps getting the variant chosen on the first click
+	Sticky      bool
+	Passthrough Passthrough
+	// Time after which the small url stops redirecting, zero for never
+	ExpiresAt time.Time
+	// Time the small url was deleted, zero while it exists
+	DeletedAt time.Time
+	// Time the small url was disabled by an operator, zero while enabled
+	DisabledAt time.Time
+	// API key that created the small url, zero for none
+	OwnerID int64
+}
+
+// Expired reports whether the small url has expired at the given time
+func (s Smurl) Expired(now time.Time) bool {
+	return !s.ExpiresAt.IsZero() && !now.Before(s.ExpiresAt)
+}
+
+// Deleted reports whether the small url was deleted
+func (s Smurl) Deleted() bool {
+	return !s.DeletedAt.IsZero()
+}
+
+// Disabled reports whether the small url was disabled
+func (s Smurl) Disabled() bool {
+	return !s.DisabledAt.IsZero()
+}
+
+// Passthrough mode of a small url: whether the query parameters of
+// a click are forwarded to the destination and which win on conflict
+type Passthrough string
+
+const (
+	PassthroughOff Passthrough = ""
+	// Parameters already in the destination are kept
+	PassthroughKeep Passthrough = "keep"
+	// Parameters of the click replace those of the destination
+	PassthroughOverride Passthrough = "override"
+)
+
+// Valid reports whether the passthrough mode is known
+func (p Passthrough) Valid() bool {
+	return p == PassthroughOff || p == PassthroughKeep || p == PassthroughOverride
 }
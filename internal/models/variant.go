@@ -0,0 +1,12 @@
+package models
+
+// Weighted destination of a small url. When a small url has variants
+// every click not matched by a routing rule goes to one of them,
+// chosen with probability proportional to Weight
+type Variant struct {
+	ID     int64
+	URL    string
+	Weight int
+	// Number of clicks by people redirected to the variant
+	Count uint64
+}
@@ -0,0 +1,142 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// EventType is a kind of link event sent to webhooks
+type EventType string
+
+const (
+	EventCreated EventType = "link.created"
+	EventEdited  EventType = "link.edited"
+	EventDeleted EventType = "link.deleted"
+	EventExpired EventType = "link.expired"
+	// The clicks of a link reached a power of ten, from ten
+	EventMilestone EventType = "link.milestone"
+)
+
+// EventTypes lists the event types
+var EventTypes = []EventType{EventCreated, EventEdited, EventDeleted, EventExpired, EventMilestone}
+
+// Valid reports whether the event type is known
+func (t EventType) Valid() bool {
+	for _, known := range EventTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// IsMilestone reports whether a count of clicks is a milestone
+func IsMilestone(count uint64) bool {
+	if count < 10 {
+		return false
+	}
+	for count%10 == 0 {
+		count /= 10
+	}
+	return count == 1
+}
+
+// Event is a change of a link, written to the outbox
+// in the transaction of the change
+type Event struct {
+	ID       int64
+	Type     EventType
+	SmallURL string
+	// API key that created the link, zero for none
+	OwnerID   int64
+	Data      json.RawMessage
+	CreatedAt time.Time
+}
+
+// EventData is the data of an event sent to webhooks
+type EventData struct {
+	SmallURL    string     `json:"small_url"`
+	LongURL     string     `json:"long_url,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	Passthrough string     `json:"passthrough,omitempty"`
+	Sticky      bool       `json:"sticky,omitempty"`
+	// Clicks of a milestone
+	Count uint64 `json:"count,omitempty"`
+}
+
+// Webhook is a subscription to the events of the links of an owner
+type Webhook struct {
+	ID int64
+	// API key whose links are followed, zero for all links
+	OwnerID int64
+	URL     string
+	// Key of the signatures of the payloads
+	Secret string
+	// Event types sent, all when empty
+	Events    []EventType
+	CreatedAt time.Time
+	DeletedAt time.Time
+}
+
+// Wants reports whether the webhook is sent events of the type
+func (w Webhook) Wants(t EventType) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, event := range w.Events {
+		if event == t {
+			return true
+		}
+	}
+	return false
+}
+
+// DeliveryStatus is the state of the delivery of an event to a webhook
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliveryDelivered DeliveryStatus = "delivered"
+	// Every attempt failed
+	DeliveryFailed DeliveryStatus = "failed"
+)
+
+// WebhookDelivery is an event to be sent to a webhook
+type WebhookDelivery struct {
+	ID            int64
+	Webhook       Webhook
+	Event         Event
+	Status        DeliveryStatus
+	Attempts      int
+	NextAttemptAt time.Time
+	DeliveredAt   time.Time
+}
+
+// WebhookAttempt is an entry of the delivery log
+type WebhookAttempt struct {
+	DeliveryID int64
+	WebhookID  int64
+	EventType  EventType
+	SmallURL   string
+	// Number of the attempt, from 1
+	Attempt int
+	At      time.Time
+	// HTTP status of the response, zero without one
+	StatusCode int
+	Error      string
+	Duration   time.Duration
+}
+
+type ownerKey struct{}
+
+// WithOwner returns a context of calls by the API key
+func WithOwner(ctx context.Context, ownerID int64) context.Context {
+	return context.WithValue(ctx, ownerKey{}, ownerID)
+}
+
+// OwnerFrom returns the API key of the calls of the context, zero for none
+func OwnerFrom(ctx context.Context) int64 {
+	ownerID, _ := ctx.Value(ownerKey{}).(int64)
+	return ownerID
+}
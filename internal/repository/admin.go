@@ -0,0 +1,375 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/sanyarise/smurl/internal/models"
+	"go.uber.org/zap"
+)
+
+// likePattern matches the values containing the query in a LIKE expression
+func likePattern(query string) string {
+	query = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(query)
+	return "%" + query + "%"
+}
+
+// List reads the small urls matching the filter, the newest first.
+// The admin urls are not read
+func (repo *SmurlRepository) List(ctx context.Context, filter models.ListFilter) ([]models.Smurl, error) {
+	repo.logger.Debug("Enter in pgstore func List()")
+	pattern := ""
+	if filter.Query != "" {
+		pattern = likePattern(filter.Query)
+	}
+	rows, err := repo.db.Query(ctx, `SELECT small_url, created_at, modified_at, long_url, count, unique_count, bot_count,
+		imported_clicks, sticky, passthrough, expires_at, deleted_at, disabled_at FROM smurls
+		WHERE ($1 = '' OR small_url ILIKE $1 OR long_url ILIKE $1) AND ($2 OR deleted_at IS NULL)
+		ORDER BY created_at DESC, small_url LIMIT $3 OFFSET $4`,
+		pattern, filter.Deleted, filter.Limit, filter.Offset)
+	if err != nil {
+		repo.logger.Error("error on query in table",
+			zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+	var smurls []models.Smurl
+	for rows.Next() {
+		var s Smurl
+		if err := rows.Scan(&s.SmallURL, &s.CreatedAt, &s.ModifiedAt, &s.LongURL, &s.Count, &s.Uniques, &s.BotCount,
+			&s.Imported, &s.Sticky, &s.Passthrough, &s.ExpiresAt, &s.DeletedAt, &s.DisabledAt); err != nil {
+			repo.logger.Error("error on rows scan",
+				zap.Error(err))
+			return nil, err
+		}
+		smurls = append(smurls, models.Smurl{
+			SmallURL:       s.SmallURL,
+			CreatedAt:      s.CreatedAt,
+			ModifiedAt:     s.ModifiedAt,
+			LongURL:        s.LongURL,
+			Count:          s.Count,
+			Uniques:        s.Uniques,
+			BotCount:       s.BotCount,
+			ImportedClicks: s.Imported,
+			Sticky:         s.Sticky,
+			Passthrough:    models.Passthrough(s.Passthrough),
+			ExpiresAt:      timeOrZero(s.ExpiresAt),
+			DeletedAt:      timeOrZero(s.DeletedAt),
+			DisabledAt:     timeOrZero(s.DisabledAt),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		repo.logger.Error("error on rows read",
+			zap.Error(err))
+		return nil, err
+	}
+	return smurls, nil
+}
+
+// SetDisabled disables or enables a small url
+func (repo *SmurlRepository) SetDisabled(ctx context.Context, smallUrl string, disabled bool) error {
+	repo.logger.Debug("Enter in pgstore func SetDisabled()")
+	var disabledAt *time.Time
+	if disabled {
+		now := time.Now()
+		disabledAt = &now
+	}
+	// Disabling again keeps the time of the first disabling
+	tag, err := repo.db.Exec(ctx, `UPDATE smurls SET disabled_at = CASE WHEN $1::timestamptz IS NULL
+		THEN NULL ELSE COALESCE(disabled_at, $1) END WHERE small_url = $2`, disabledAt, smallUrl)
+	if err != nil {
+		repo.logger.Error("error on disable small url",
+			zap.Error(err))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrNotFound
+	}
+	return nil
+}
+
+// CreateAPIKey saves a key with the hash of its token
+func (repo *SmurlRepository) CreateAPIKey(ctx context.Context, key models.APIKey) (*models.APIKey, error) {
+	repo.logger.Debug("Enter in pgstore func CreateAPIKey()")
+	err := repo.db.QueryRow(ctx, `INSERT INTO api_keys (name, key_hash, created_at) VALUES ($1, $2, $3) RETURNING id`,
+		key.Name, key.Hash, key.CreatedAt).Scan(&key.ID)
+	if err != nil {
+		repo.logger.Error("error on create api key",
+			zap.Error(err))
+		return nil, err
+	}
+	return &key, nil
+}
+
+// ListAPIKeys reads the keys in the order of creation
+func (repo *SmurlRepository) ListAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	repo.logger.Debug("Enter in pgstore func ListAPIKeys()")
+	rows, err := repo.db.Query(ctx, `SELECT id, name, key_hash, created_at, revoked_at FROM api_keys ORDER BY id`)
+	if err != nil {
+		repo.logger.Error("error on query in table",
+			zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+	var keys []models.APIKey
+	for rows.Next() {
+		var key models.APIKey
+		var revokedAt *time.Time
+		if err := rows.Scan(&key.ID, &key.Name, &key.Hash, &key.CreatedAt, &revokedAt); err != nil {
+			repo.logger.Error("error on rows scan",
+				zap.Error(err))
+			return nil, err
+		}
+		key.RevokedAt = timeOrZero(revokedAt)
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		repo.logger.Error("error on rows read",
+			zap.Error(err))
+		return nil, err
+	}
+	return keys, nil
+}
+
+// FindAPIKey reads the key with the hash
+func (repo *SmurlRepository) FindAPIKey(ctx context.Context, hash string) (*models.APIKey, error) {
+	repo.logger.Debug("Enter in pgstore func FindAPIKey()")
+	var key models.APIKey
+	var revokedAt *time.Time
+	err := repo.db.QueryRow(ctx, `SELECT id, name, key_hash, created_at, revoked_at FROM api_keys WHERE key_hash = $1`,
+		hash).Scan(&key.ID, &key.Name, &key.Hash, &key.CreatedAt, &revokedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, models.ErrNotFound
+	}
+	if err != nil {
+		repo.logger.Error("error on find api key",
+			zap.Error(err))
+		return nil, err
+	}
+	key.RevokedAt = timeOrZero(revokedAt)
+	return &key, nil
+}
+
+// RevokeAPIKey revokes the key with the id
+func (repo *SmurlRepository) RevokeAPIKey(ctx context.Context, id int64) error {
+	repo.logger.Debug("Enter in pgstore func RevokeAPIKey()")
+	tag, err := repo.db.Exec(ctx, `UPDATE api_keys SET revoked_at = $1 WHERE id = $2 AND revoked_at IS NULL`,
+		time.Now(), id)
+	if err != nil {
+		repo.logger.Error("error on revoke api key",
+			zap.Error(err))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrNotFound
+	}
+	return nil
+}
+
+// Routing rule and variant as aggregated into JSON by ExportLinks
+type (
+	exportRule struct {
+		Position int    `json:"position"`
+		Platform string `json:"platform"`
+		Country  string `json:"country"`
+		Language string `json:"language"`
+		URL      string `json:"url"`
+	}
+	exportVariant struct {
+		URL    string `json:"url"`
+		Weight int    `json:"weight"`
+		Count  uint64 `json:"count"`
+	}
+	exportRollup struct {
+		Granularity string    `json:"granularity"`
+		Bucket      time.Time `json:"bucket"`
+		Count       uint64    `json:"count"`
+	}
+)
+
+// ExportLinks streams every small url in the order of creation. The
+// rules, the variants and the rollups are read in the same query as JSON
+func (repo *SmurlRepository) ExportLinks(ctx context.Context, fn func(models.Smurl) error) error {
+	repo.logger.Debug("Enter in pgstore func ExportLinks()")
+	rows, err := repo.db.Query(ctx, `SELECT s.small_url, s.admin_url, s.long_url, s.created_at, s.modified_at,
+		s.expires_at, s.deleted_at, s.disabled_at, s.count, s.unique_count, s.bot_count, s.imported_clicks,
+		s.sticky, s.passthrough, s.owner_id,
+		COALESCE((SELECT json_agg(json_build_object('position', r.position, 'platform', r.platform,
+			'country', r.country, 'language', r.language, 'url', r.url) ORDER BY r.position, r.id)
+			FROM routing_rules r WHERE r.small_url = s.small_url), '[]'),
+		COALESCE((SELECT json_agg(json_build_object('url', v.url, 'weight', v.weight, 'count', v.count) ORDER BY v.id)
+			FROM variants v WHERE v.small_url = s.small_url), '[]'),
+		COALESCE((SELECT json_agg(json_build_object('granularity', c.granularity, 'bucket', c.bucket, 'count', c.count)
+			ORDER BY c.granularity, c.bucket)
+			FROM click_rollups c WHERE c.small_url = s.small_url), '[]')
+		FROM smurls s ORDER BY s.created_at, s.small_url`)
+	if err != nil {
+		repo.logger.Error("error on query in table",
+			zap.Error(err))
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var s Smurl
+		var rulesJSON, variantsJSON, rollupsJSON []byte
+		if err := rows.Scan(&s.SmallURL, &s.AdminURL, &s.LongURL, &s.CreatedAt, &s.ModifiedAt,
+			&s.ExpiresAt, &s.DeletedAt, &s.DisabledAt, &s.Count, &s.Uniques, &s.BotCount, &s.Imported,
+			&s.Sticky, &s.Passthrough, &s.OwnerID, &rulesJSON, &variantsJSON, &rollupsJSON); err != nil {
+			repo.logger.Error("error on rows scan",
+				zap.Error(err))
+			return err
+		}
+		var rules []exportRule
+		var variants []exportVariant
+		if err := json.Unmarshal(rulesJSON, &rules); err != nil {
+			return err
+		}
+		if err := json.Unmarshal(variantsJSON, &variants); err != nil {
+			return err
+		}
+		var rollups []exportRollup
+		if err := json.Unmarshal(rollupsJSON, &rollups); err != nil {
+			return err
+		}
+		smurl := models.Smurl{
+			SmallURL:       s.SmallURL,
+			AdminURL:       s.AdminURL,
+			LongURL:        s.LongURL,
+			CreatedAt:      s.CreatedAt,
+			ModifiedAt:     s.ModifiedAt,
+			ExpiresAt:      timeOrZero(s.ExpiresAt),
+			DeletedAt:      timeOrZero(s.DeletedAt),
+			DisabledAt:     timeOrZero(s.DisabledAt),
+			Count:          s.Count,
+			Uniques:        s.Uniques,
+			BotCount:       s.BotCount,
+			ImportedClicks: s.Imported,
+			Sticky:         s.Sticky,
+			Passthrough:    models.Passthrough(s.Passthrough),
+			OwnerID:        s.OwnerID,
+		}
+		for _, rule := range rules {
+			smurl.Rules = append(smurl.Rules, models.Rule{
+				Position: rule.Position,
+				Platform: rule.Platform,
+				Country:  rule.Country,
+				Language: rule.Language,
+				URL:      rule.URL,
+			})
+		}
+		for _, variant := range variants {
+			smurl.Variants = append(smurl.Variants, models.Variant{URL: variant.URL, Weight: variant.Weight, Count: variant.Count})
+		}
+		for _, rollup := range rollups {
+			smurl.Rollups = append(smurl.Rollups, models.Rollup{Granularity: models.Granularity(rollup.Granularity),
+				Start: rollup.Bucket, Count: rollup.Count})
+		}
+		if err := fn(smurl); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		repo.logger.Error("error on rows read",
+			zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// takenCode returns ErrConflict with the small url holding the
+// code, or without one when only the admin code is taken
+func (repo *SmurlRepository) takenCode(ctx context.Context, smallUrl string) (*models.Smurl, error) {
+	existing := &models.Smurl{}
+	err := repo.db.QueryRow(ctx, `SELECT small_url, admin_url, long_url FROM smurls WHERE small_url = $1`,
+		smallUrl).Scan(&existing.SmallURL, &existing.AdminURL, &existing.LongURL)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, models.ErrConflict
+	}
+	if err != nil {
+		repo.logger.Error("error on find small url",
+			zap.Error(err))
+		return nil, err
+	}
+	return existing, models.ErrConflict
+}
+
+// nullTime returns nil for a zero time, which is stored as NULL
+func nullTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// ImportLink inserts a small url with its codes, times, counters, owner,
+// rules, variants and rollups in a transaction, unless one of its codes is taken
+func (repo *SmurlRepository) ImportLink(ctx context.Context, smurl models.Smurl) (*models.Smurl, error) {
+	repo.logger.Debug("Enter in pgstore func ImportLink()")
+	tx, err := repo.db.Begin(ctx)
+	if err != nil {
+		repo.logger.Error("error on begin transaction",
+			zap.Error(err))
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `INSERT INTO smurls (small_url, created_at, modified_at, long_url, admin_url,
+		count, unique_count, bot_count, imported_clicks, sticky, passthrough, expires_at, deleted_at, disabled_at,
+		ip_info, admin_hashed, owner_id)
+		SELECT $1, $2, $3, $4, $5, $6, $7, $8, $14, $9, $10, $11, $12, $13, '{}', true, $15
+		WHERE NOT EXISTS (SELECT 1 FROM smurls WHERE small_url = $1 OR admin_url = $5)`,
+		smurl.SmallURL, smurl.CreatedAt, smurl.ModifiedAt, smurl.LongURL, smurl.AdminURL,
+		smurl.Count, smurl.Uniques, smurl.BotCount, smurl.Sticky, string(smurl.Passthrough),
+		nullTime(smurl.ExpiresAt), nullTime(smurl.DeletedAt), nullTime(smurl.DisabledAt), smurl.ImportedClicks,
+		smurl.OwnerID)
+	// A concurrent insert may take one of the codes after the check
+	if isUniqueViolation(err) || (err == nil && tag.RowsAffected() == 0) {
+		tx.Rollback(ctx)
+		return repo.takenCode(ctx, smurl.SmallURL)
+	}
+	if err != nil {
+		repo.logger.Error("error on import small url",
+			zap.Error(err))
+		return nil, err
+	}
+	for _, rule := range smurl.Rules {
+		_, err := tx.Exec(ctx, `INSERT INTO routing_rules (small_url, position, platform, country, language, url)
+			VALUES ($1, $2, $3, $4, $5, $6)`,
+			smurl.SmallURL, rule.Position, rule.Platform, rule.Country, rule.Language, rule.URL)
+		if err != nil {
+			repo.logger.Error("error on import rule",
+				zap.Error(err))
+			return nil, err
+		}
+	}
+	for _, variant := range smurl.Variants {
+		_, err := tx.Exec(ctx, `INSERT INTO variants (small_url, url, weight, count) VALUES ($1, $2, $3, $4)`,
+			smurl.SmallURL, variant.URL, variant.Weight, variant.Count)
+		if err != nil {
+			repo.logger.Error("error on import variant",
+				zap.Error(err))
+			return nil, err
+		}
+	}
+	// Rollups left by a purged small url with the same code are replaced
+	for _, rollup := range smurl.Rollups {
+		_, err := tx.Exec(ctx, `INSERT INTO click_rollups (small_url, granularity, bucket, count) VALUES ($1, $2, $3, $4)
+			ON CONFLICT (small_url, granularity, bucket) DO UPDATE SET count = excluded.count`,
+			smurl.SmallURL, rollup.Granularity, rollup.Start, rollup.Count)
+		if err != nil {
+			repo.logger.Error("error on import rollup",
+				zap.Error(err))
+			return nil, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		repo.logger.Error("error on commit transaction",
+			zap.Error(err))
+		return nil, err
+	}
+	return &smurl, nil
+}
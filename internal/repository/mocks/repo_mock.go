@@ -7,6 +7,7 @@ package mocks
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 	models "github.com/sanyarise/smurl/internal/models"
@@ -35,6 +36,34 @@ func (m *MockSmurlStore) EXPECT() *MockSmurlStoreMockRecorder {
 	return m.recorder
 }
 
+// AddRule mocks base method.
+func (m *MockSmurlStore) AddRule(ctx context.Context, smallUrl string, rule models.Rule) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddRule", ctx, smallUrl, rule)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddRule indicates an expected call of AddRule.
+func (mr *MockSmurlStoreMockRecorder) AddRule(ctx, smallUrl, rule interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddRule", reflect.TypeOf((*MockSmurlStore)(nil).AddRule), ctx, smallUrl, rule)
+}
+
+// AddVariant mocks base method.
+func (m *MockSmurlStore) AddVariant(ctx context.Context, smallUrl string, variant models.Variant) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddVariant", ctx, smallUrl, variant)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddVariant indicates an expected call of AddVariant.
+func (mr *MockSmurlStoreMockRecorder) AddVariant(ctx, smallUrl, variant interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddVariant", reflect.TypeOf((*MockSmurlStore)(nil).AddVariant), ctx, smallUrl, variant)
+}
+
 // Create mocks base method.
 func (m *MockSmurlStore) Create(ctx context.Context, smurl models.Smurl) (*models.Smurl, error) {
 	m.ctrl.T.Helper()
@@ -50,6 +79,76 @@ func (mr *MockSmurlStoreMockRecorder) Create(ctx, smurl interface{}) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockSmurlStore)(nil).Create), ctx, smurl)
 }
 
+// Delete mocks base method.
+func (m *MockSmurlStore) Delete(ctx context.Context, smallUrl string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, smallUrl)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockSmurlStoreMockRecorder) Delete(ctx, smallUrl interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockSmurlStore)(nil).Delete), ctx, smallUrl)
+}
+
+// DeleteRule mocks base method.
+func (m *MockSmurlStore) DeleteRule(ctx context.Context, smallUrl string, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteRule", ctx, smallUrl, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteRule indicates an expected call of DeleteRule.
+func (mr *MockSmurlStoreMockRecorder) DeleteRule(ctx, smallUrl, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteRule", reflect.TypeOf((*MockSmurlStore)(nil).DeleteRule), ctx, smallUrl, id)
+}
+
+// DeleteVariant mocks base method.
+func (m *MockSmurlStore) DeleteVariant(ctx context.Context, smallUrl string, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteVariant", ctx, smallUrl, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteVariant indicates an expected call of DeleteVariant.
+func (mr *MockSmurlStoreMockRecorder) DeleteVariant(ctx, smallUrl, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteVariant", reflect.TypeOf((*MockSmurlStore)(nil).DeleteVariant), ctx, smallUrl, id)
+}
+
+// Edit mocks base method.
+func (m *MockSmurlStore) Edit(ctx context.Context, smallUrl string, edit models.Edit) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Edit", ctx, smallUrl, edit)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Edit indicates an expected call of Edit.
+func (mr *MockSmurlStoreMockRecorder) Edit(ctx, smallUrl, edit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Edit", reflect.TypeOf((*MockSmurlStore)(nil).Edit), ctx, smallUrl, edit)
+}
+
+// ExportLinks mocks base method.
+func (m *MockSmurlStore) ExportLinks(ctx context.Context, fn func(models.Smurl) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExportLinks", ctx, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ExportLinks indicates an expected call of ExportLinks.
+func (mr *MockSmurlStoreMockRecorder) ExportLinks(ctx, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportLinks", reflect.TypeOf((*MockSmurlStore)(nil).ExportLinks), ctx, fn)
+}
+
 // FindURL mocks base method.
 func (m *MockSmurlStore) FindURL(ctx context.Context, smallUrl string) (*models.Smurl, error) {
 	m.ctrl.T.Helper()
@@ -65,31 +164,242 @@ func (mr *MockSmurlStoreMockRecorder) FindURL(ctx, smallUrl interface{}) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindURL", reflect.TypeOf((*MockSmurlStore)(nil).FindURL), ctx, smallUrl)
 }
 
+// ForgetVisitors mocks base method.
+func (m *MockSmurlStore) ForgetVisitors(ctx context.Context, smallUrl string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ForgetVisitors", ctx, smallUrl)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ForgetVisitors indicates an expected call of ForgetVisitors.
+func (mr *MockSmurlStoreMockRecorder) ForgetVisitors(ctx, smallUrl interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ForgetVisitors", reflect.TypeOf((*MockSmurlStore)(nil).ForgetVisitors), ctx, smallUrl)
+}
+
+// ImportLink mocks base method.
+func (m *MockSmurlStore) ImportLink(ctx context.Context, smurl models.Smurl) (*models.Smurl, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ImportLink", ctx, smurl)
+	ret0, _ := ret[0].(*models.Smurl)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ImportLink indicates an expected call of ImportLink.
+func (mr *MockSmurlStoreMockRecorder) ImportLink(ctx, smurl interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImportLink", reflect.TypeOf((*MockSmurlStore)(nil).ImportLink), ctx, smurl)
+}
+
+// PurgeClicks mocks base method.
+func (m *MockSmurlStore) PurgeClicks(ctx context.Context, before time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeClicks", ctx, before)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeClicks indicates an expected call of PurgeClicks.
+func (mr *MockSmurlStoreMockRecorder) PurgeClicks(ctx, before interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeClicks", reflect.TypeOf((*MockSmurlStore)(nil).PurgeClicks), ctx, before)
+}
+
+// ReadClickStats mocks base method.
+func (m *MockSmurlStore) ReadClickStats(ctx context.Context, smallUrl string) (*models.ClickStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadClickStats", ctx, smallUrl)
+	ret0, _ := ret[0].(*models.ClickStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadClickStats indicates an expected call of ReadClickStats.
+func (mr *MockSmurlStoreMockRecorder) ReadClickStats(ctx, smallUrl interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadClickStats", reflect.TypeOf((*MockSmurlStore)(nil).ReadClickStats), ctx, smallUrl)
+}
+
 // ReadStat mocks base method.
-func (m *MockSmurlStore) ReadStat(ctx context.Context, adminUrl string) (*models.Smurl, error) {
+func (m *MockSmurlStore) ReadStat(ctx context.Context, adminHash string) (*models.Smurl, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ReadStat", ctx, adminUrl)
+	ret := m.ctrl.Call(m, "ReadStat", ctx, adminHash)
 	ret0, _ := ret[0].(*models.Smurl)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // ReadStat indicates an expected call of ReadStat.
-func (mr *MockSmurlStoreMockRecorder) ReadStat(ctx, adminUrl interface{}) *gomock.Call {
+func (mr *MockSmurlStoreMockRecorder) ReadStat(ctx, adminHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadStat", reflect.TypeOf((*MockSmurlStore)(nil).ReadStat), ctx, adminHash)
+}
+
+// ReadTimeSeries mocks base method.
+func (m *MockSmurlStore) ReadTimeSeries(ctx context.Context, smallUrl string, granularity models.Granularity, from, to time.Time) ([]models.TimeBucket, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadTimeSeries", ctx, smallUrl, granularity, from, to)
+	ret0, _ := ret[0].([]models.TimeBucket)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadTimeSeries indicates an expected call of ReadTimeSeries.
+func (mr *MockSmurlStoreMockRecorder) ReadTimeSeries(ctx, smallUrl, granularity, from, to interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadStat", reflect.TypeOf((*MockSmurlStore)(nil).ReadStat), ctx, adminUrl)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadTimeSeries", reflect.TypeOf((*MockSmurlStore)(nil).ReadTimeSeries), ctx, smallUrl, granularity, from, to)
+}
+
+// SetPassthrough mocks base method.
+func (m *MockSmurlStore) SetPassthrough(ctx context.Context, smallUrl string, passthrough models.Passthrough) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetPassthrough", ctx, smallUrl, passthrough)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetPassthrough indicates an expected call of SetPassthrough.
+func (mr *MockSmurlStoreMockRecorder) SetPassthrough(ctx, smallUrl, passthrough interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPassthrough", reflect.TypeOf((*MockSmurlStore)(nil).SetPassthrough), ctx, smallUrl, passthrough)
+}
+
+// SetSticky mocks base method.
+func (m *MockSmurlStore) SetSticky(ctx context.Context, smallUrl string, sticky bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetSticky", ctx, smallUrl, sticky)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetSticky indicates an expected call of SetSticky.
+func (mr *MockSmurlStoreMockRecorder) SetSticky(ctx, smallUrl, sticky interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetSticky", reflect.TypeOf((*MockSmurlStore)(nil).SetSticky), ctx, smallUrl, sticky)
 }
 
 // UpdateStat mocks base method.
-func (m *MockSmurlStore) UpdateStat(ctx context.Context, smurl models.Smurl) error {
+func (m *MockSmurlStore) UpdateStat(ctx context.Context, smurl models.Smurl, click models.Click) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateStat", ctx, smurl)
+	ret := m.ctrl.Call(m, "UpdateStat", ctx, smurl, click)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // UpdateStat indicates an expected call of UpdateStat.
-func (mr *MockSmurlStoreMockRecorder) UpdateStat(ctx, smurl interface{}) *gomock.Call {
+func (mr *MockSmurlStoreMockRecorder) UpdateStat(ctx, smurl, click interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateStat", reflect.TypeOf((*MockSmurlStore)(nil).UpdateStat), ctx, smurl, click)
+}
+
+// MockDomainPolicy is a mock of DomainPolicy interface.
+type MockDomainPolicy struct {
+	ctrl     *gomock.Controller
+	recorder *MockDomainPolicyMockRecorder
+}
+
+// MockDomainPolicyMockRecorder is the mock recorder for MockDomainPolicy.
+type MockDomainPolicyMockRecorder struct {
+	mock *MockDomainPolicy
+}
+
+// NewMockDomainPolicy creates a new mock instance.
+func NewMockDomainPolicy(ctrl *gomock.Controller) *MockDomainPolicy {
+	mock := &MockDomainPolicy{ctrl: ctrl}
+	mock.recorder = &MockDomainPolicyMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDomainPolicy) EXPECT() *MockDomainPolicyMockRecorder {
+	return m.recorder
+}
+
+// Check mocks base method.
+func (m *MockDomainPolicy) Check(host string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Check", host)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Check indicates an expected call of Check.
+func (mr *MockDomainPolicyMockRecorder) Check(host interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Check", reflect.TypeOf((*MockDomainPolicy)(nil).Check), host)
+}
+
+// MockGeoLocator is a mock of GeoLocator interface.
+type MockGeoLocator struct {
+	ctrl     *gomock.Controller
+	recorder *MockGeoLocatorMockRecorder
+}
+
+// MockGeoLocatorMockRecorder is the mock recorder for MockGeoLocator.
+type MockGeoLocatorMockRecorder struct {
+	mock *MockGeoLocator
+}
+
+// NewMockGeoLocator creates a new mock instance.
+func NewMockGeoLocator(ctrl *gomock.Controller) *MockGeoLocator {
+	mock := &MockGeoLocator{ctrl: ctrl}
+	mock.recorder = &MockGeoLocatorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockGeoLocator) EXPECT() *MockGeoLocatorMockRecorder {
+	return m.recorder
+}
+
+// Locate mocks base method.
+func (m *MockGeoLocator) Locate(ip string) models.Location {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Locate", ip)
+	ret0, _ := ret[0].(models.Location)
+	return ret0
+}
+
+// Locate indicates an expected call of Locate.
+func (mr *MockGeoLocatorMockRecorder) Locate(ip interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Locate", reflect.TypeOf((*MockGeoLocator)(nil).Locate), ip)
+}
+
+// MockClickPublisher is a mock of ClickPublisher interface.
+type MockClickPublisher struct {
+	ctrl     *gomock.Controller
+	recorder *MockClickPublisherMockRecorder
+}
+
+// MockClickPublisherMockRecorder is the mock recorder for MockClickPublisher.
+type MockClickPublisherMockRecorder struct {
+	mock *MockClickPublisher
+}
+
+// NewMockClickPublisher creates a new mock instance.
+func NewMockClickPublisher(ctrl *gomock.Controller) *MockClickPublisher {
+	mock := &MockClickPublisher{ctrl: ctrl}
+	mock.recorder = &MockClickPublisherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockClickPublisher) EXPECT() *MockClickPublisherMockRecorder {
+	return m.recorder
+}
+
+// Publish mocks base method.
+func (m *MockClickPublisher) Publish(click models.Click) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Publish", click)
+}
+
+// Publish indicates an expected call of Publish.
+func (mr *MockClickPublisherMockRecorder) Publish(click interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateStat", reflect.TypeOf((*MockSmurlStore)(nil).UpdateStat), ctx, smurl)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Publish", reflect.TypeOf((*MockClickPublisher)(nil).Publish), click)
 }
@@ -2,26 +2,59 @@ package repository
 
 import (
 	"context"
+	"crypto/sha256"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 	_ "github.com/jackc/pgx/v4/stdlib"
+	"github.com/sanyarise/smurl/internal/helpers/codegen"
+	"github.com/sanyarise/smurl/internal/helpers/hll"
 	"github.com/sanyarise/smurl/internal/models"
 	"github.com/sanyarise/smurl/internal/usecase"
 	"go.uber.org/zap"
 )
 
-var _ usecase.SmurlStore = &SmurlRepository{}
+var (
+	_ usecase.SmurlStore = &SmurlRepository{}
+	_ codegen.Counter    = &SmurlRepository{}
+)
+
+// Number of unique visitors of a small url counted exactly. Beyond it
+// the visitors are counted approximately with a HyperLogLog sketch
+const exactVisitorsLimit = 10000
+
+// Number of the most recent client addresses shown in the statistics
+const recentIPsLimit = 1000
 
 type Smurl struct {
-	SmallURL   string
-	CreatedAt  time.Time
-	ModifiedAt time.Time
-	LongURL    string
-	AdminURL   string
-	IPInfo     []string
-	Count      uint64
+	SmallURL    string
+	CreatedAt   time.Time
+	ModifiedAt  time.Time
+	LongURL     string
+	AdminURL    string
+	IPInfo      []string
+	Count       uint64
+	Uniques     uint64
+	BotCount    uint64
+	Imported    uint64
+	OwnerID     int64
+	Sticky      bool
+	Passthrough string
+	ExpiresAt   *time.Time
+	DeletedAt   *time.Time
+	DisabledAt  *time.Time
+}
+
+// timeOrZero returns the time of a nullable column, zero for NULL
+func timeOrZero(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return *t
 }
 
 type SmurlRepository struct {
@@ -56,6 +89,202 @@ func NewSmurlRepository(dns string, logger *zap.Logger) (*SmurlRepository, error
 		db.Close()
 		return nil, err
 	}
+	_, err = db.Exec(context.Background(), `CREATE TABLE IF NOT EXISTS clicks (
+		small_url varchar NOT NULL,
+		created_at timestamptz NOT NULL,
+		ip varchar NOT NULL,
+		visitor_id varchar NOT NULL,
+		referer varchar NOT NULL,
+		referer_domain varchar NOT NULL,
+		user_agent varchar NOT NULL,
+		browser varchar NOT NULL,
+		os varchar NOT NULL,
+		device varchar NOT NULL,
+		accept_language varchar NOT NULL,
+		bot boolean NOT NULL DEFAULT false,
+		country varchar NOT NULL DEFAULT '',
+		region varchar NOT NULL DEFAULT '',
+		city varchar NOT NULL DEFAULT ''
+		);
+		CREATE INDEX IF NOT EXISTS clicks_small_url_idx ON clicks (small_url, created_at)`)
+	if err != nil {
+		logger.Error("error on create clicks table",
+			zap.Error(err))
+		db.Close()
+		return nil, err
+	}
+	_, err = db.Exec(context.Background(), `ALTER TABLE smurls ADD COLUMN IF NOT EXISTS unique_count bigint NOT NULL DEFAULT 0;
+		ALTER TABLE smurls ADD COLUMN IF NOT EXISTS bot_count bigint NOT NULL DEFAULT 0;
+		CREATE TABLE IF NOT EXISTS visitors (
+		small_url varchar NOT NULL,
+		visitor_id varchar NOT NULL,
+		PRIMARY KEY (small_url, visitor_id)
+		);
+		CREATE TABLE IF NOT EXISTS visitor_sketches (
+		small_url varchar PRIMARY KEY,
+		sketch bytea NOT NULL
+		)`)
+	if err != nil {
+		logger.Error("error on create visitors tables",
+			zap.Error(err))
+		db.Close()
+		return nil, err
+	}
+	_, err = db.Exec(context.Background(), `CREATE TABLE IF NOT EXISTS click_rollups (
+		small_url varchar NOT NULL,
+		granularity varchar NOT NULL,
+		bucket timestamptz NOT NULL,
+		count bigint NOT NULL,
+		PRIMARY KEY (small_url, granularity, bucket)
+		)`)
+	if err != nil {
+		logger.Error("error on create click rollups table",
+			zap.Error(err))
+		db.Close()
+		return nil, err
+	}
+	_, err = db.Exec(context.Background(), `CREATE TABLE IF NOT EXISTS routing_rules (
+		id bigserial PRIMARY KEY,
+		small_url varchar NOT NULL,
+		position integer NOT NULL,
+		platform varchar NOT NULL DEFAULT '',
+		country varchar NOT NULL DEFAULT '',
+		language varchar NOT NULL DEFAULT '',
+		url varchar NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS routing_rules_small_url_idx ON routing_rules (small_url, position)`)
+	if err != nil {
+		logger.Error("error on create routing rules table",
+			zap.Error(err))
+		db.Close()
+		return nil, err
+	}
+	_, err = db.Exec(context.Background(), `CREATE TABLE IF NOT EXISTS variants (
+		id bigserial PRIMARY KEY,
+		small_url varchar NOT NULL,
+		url varchar NOT NULL,
+		weight integer NOT NULL,
+		count bigint NOT NULL DEFAULT 0
+		);
+		CREATE INDEX IF NOT EXISTS variants_small_url_idx ON variants (small_url);
+		ALTER TABLE smurls ADD COLUMN IF NOT EXISTS sticky boolean NOT NULL DEFAULT false;
+		ALTER TABLE clicks ADD COLUMN IF NOT EXISTS variant_id bigint NOT NULL DEFAULT 0;
+		ALTER TABLE smurls ADD COLUMN IF NOT EXISTS passthrough varchar NOT NULL DEFAULT ''`)
+	if err != nil {
+		logger.Error("error on create variants table",
+			zap.Error(err))
+		db.Close()
+		return nil, err
+	}
+	// Admin tokens were stored in plain text before, they
+	// are replaced with their hashes once
+	_, err = db.Exec(context.Background(), `ALTER TABLE smurls ADD COLUMN IF NOT EXISTS admin_hashed boolean NOT NULL DEFAULT false;
+		UPDATE smurls SET admin_url = encode(sha256(convert_to(admin_url, 'UTF8')), 'hex'), admin_hashed = true
+		WHERE NOT admin_hashed;
+		ALTER TABLE smurls ALTER COLUMN admin_hashed SET DEFAULT true;
+		CREATE INDEX IF NOT EXISTS smurls_admin_url_idx ON smurls (admin_url)`)
+	if err != nil {
+		logger.Error("error on hash admin tokens",
+			zap.Error(err))
+		db.Close()
+		return nil, err
+	}
+	_, err = db.Exec(context.Background(), `CREATE SEQUENCE IF NOT EXISTS smurl_codes`)
+	if err != nil {
+		logger.Error("error on create codes sequence",
+			zap.Error(err))
+		db.Close()
+		return nil, err
+	}
+	_, err = db.Exec(context.Background(), `ALTER TABLE smurls ADD COLUMN IF NOT EXISTS expires_at timestamptz;
+		ALTER TABLE smurls ADD COLUMN IF NOT EXISTS deleted_at timestamptz`)
+	if err != nil {
+		logger.Error("error on add expiry columns",
+			zap.Error(err))
+		db.Close()
+		return nil, err
+	}
+	_, err = db.Exec(context.Background(), `ALTER TABLE smurls ADD COLUMN IF NOT EXISTS disabled_at timestamptz;
+		CREATE TABLE IF NOT EXISTS api_keys (
+			id bigserial PRIMARY KEY,
+			name varchar NOT NULL,
+			key_hash varchar NOT NULL UNIQUE,
+			created_at timestamptz NOT NULL,
+			revoked_at timestamptz
+		)`)
+	if err != nil {
+		logger.Error("error on create admin columns and tables",
+			zap.Error(err))
+		db.Close()
+		return nil, err
+	}
+	_, err = db.Exec(context.Background(), `ALTER TABLE smurls ADD COLUMN IF NOT EXISTS imported_clicks bigint NOT NULL DEFAULT 0`)
+	if err != nil {
+		logger.Error("error on add imported clicks column",
+			zap.Error(err))
+		db.Close()
+		return nil, err
+	}
+	_, err = db.Exec(context.Background(), `ALTER TABLE smurls ADD COLUMN IF NOT EXISTS owner_id bigint NOT NULL DEFAULT 0;
+		ALTER TABLE smurls ADD COLUMN IF NOT EXISTS expired_notified boolean NOT NULL DEFAULT false;
+		CREATE TABLE IF NOT EXISTS webhooks (
+			id bigserial PRIMARY KEY,
+			owner_id bigint NOT NULL DEFAULT 0,
+			url varchar NOT NULL,
+			secret varchar NOT NULL,
+			events text[] NOT NULL DEFAULT '{}',
+			created_at timestamptz NOT NULL,
+			deleted_at timestamptz
+		);
+		CREATE TABLE IF NOT EXISTS webhook_outbox (
+			id bigserial PRIMARY KEY,
+			type varchar NOT NULL,
+			small_url varchar NOT NULL,
+			owner_id bigint NOT NULL DEFAULT 0,
+			payload jsonb NOT NULL,
+			created_at timestamptz NOT NULL,
+			dispatched_at timestamptz
+		);
+		CREATE INDEX IF NOT EXISTS webhook_outbox_pending_idx ON webhook_outbox (id) WHERE dispatched_at IS NULL;
+		CREATE TABLE IF NOT EXISTS webhook_deliveries (
+			id bigserial PRIMARY KEY,
+			webhook_id bigint NOT NULL,
+			event_id bigint NOT NULL,
+			status varchar NOT NULL,
+			attempts integer NOT NULL DEFAULT 0,
+			next_attempt_at timestamptz NOT NULL,
+			created_at timestamptz NOT NULL,
+			delivered_at timestamptz
+		);
+		CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+		CREATE TABLE IF NOT EXISTS webhook_attempts (
+			id bigserial PRIMARY KEY,
+			delivery_id bigint NOT NULL,
+			attempt integer NOT NULL,
+			at timestamptz NOT NULL,
+			status_code integer NOT NULL DEFAULT 0,
+			error varchar NOT NULL DEFAULT '',
+			duration_ms bigint NOT NULL DEFAULT 0
+		);
+		CREATE INDEX IF NOT EXISTS webhook_attempts_delivery_idx ON webhook_attempts (delivery_id)`)
+	if err != nil {
+		logger.Error("error on create webhook columns and tables",
+			zap.Error(err))
+		db.Close()
+		return nil, err
+	}
+	// The codes are unique, so that concurrent inserts of a code
+	// can't both succeed. Duplicates left by earlier versions
+	// must be removed by hand before this migration
+	_, err = db.Exec(context.Background(), `CREATE UNIQUE INDEX IF NOT EXISTS smurls_small_url_key ON smurls (small_url);
+		CREATE UNIQUE INDEX IF NOT EXISTS smurls_admin_url_key ON smurls (admin_url);
+		DROP INDEX IF EXISTS smurls_admin_url_idx`)
+	if err != nil {
+		logger.Error("error on create unique code indexes",
+			zap.Error(err))
+		db.Close()
+		return nil, err
+	}
 	repository := &SmurlRepository{
 		db:     db,
 		logger: logger,
@@ -63,6 +292,16 @@ func NewSmurlRepository(dns string, logger *zap.Logger) (*SmurlRepository, error
 	return repository, nil
 }
 
+// SQLSTATE of a unique constraint violation
+const uniqueViolation = "23505"
+
+// isUniqueViolation reports whether the error is a violation of
+// a unique constraint, such as a small or admin code already taken
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation
+}
+
 func (repo *SmurlRepository) Close() {
 	repo.logger.Debug("Enter in repository Close()")
 	repo.db.Close()
@@ -72,24 +311,29 @@ func (repo *SmurlRepository) Close() {
 func (repo *SmurlRepository) Create(ctx context.Context, smurl models.Smurl) (*models.Smurl, error) {
 	repo.logger.Debug("Enter in repository CreateURL()")
 	repositorySmurl := &Smurl{
-		LongURL:    smurl.LongURL,
-		CreatedAt:  time.Now(),
-		ModifiedAt: time.Now(),
-		SmallURL:   smurl.SmallURL,
-		AdminURL:   smurl.AdminURL,
-		Count:      0,
-		IPInfo:     []string{},
+		LongURL:     smurl.LongURL,
+		CreatedAt:   time.Now(),
+		ModifiedAt:  time.Now(),
+		SmallURL:    smurl.SmallURL,
+		AdminURL:    smurl.AdminURL,
+		Count:       0,
+		IPInfo:      []string{},
+		Passthrough: string(smurl.Passthrough),
+		OwnerID:     smurl.OwnerID,
 	}
 	// Starting a transaction to write data to the database
 	tx, err := repo.db.Begin(ctx)
 	if err != nil {
 		repo.logger.Error("error on begin transaction",
 			zap.Error(err))
+		return nil, err
 	}
 	// Write to database
-	_, err = tx.Exec(ctx, `INSERT INTO smurls
-	(small_url, created_at, modified_at, long_url, admin_url, count, ip_info)
-	values ($1, $2, $3, $4, $5, $6, $7)`,
+	// The codes are inserted only if they are not taken
+	tag, err := tx.Exec(ctx, `INSERT INTO smurls
+	(small_url, created_at, modified_at, long_url, admin_url, count, ip_info, passthrough, owner_id, admin_hashed)
+	SELECT $1, $2, $3, $4, $5, $6, $7, $8, $9, true
+	WHERE NOT EXISTS (SELECT 1 FROM smurls WHERE small_url = $1 OR admin_url = $5)`,
 		repositorySmurl.SmallURL,
 		repositorySmurl.CreatedAt,
 		repositorySmurl.ModifiedAt,
@@ -97,16 +341,41 @@ func (repo *SmurlRepository) Create(ctx context.Context, smurl models.Smurl) (*m
 		repositorySmurl.AdminURL,
 		repositorySmurl.Count,
 		repositorySmurl.IPInfo,
+		repositorySmurl.Passthrough,
+		repositorySmurl.OwnerID,
 	)
 	if err != nil {
 		//Return to original value in case of unsuccessful write
 		tx.Rollback(ctx)
+		// A concurrent insert took one of the codes
+		if isUniqueViolation(err) {
+			return nil, models.ErrConflict
+		}
 		repo.logger.Error("error on insert values into table",
 			zap.Error(err))
 		return nil, err
 	}
-	// End of transaction
-	tx.Commit(ctx)
+	if tag.RowsAffected() == 0 {
+		tx.Rollback(ctx)
+		return nil, models.ErrConflict
+	}
+	err = queueEvent(ctx, tx, models.EventCreated, repositorySmurl.OwnerID, models.EventData{
+		SmallURL:    repositorySmurl.SmallURL,
+		LongURL:     repositorySmurl.LongURL,
+		Passthrough: repositorySmurl.Passthrough,
+	})
+	if err != nil {
+		tx.Rollback(ctx)
+		repo.logger.Error("error on queue event",
+			zap.Error(err))
+		return nil, err
+	}
+	// End of transaction, the link and its event are saved together
+	if err := tx.Commit(ctx); err != nil {
+		repo.logger.Error("error on commit transaction",
+			zap.Error(err))
+		return nil, err
+	}
 	repo.logger.Debug("Pgstore create smurl successfull")
 	// Return object with short and admin url
 	return &models.Smurl{
@@ -115,13 +384,26 @@ func (repo *SmurlRepository) Create(ctx context.Context, smurl models.Smurl) (*m
 	}, nil
 }
 
-// UpdateStat updating statistics data when clicking on a reduced url
-func (repo *SmurlRepository) UpdateStat(ctx context.Context, smurl models.Smurl) error {
+// NextSequence returns the next value of the sequence
+// encoded in the codes of the sequential strategy
+func (repo *SmurlRepository) NextSequence(ctx context.Context) (uint64, error) {
+	repo.logger.Debug("Enter in repository NextSequence()")
+	var n int64
+	err := repo.db.QueryRow(ctx, `SELECT nextval('smurl_codes')`).Scan(&n)
+	if err != nil {
+		repo.logger.Error("error on read codes sequence",
+			zap.Error(err))
+		return 0, err
+	}
+	return uint64(n), nil
+}
+
+// UpdateStat updating statistics data and saving
+// the click details when clicking on a reduced url
+func (repo *SmurlRepository) UpdateStat(ctx context.Context, smurl models.Smurl, click models.Click) error {
 	repo.logger.Debug("Enter in repository UpdateStat()")
 	repositorySmurl := &Smurl{
 		ModifiedAt: time.Now(),
-		Count:      smurl.Count,
-		IPInfo:     smurl.IPInfo,
 		SmallURL:   smurl.SmallURL,
 	}
 	// Starting a transaction to write updated data
@@ -129,33 +411,211 @@ func (repo *SmurlRepository) UpdateStat(ctx context.Context, smurl models.Smurl)
 	if err != nil {
 		repo.logger.Error("error on begin transaction",
 			zap.Error(err))
+		return err
 	}
-	// Write updated data
-	_, err = tx.Exec(ctx, `UPDATE smurls SET modified_at = $1, count = $2, ip_info = $3
-	WHERE small_url = $4`, repositorySmurl.ModifiedAt, repositorySmurl.Count, repositorySmurl.IPInfo, repositorySmurl.SmallURL)
+	// Write updated data. The hit counters are incremented in place,
+	// bot clicks separately, and the count is read back, so that
+	// concurrent clicks are all counted and every milestone is
+	// reached by exactly one click
+	var count uint64
+	err = tx.QueryRow(ctx, `UPDATE smurls SET modified_at = $1,
+	count = COALESCE(count, 0) + CASE WHEN $2 THEN 0 ELSE 1 END,
+	bot_count = bot_count + CASE WHEN $2 THEN 1 ELSE 0 END
+	WHERE small_url = $3 RETURNING count`,
+		repositorySmurl.ModifiedAt, click.Bot, repositorySmurl.SmallURL).Scan(&count)
 	if err != nil {
+		// Return to original value in case of unsuccessful write
+		tx.Rollback(ctx)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.ErrNotFound
+		}
 		repo.logger.Error("error on update values into table",
 			zap.Error(err))
-
-		// Return to original value in case of unsuccessful write
+		return err
+	}
+	// Write the click details
+	_, err = tx.Exec(ctx, `INSERT INTO clicks
+	(small_url, created_at, ip, visitor_id, referer, referer_domain, user_agent, browser, os, device, accept_language, bot, country, region, city, variant_id)
+	values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)`,
+		click.SmallURL,
+		click.CreatedAt,
+		click.IP,
+		click.VisitorID,
+		click.Referer,
+		click.RefererDomain,
+		click.UserAgent,
+		click.Browser,
+		click.OS,
+		click.Device,
+		click.AcceptLanguage,
+		click.Bot,
+		click.Country,
+		click.Region,
+		click.City,
+		click.Variant,
+	)
+	if err != nil {
+		repo.logger.Error("error on insert click into table",
+			zap.Error(err))
+		tx.Rollback(ctx)
+		return err
+	}
+	// Bot clicks are kept only in the clicks table
+	if click.Bot {
+		if err := tx.Commit(ctx); err != nil {
+			repo.logger.Error("error on commit transaction",
+				zap.Error(err))
+			return err
+		}
+		repo.logger.Debug("Pgstore update bot stat successfull")
+		return nil
+	}
+	// Update the hourly and daily rollups, so that time series
+	// are read without scanning the clicks table
+	_, err = tx.Exec(ctx, `INSERT INTO click_rollups (small_url, granularity, bucket, count)
+	values ($1, $2, $3, 1), ($1, $4, $5, 1)
+	ON CONFLICT (small_url, granularity, bucket) DO UPDATE SET count = click_rollups.count + 1`,
+		click.SmallURL,
+		models.Hourly, models.Hourly.Truncate(click.CreatedAt),
+		models.Daily, models.Daily.Truncate(click.CreatedAt),
+	)
+	if err != nil {
+		repo.logger.Error("error on update click rollups",
+			zap.Error(err))
+		tx.Rollback(ctx)
+		return err
+	}
+	if click.Variant != 0 {
+		_, err = tx.Exec(ctx, `UPDATE variants SET count = count + 1
+		WHERE id = $1 AND small_url = $2`, click.Variant, click.SmallURL)
+		if err != nil {
+			repo.logger.Error("error on update variant count",
+				zap.Error(err))
+			tx.Rollback(ctx)
+			return err
+		}
+	}
+	// Count the visitor. The smurls row is locked by the update
+	// above, so clicks on the same small url are serialized here
+	err = repo.countVisitor(ctx, tx, click.SmallURL, click.VisitorID)
+	if err != nil {
+		repo.logger.Error("error on count unique visitor",
+			zap.Error(err))
 		tx.Rollback(ctx)
 		return err
 	}
+	if models.IsMilestone(count) {
+		err = queueEvent(ctx, tx, models.EventMilestone, smurl.OwnerID, models.EventData{
+			SmallURL: smurl.SmallURL,
+			LongURL:  smurl.LongURL,
+			Count:    count,
+		})
+		if err != nil {
+			repo.logger.Error("error on queue event",
+				zap.Error(err))
+			tx.Rollback(ctx)
+			return err
+		}
+	}
 	// End of transaction
-	tx.Commit(ctx)
+	if err := tx.Commit(ctx); err != nil {
+		repo.logger.Error("error on commit transaction",
+			zap.Error(err))
+		return err
+	}
 	repo.logger.Debug("Pgstore update stat successfull")
 
 	return nil
 }
 
-// ReadStat reads statistics data
-func (repo *SmurlRepository) ReadStat(ctx context.Context, adminUrl string) (*models.Smurl, error) {
+// countVisitor updates the unique visitors count of a small url. Visitors
+// are stored in the visitors table until there are more than
+// exactVisitorsLimit of them, then they are moved into a sketch
+func (repo *SmurlRepository) countVisitor(ctx context.Context, tx pgx.Tx, smallUrl string, visitorID string) error {
+	var data []byte
+	err := tx.QueryRow(ctx, `SELECT sketch FROM visitor_sketches WHERE small_url = $1`, smallUrl).Scan(&data)
+	if err == nil {
+		sketch := &hll.Sketch{}
+		if err := sketch.UnmarshalBinary(data); err != nil {
+			return err
+		}
+		addVisitor(sketch, visitorID)
+		return repo.saveSketch(ctx, tx, smallUrl, sketch)
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+
+	tag, err := tx.Exec(ctx, `INSERT INTO visitors (small_url, visitor_id) values ($1, $2)
+	ON CONFLICT DO NOTHING`, smallUrl, visitorID)
+	if err != nil || tag.RowsAffected() == 0 {
+		return err
+	}
+	var uniques uint64
+	err = tx.QueryRow(ctx, `UPDATE smurls SET unique_count = unique_count + 1
+	WHERE small_url = $1 RETURNING unique_count`, smallUrl).Scan(&uniques)
+	if err != nil || uniques <= exactVisitorsLimit {
+		return err
+	}
+
+	// Too many visitors to count exactly, switch to the sketch
+	repo.logger.Debug("Pgstore switch unique visitors to sketch",
+		zap.String("small_url", smallUrl))
+	sketch := hll.New()
+	rows, err := tx.Query(ctx, `SELECT visitor_id FROM visitors WHERE small_url = $1`, smallUrl)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		addVisitor(sketch, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM visitors WHERE small_url = $1`, smallUrl); err != nil {
+		return err
+	}
+	return repo.saveSketch(ctx, tx, smallUrl, sketch)
+}
+
+// addVisitor adds a visitor to the sketch. The identifier is hashed
+// again, so the sketch gets uniformly distributed bits whatever
+// the format of the identifier is
+func addVisitor(sketch *hll.Sketch, visitorID string) {
+	digest := sha256.Sum256([]byte(visitorID))
+	sketch.AddBytes(digest[:])
+}
+
+// saveSketch writes the sketch and its estimate of unique visitors
+func (repo *SmurlRepository) saveSketch(ctx context.Context, tx pgx.Tx, smallUrl string, sketch *hll.Sketch) error {
+	data, err := sketch.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `INSERT INTO visitor_sketches (small_url, sketch) values ($1, $2)
+	ON CONFLICT (small_url) DO UPDATE SET sketch = excluded.sketch`, smallUrl, data)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `UPDATE smurls SET unique_count = $1 WHERE small_url = $2`, sketch.Count(), smallUrl)
+	return err
+}
+
+// ReadStat reads statistics data of the small url
+// with the given hash of the admin token
+func (repo *SmurlRepository) ReadStat(ctx context.Context, adminHash string) (*models.Smurl, error) {
 	repo.logger.Debug("Enter in pgstore func ReadStat()")
 	repositorySmurl := &Smurl{}
 	// Performing a database search
 	rows, err := repo.db.Query(ctx,
-		`SELECT small_url, created_at, modified_at, long_url, admin_url, count, ip_info FROM smurls
-	 WHERE admin_url = $1`, adminUrl)
+		`SELECT small_url, created_at, modified_at, long_url, admin_url, count, unique_count, bot_count, imported_clicks, sticky,
+	 passthrough, expires_at, deleted_at, disabled_at FROM smurls WHERE admin_url = $1`, adminHash)
 	if err != nil {
 		repo.logger.Error("error on query in table",
 			zap.Error(err))
@@ -170,7 +630,14 @@ func (repo *SmurlRepository) ReadStat(ctx context.Context, adminUrl string) (*mo
 			&repositorySmurl.LongURL,
 			&repositorySmurl.AdminURL,
 			&repositorySmurl.Count,
-			&repositorySmurl.IPInfo,
+			&repositorySmurl.Uniques,
+			&repositorySmurl.BotCount,
+			&repositorySmurl.Imported,
+			&repositorySmurl.Sticky,
+			&repositorySmurl.Passthrough,
+			&repositorySmurl.ExpiresAt,
+			&repositorySmurl.DeletedAt,
+			&repositorySmurl.DisabledAt,
 		); err != nil {
 			repo.logger.Error("error on rows scan",
 				zap.Error(err))
@@ -180,53 +647,490 @@ func (repo *SmurlRepository) ReadStat(ctx context.Context, adminUrl string) (*mo
 	if repositorySmurl.AdminURL == "" {
 		return nil, models.ErrNotFound
 	}
+	// The client addresses of the most recent clicks by people
+	ipRows, err := repo.db.Query(ctx, `SELECT ip FROM clicks WHERE small_url = $1 AND NOT bot
+	ORDER BY created_at DESC LIMIT $2`, repositorySmurl.SmallURL, recentIPsLimit)
+	if err != nil {
+		repo.logger.Error("error on query in table",
+			zap.Error(err))
+		return nil, err
+	}
+	defer ipRows.Close()
+	for ipRows.Next() {
+		var ip string
+		if err := ipRows.Scan(&ip); err != nil {
+			repo.logger.Error("error on rows scan",
+				zap.Error(err))
+			return nil, err
+		}
+		repositorySmurl.IPInfo = append(repositorySmurl.IPInfo, ip)
+	}
+	if err := ipRows.Err(); err != nil {
+		repo.logger.Error("error on rows read",
+			zap.Error(err))
+		return nil, err
+	}
+	rules, err := repo.readRules(ctx, repositorySmurl.SmallURL)
+	if err != nil {
+		return nil, err
+	}
+	variants, err := repo.readVariants(ctx, repositorySmurl.SmallURL)
+	if err != nil {
+		return nil, err
+	}
 	result := &models.Smurl{
-		SmallURL:   repositorySmurl.SmallURL,
-		CreatedAt:  repositorySmurl.CreatedAt,
-		ModifiedAt: repositorySmurl.ModifiedAt,
-		LongURL:    repositorySmurl.LongURL,
-		AdminURL:   repositorySmurl.AdminURL,
-		Count:      repositorySmurl.Count,
-		IPInfo:     repositorySmurl.IPInfo,
+		SmallURL:       repositorySmurl.SmallURL,
+		CreatedAt:      repositorySmurl.CreatedAt,
+		ModifiedAt:     repositorySmurl.ModifiedAt,
+		LongURL:        repositorySmurl.LongURL,
+		AdminURL:       repositorySmurl.AdminURL,
+		Count:          repositorySmurl.Count,
+		Uniques:        repositorySmurl.Uniques,
+		BotCount:       repositorySmurl.BotCount,
+		ImportedClicks: repositorySmurl.Imported,
+		IPInfo:         repositorySmurl.IPInfo,
+		Rules:          rules,
+		Variants:       variants,
+		Sticky:         repositorySmurl.Sticky,
+		Passthrough:    models.Passthrough(repositorySmurl.Passthrough),
+		ExpiresAt:      timeOrZero(repositorySmurl.ExpiresAt),
+		DeletedAt:      timeOrZero(repositorySmurl.DeletedAt),
+		DisabledAt:     timeOrZero(repositorySmurl.DisabledAt),
 	}
 	repo.logger.Debug("Pgstore read stat successfull")
 
 	return result, nil
 }
 
+// Columns of the clicks table used for the statistics breakdown
+var clickStatColumns = []string{"referer_domain", "browser", "os", "device", "country"}
+
+// ReadClickStats counts the clicks on a small url grouped by referring
+// domain, browser, operating system, device class and country
+func (repo *SmurlRepository) ReadClickStats(ctx context.Context, smallUrl string) (*models.ClickStats, error) {
+	repo.logger.Debug("Enter in pgstore func ReadClickStats()")
+	groups := make([][]models.StatItem, len(clickStatColumns))
+	for i, column := range clickStatColumns {
+		rows, err := repo.db.Query(ctx, fmt.Sprintf(`SELECT %[1]s, count(*) FROM clicks
+		WHERE small_url = $1 AND NOT bot GROUP BY %[1]s ORDER BY count(*) DESC, %[1]s`, column), smallUrl)
+		if err != nil {
+			repo.logger.Error("error on query in table",
+				zap.Error(err))
+			return nil, err
+		}
+		for rows.Next() {
+			var item models.StatItem
+			if err := rows.Scan(&item.Name, &item.Count); err != nil {
+				rows.Close()
+				repo.logger.Error("error on rows scan",
+					zap.Error(err))
+				return nil, err
+			}
+			groups[i] = append(groups[i], item)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			repo.logger.Error("error on rows read",
+				zap.Error(err))
+			return nil, err
+		}
+	}
+	repo.logger.Debug("Pgstore read click stats successfull")
+	return &models.ClickStats{
+		Referrers: groups[0],
+		Browsers:  groups[1],
+		OS:        groups[2],
+		Devices:   groups[3],
+		Countries: groups[4],
+	}, nil
+}
+
+// ReadTimeSeries reads the rollups of a small url for the buckets
+// between from and to inclusive. Buckets without clicks are omitted
+func (repo *SmurlRepository) ReadTimeSeries(ctx context.Context, smallUrl string, granularity models.Granularity, from, to time.Time) ([]models.TimeBucket, error) {
+	repo.logger.Debug("Enter in pgstore func ReadTimeSeries()")
+	rows, err := repo.db.Query(ctx, `SELECT bucket, count FROM click_rollups
+	WHERE small_url = $1 AND granularity = $2 AND bucket BETWEEN $3 AND $4 ORDER BY bucket`,
+		smallUrl, granularity, from, to)
+	if err != nil {
+		repo.logger.Error("error on query in table",
+			zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+	var buckets []models.TimeBucket
+	for rows.Next() {
+		var bucket models.TimeBucket
+		if err := rows.Scan(&bucket.Start, &bucket.Count); err != nil {
+			repo.logger.Error("error on rows scan",
+				zap.Error(err))
+			return nil, err
+		}
+		buckets = append(buckets, bucket)
+	}
+	if err := rows.Err(); err != nil {
+		repo.logger.Error("error on rows read",
+			zap.Error(err))
+		return nil, err
+	}
+	repo.logger.Debug("Pgstore read time series successfull")
+	return buckets, nil
+}
+
 // FindURL search small url in database
 func (repo *SmurlRepository) FindURL(ctx context.Context, smallUrl string) (*models.Smurl, error) {
 	repo.logger.Debug("Enter in pgstore func FindUrl()")
 
 	repositorySmurl := Smurl{}
 	row := repo.db.QueryRow(ctx,
-		`SELECT small_url, created_at, modified_at, long_url, count, ip_info FROM smurls WHERE small_url = $1`, smallUrl)
-	if err := row.Scan(
+		`SELECT small_url, created_at, modified_at, long_url, count, unique_count, bot_count, imported_clicks, sticky,
+		passthrough, expires_at, deleted_at, disabled_at, owner_id FROM smurls WHERE small_url = $1`, smallUrl)
+	err := row.Scan(
 		&repositorySmurl.SmallURL,
 		&repositorySmurl.CreatedAt,
 		&repositorySmurl.ModifiedAt,
 		&repositorySmurl.LongURL,
 		&repositorySmurl.Count,
-		&repositorySmurl.IPInfo,
-	); err != nil {
+		&repositorySmurl.Uniques,
+		&repositorySmurl.BotCount,
+		&repositorySmurl.Imported,
+		&repositorySmurl.Sticky,
+		&repositorySmurl.Passthrough,
+		&repositorySmurl.ExpiresAt,
+		&repositorySmurl.DeletedAt,
+		&repositorySmurl.DisabledAt,
+		&repositorySmurl.OwnerID,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		repo.logger.Debug(fmt.Sprintf("small url %s not found", smallUrl))
+		return nil, models.ErrNotFound
+	}
+	if err != nil {
 		repo.logger.Error("error find small url",
 			zap.Error(err))
 		return nil, err
 	}
-
-	if repositorySmurl.SmallURL == "" {
-		err := fmt.Errorf("small url not found")
-		repo.logger.Error("",
-			zap.Error(err))
-		return nil, models.ErrNotFound
+	rules, err := repo.readRules(ctx, repositorySmurl.SmallURL)
+	if err != nil {
+		return nil, err
+	}
+	variants, err := repo.readVariants(ctx, repositorySmurl.SmallURL)
+	if err != nil {
+		return nil, err
 	}
 	repo.logger.Debug("URL find successfull")
 	return &models.Smurl{
-		SmallURL:   repositorySmurl.SmallURL,
-		CreatedAt:  repositorySmurl.CreatedAt,
-		ModifiedAt: repositorySmurl.ModifiedAt,
-		LongURL:    repositorySmurl.LongURL,
-		Count:      repositorySmurl.Count,
-		IPInfo:     repositorySmurl.IPInfo,
+		SmallURL:       repositorySmurl.SmallURL,
+		CreatedAt:      repositorySmurl.CreatedAt,
+		ModifiedAt:     repositorySmurl.ModifiedAt,
+		LongURL:        repositorySmurl.LongURL,
+		Count:          repositorySmurl.Count,
+		Uniques:        repositorySmurl.Uniques,
+		BotCount:       repositorySmurl.BotCount,
+		ImportedClicks: repositorySmurl.Imported,
+		Rules:          rules,
+		Variants:       variants,
+		Sticky:         repositorySmurl.Sticky,
+		Passthrough:    models.Passthrough(repositorySmurl.Passthrough),
+		ExpiresAt:      timeOrZero(repositorySmurl.ExpiresAt),
+		DeletedAt:      timeOrZero(repositorySmurl.DeletedAt),
+		DisabledAt:     timeOrZero(repositorySmurl.DisabledAt),
+		OwnerID:        repositorySmurl.OwnerID,
 	}, nil
 }
+
+// readRules reads the routing rules of a small url in evaluation order
+func (repo *SmurlRepository) readRules(ctx context.Context, smallUrl string) ([]models.Rule, error) {
+	rows, err := repo.db.Query(ctx, `SELECT id, position, platform, country, language, url
+	FROM routing_rules WHERE small_url = $1 ORDER BY position, id`, smallUrl)
+	if err != nil {
+		repo.logger.Error("error on query in table",
+			zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+	var rules []models.Rule
+	for rows.Next() {
+		var rule models.Rule
+		if err := rows.Scan(&rule.ID, &rule.Position, &rule.Platform,
+			&rule.Country, &rule.Language, &rule.URL); err != nil {
+			repo.logger.Error("error on rows scan",
+				zap.Error(err))
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	if err := rows.Err(); err != nil {
+		repo.logger.Error("error on rows read",
+			zap.Error(err))
+		return nil, err
+	}
+	return rules, nil
+}
+
+// AddRule adds a routing rule to a small url. A rule without
+// a position is placed after the existing rules
+func (repo *SmurlRepository) AddRule(ctx context.Context, smallUrl string, rule models.Rule) error {
+	repo.logger.Debug("Enter in pgstore func AddRule()")
+	_, err := repo.db.Exec(ctx, `INSERT INTO routing_rules
+	(small_url, position, platform, country, language, url)
+	SELECT $1, CASE WHEN $2 > 0 THEN $2 ELSE COALESCE(MAX(position), 0) + 1 END, $3, $4, $5, $6
+	FROM routing_rules WHERE small_url = $1`,
+		smallUrl, rule.Position, rule.Platform, rule.Country, rule.Language, rule.URL)
+	if err != nil {
+		repo.logger.Error("error on insert routing rule",
+			zap.Error(err))
+		return err
+	}
+	repo.logger.Debug("Pgstore add rule successfull")
+	return nil
+}
+
+// DeleteRule deletes a routing rule of a small url
+func (repo *SmurlRepository) DeleteRule(ctx context.Context, smallUrl string, id int64) error {
+	repo.logger.Debug("Enter in pgstore func DeleteRule()")
+	tag, err := repo.db.Exec(ctx, `DELETE FROM routing_rules WHERE small_url = $1 AND id = $2`, smallUrl, id)
+	if err != nil {
+		repo.logger.Error("error on delete routing rule",
+			zap.Error(err))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrNotFound
+	}
+	repo.logger.Debug("Pgstore delete rule successfull")
+	return nil
+}
+
+// readVariants reads the weighted destinations of a small url
+func (repo *SmurlRepository) readVariants(ctx context.Context, smallUrl string) ([]models.Variant, error) {
+	rows, err := repo.db.Query(ctx, `SELECT id, url, weight, count
+	FROM variants WHERE small_url = $1 ORDER BY id`, smallUrl)
+	if err != nil {
+		repo.logger.Error("error on query in table",
+			zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+	var variants []models.Variant
+	for rows.Next() {
+		var variant models.Variant
+		if err := rows.Scan(&variant.ID, &variant.URL, &variant.Weight, &variant.Count); err != nil {
+			repo.logger.Error("error on rows scan",
+				zap.Error(err))
+			return nil, err
+		}
+		variants = append(variants, variant)
+	}
+	if err := rows.Err(); err != nil {
+		repo.logger.Error("error on rows read",
+			zap.Error(err))
+		return nil, err
+	}
+	return variants, nil
+}
+
+// AddVariant adds a weighted destination to a small url
+func (repo *SmurlRepository) AddVariant(ctx context.Context, smallUrl string, variant models.Variant) error {
+	repo.logger.Debug("Enter in pgstore func AddVariant()")
+	_, err := repo.db.Exec(ctx, `INSERT INTO variants (small_url, url, weight) values ($1, $2, $3)`,
+		smallUrl, variant.URL, variant.Weight)
+	if err != nil {
+		repo.logger.Error("error on insert variant",
+			zap.Error(err))
+		return err
+	}
+	repo.logger.Debug("Pgstore add variant successfull")
+	return nil
+}
+
+// DeleteVariant deletes a weighted destination of a small url. The
+// clicks keep the ID of the variant they were redirected to
+func (repo *SmurlRepository) DeleteVariant(ctx context.Context, smallUrl string, id int64) error {
+	repo.logger.Debug("Enter in pgstore func DeleteVariant()")
+	tag, err := repo.db.Exec(ctx, `DELETE FROM variants WHERE small_url = $1 AND id = $2`, smallUrl, id)
+	if err != nil {
+		repo.logger.Error("error on delete variant",
+			zap.Error(err))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrNotFound
+	}
+	repo.logger.Debug("Pgstore delete variant successfull")
+	return nil
+}
+
+// SetSticky sets whether visitors of a small url keep their variant
+func (repo *SmurlRepository) SetSticky(ctx context.Context, smallUrl string, sticky bool) error {
+	repo.logger.Debug("Enter in pgstore func SetSticky()")
+	_, err := repo.db.Exec(ctx, `UPDATE smurls SET sticky = $1 WHERE small_url = $2`, sticky, smallUrl)
+	if err != nil {
+		repo.logger.Error("error on update sticky",
+			zap.Error(err))
+		return err
+	}
+	repo.logger.Debug("Pgstore set sticky successfull")
+	return nil
+}
+
+// SetPassthrough sets the passthrough mode of a small url
+func (repo *SmurlRepository) SetPassthrough(ctx context.Context, smallUrl string, passthrough models.Passthrough) error {
+	repo.logger.Debug("Enter in pgstore func SetPassthrough()")
+	_, err := repo.db.Exec(ctx, `UPDATE smurls SET passthrough = $1 WHERE small_url = $2`, string(passthrough), smallUrl)
+	if err != nil {
+		repo.logger.Error("error on update passthrough",
+			zap.Error(err))
+		return err
+	}
+	repo.logger.Debug("Pgstore set passthrough successfull")
+	return nil
+}
+
+// Edit changes the fields of a small url set in the edit. A new
+// expiry time lets the small url be notified as expired again
+func (repo *SmurlRepository) Edit(ctx context.Context, smallUrl string, edit models.Edit) error {
+	repo.logger.Debug("Enter in pgstore func Edit()")
+	var expiresAt *time.Time
+	if edit.ExpiresAt != nil && !edit.ExpiresAt.IsZero() {
+		expiresAt = edit.ExpiresAt
+	}
+	var passthrough *string
+	if edit.Passthrough != nil {
+		value := string(*edit.Passthrough)
+		passthrough = &value
+	}
+	tx, err := repo.db.Begin(ctx)
+	if err != nil {
+		repo.logger.Error("error on begin transaction",
+			zap.Error(err))
+		return err
+	}
+	defer tx.Rollback(ctx)
+	var s Smurl
+	err = tx.QueryRow(ctx, `UPDATE smurls SET long_url = COALESCE($1, long_url),
+		expires_at = CASE WHEN $2 THEN $3 ELSE expires_at END,
+		expired_notified = CASE WHEN $2 THEN false ELSE expired_notified END,
+		passthrough = COALESCE($4, passthrough), sticky = COALESCE($5, sticky), modified_at = $6
+		WHERE small_url = $7 AND deleted_at IS NULL
+		RETURNING long_url, expires_at, passthrough, sticky, owner_id`,
+		edit.LongURL, edit.ExpiresAt != nil, expiresAt, passthrough, edit.Sticky, time.Now(), smallUrl,
+	).Scan(&s.LongURL, &s.ExpiresAt, &s.Passthrough, &s.Sticky, &s.OwnerID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.ErrNotFound
+	}
+	if err != nil {
+		repo.logger.Error("error on edit small url",
+			zap.Error(err))
+		return err
+	}
+	err = queueEvent(ctx, tx, models.EventEdited, s.OwnerID, models.EventData{
+		SmallURL:    smallUrl,
+		LongURL:     s.LongURL,
+		ExpiresAt:   s.ExpiresAt,
+		Passthrough: s.Passthrough,
+		Sticky:      s.Sticky,
+	})
+	if err != nil {
+		repo.logger.Error("error on queue event",
+			zap.Error(err))
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		repo.logger.Error("error on commit transaction",
+			zap.Error(err))
+		return err
+	}
+	repo.logger.Debug("Pgstore edit successfull")
+	return nil
+}
+
+// Delete marks a small url as deleted
+func (repo *SmurlRepository) Delete(ctx context.Context, smallUrl string) error {
+	repo.logger.Debug("Enter in pgstore func Delete()")
+	tx, err := repo.db.Begin(ctx)
+	if err != nil {
+		repo.logger.Error("error on begin transaction",
+			zap.Error(err))
+		return err
+	}
+	defer tx.Rollback(ctx)
+	var s Smurl
+	err = tx.QueryRow(ctx, `UPDATE smurls SET deleted_at = $1 WHERE small_url = $2 AND deleted_at IS NULL
+		RETURNING long_url, owner_id`, time.Now(), smallUrl).Scan(&s.LongURL, &s.OwnerID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.ErrNotFound
+	}
+	if err != nil {
+		repo.logger.Error("error on delete small url",
+			zap.Error(err))
+		return err
+	}
+	err = queueEvent(ctx, tx, models.EventDeleted, s.OwnerID, models.EventData{SmallURL: smallUrl, LongURL: s.LongURL})
+	if err != nil {
+		repo.logger.Error("error on queue event",
+			zap.Error(err))
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		repo.logger.Error("error on commit transaction",
+			zap.Error(err))
+		return err
+	}
+	repo.logger.Debug("Pgstore delete successfull")
+	return nil
+}
+
+// ForgetVisitors deletes the clicks, the visitor identifiers and the
+// client addresses of a small url. The counters are kept
+func (repo *SmurlRepository) ForgetVisitors(ctx context.Context, smallUrl string) error {
+	repo.logger.Debug("Enter in pgstore func ForgetVisitors()")
+	tx, err := repo.db.Begin(ctx)
+	if err != nil {
+		repo.logger.Error("error on begin transaction",
+			zap.Error(err))
+		return err
+	}
+	for _, query := range []string{
+		`DELETE FROM clicks WHERE small_url = $1`,
+		`DELETE FROM visitors WHERE small_url = $1`,
+		`DELETE FROM visitor_sketches WHERE small_url = $1`,
+		`UPDATE smurls SET ip_info = NULL WHERE small_url = $1`,
+	} {
+		if _, err := tx.Exec(ctx, query, smallUrl); err != nil {
+			repo.logger.Error("error on forget visitors",
+				zap.Error(err))
+			tx.Rollback(ctx)
+			return err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		repo.logger.Error("error on commit transaction",
+			zap.Error(err))
+		return err
+	}
+	repo.logger.Debug("Pgstore forget visitors successfull")
+	return nil
+}
+
+// PurgeClicks deletes the clicks recorded before the given time. The
+// client addresses stored in smurls.ip_info by earlier versions have no
+// time, they are deleted for links not clicked since then
+func (repo *SmurlRepository) PurgeClicks(ctx context.Context, before time.Time) (int64, error) {
+	repo.logger.Debug("Enter in pgstore func PurgeClicks()")
+	tag, err := repo.db.Exec(ctx, `DELETE FROM clicks WHERE created_at < $1`, before)
+	if err != nil {
+		repo.logger.Error("error on delete clicks",
+			zap.Error(err))
+		return 0, err
+	}
+	_, err = repo.db.Exec(ctx, `UPDATE smurls SET ip_info = NULL
+	WHERE ip_info IS NOT NULL AND modified_at < $1`, before)
+	if err != nil {
+		repo.logger.Error("error on delete ip info",
+			zap.Error(err))
+		return 0, err
+	}
+	repo.logger.Debug("Pgstore purge clicks successfull")
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,278 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/sanyarise/smurl/internal/models"
+	"go.uber.org/zap"
+)
+
+// queueEvent writes an event to the outbox in the transaction of the
+// change, so that the event is sent if and only if the change is saved
+func queueEvent(ctx context.Context, tx pgx.Tx, eventType models.EventType, ownerID int64, data models.EventData) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `INSERT INTO webhook_outbox (type, small_url, owner_id, payload, created_at)
+		VALUES ($1, $2, $3, $4, $5)`, string(eventType), data.SmallURL, ownerID, string(payload), time.Now())
+	return err
+}
+
+// QueueExpired writes an event to the outbox for every small url expired
+// at the given time and not yet notified, in a single statement
+func (repo *SmurlRepository) QueueExpired(ctx context.Context, now time.Time) (int64, error) {
+	repo.logger.Debug("Enter in pgstore func QueueExpired()")
+	tag, err := repo.db.Exec(ctx, `WITH expired AS (
+			UPDATE smurls SET expired_notified = true
+			WHERE expires_at <= $1 AND NOT expired_notified AND deleted_at IS NULL
+			RETURNING small_url, long_url, owner_id, expires_at
+		)
+		INSERT INTO webhook_outbox (type, small_url, owner_id, payload, created_at)
+		SELECT $2, small_url, owner_id,
+			json_build_object('small_url', small_url, 'long_url', long_url, 'expires_at', expires_at), $1
+		FROM expired`, now, string(models.EventExpired))
+	if err != nil {
+		repo.logger.Error("error on queue expired small urls",
+			zap.Error(err))
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// FanOutEvents creates the deliveries of up to limit events of the
+// outbox to the webhooks that want them, and marks the events as
+// dispatched in the same statement. It returns the number of events
+func (repo *SmurlRepository) FanOutEvents(ctx context.Context, now time.Time, limit int) (int64, error) {
+	repo.logger.Debug("Enter in pgstore func FanOutEvents()")
+	var events int64
+	err := repo.db.QueryRow(ctx, `WITH events AS (
+			SELECT id, type, owner_id FROM webhook_outbox WHERE dispatched_at IS NULL
+			ORDER BY id LIMIT $2 FOR UPDATE SKIP LOCKED
+		), deliveries AS (
+			INSERT INTO webhook_deliveries (webhook_id, event_id, status, attempts, next_attempt_at, created_at)
+			SELECT w.id, e.id, $3, 0, $1, $1 FROM events e JOIN webhooks w
+			ON w.deleted_at IS NULL AND (w.owner_id = 0 OR w.owner_id = e.owner_id)
+			AND (cardinality(w.events) = 0 OR e.type = ANY(w.events))
+		), dispatched AS (
+			UPDATE webhook_outbox o SET dispatched_at = $1 FROM events e WHERE o.id = e.id
+			RETURNING 1
+		)
+		SELECT count(*) FROM dispatched`,
+		now, limit, string(models.DeliveryPending)).Scan(&events)
+	if err != nil {
+		repo.logger.Error("error on fan out events",
+			zap.Error(err))
+		return 0, err
+	}
+	return events, nil
+}
+
+// ClaimDeliveries reads up to limit pending deliveries due at the given
+// time, with their webhooks and events. They are not due again before
+// the lease ends, so that other dispatchers don't send them meanwhile
+func (repo *SmurlRepository) ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error) {
+	repo.logger.Debug("Enter in pgstore func ClaimDeliveries()")
+	rows, err := repo.db.Query(ctx, `WITH due AS (
+			SELECT d.id FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id
+			WHERE d.status = $4 AND d.next_attempt_at <= $1 AND w.deleted_at IS NULL
+			ORDER BY d.next_attempt_at LIMIT $3 FOR UPDATE OF d SKIP LOCKED
+		)
+		UPDATE webhook_deliveries d SET next_attempt_at = $2
+		FROM due, webhooks w, webhook_outbox e
+		WHERE d.id = due.id AND w.id = d.webhook_id AND e.id = d.event_id
+		RETURNING d.id, d.attempts, w.id, w.owner_id, w.url, w.secret,
+		e.id, e.type, e.small_url, e.owner_id, e.payload, e.created_at`,
+		now, now.Add(lease), limit, string(models.DeliveryPending))
+	if err != nil {
+		repo.logger.Error("error on claim deliveries",
+			zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		var d models.WebhookDelivery
+		var eventType string
+		var payload []byte
+		if err := rows.Scan(&d.ID, &d.Attempts, &d.Webhook.ID, &d.Webhook.OwnerID, &d.Webhook.URL, &d.Webhook.Secret,
+			&d.Event.ID, &eventType, &d.Event.SmallURL, &d.Event.OwnerID, &payload, &d.Event.CreatedAt); err != nil {
+			repo.logger.Error("error on rows scan",
+				zap.Error(err))
+			return nil, err
+		}
+		d.Status = models.DeliveryPending
+		d.Event.Type = models.EventType(eventType)
+		d.Event.Data = payload
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		repo.logger.Error("error on rows read",
+			zap.Error(err))
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// RecordAttempt writes an attempt to the delivery log and saves the
+// status, the attempts and the next attempt of the delivery
+func (repo *SmurlRepository) RecordAttempt(ctx context.Context, attempt models.WebhookAttempt, delivery models.WebhookDelivery) error {
+	repo.logger.Debug("Enter in pgstore func RecordAttempt()")
+	tx, err := repo.db.Begin(ctx)
+	if err != nil {
+		repo.logger.Error("error on begin transaction",
+			zap.Error(err))
+		return err
+	}
+	defer tx.Rollback(ctx)
+	_, err = tx.Exec(ctx, `INSERT INTO webhook_attempts (delivery_id, attempt, at, status_code, error, duration_ms)
+		VALUES ($1, $2, $3, $4, $5, $6)`, attempt.DeliveryID, attempt.Attempt, attempt.At, attempt.StatusCode,
+		attempt.Error, attempt.Duration.Milliseconds())
+	if err != nil {
+		repo.logger.Error("error on insert attempt",
+			zap.Error(err))
+		return err
+	}
+	_, err = tx.Exec(ctx, `UPDATE webhook_deliveries SET status = $1, attempts = $2, next_attempt_at = $3,
+		delivered_at = $4 WHERE id = $5`, string(delivery.Status), delivery.Attempts, delivery.NextAttemptAt,
+		nullTime(delivery.DeliveredAt), delivery.ID)
+	if err != nil {
+		repo.logger.Error("error on update delivery",
+			zap.Error(err))
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		repo.logger.Error("error on commit transaction",
+			zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// CreateWebhook saves a webhook and returns it with its id
+func (repo *SmurlRepository) CreateWebhook(ctx context.Context, webhook models.Webhook) (*models.Webhook, error) {
+	repo.logger.Debug("Enter in pgstore func CreateWebhook()")
+	events := make([]string, 0, len(webhook.Events))
+	for _, event := range webhook.Events {
+		events = append(events, string(event))
+	}
+	err := repo.db.QueryRow(ctx, `INSERT INTO webhooks (owner_id, url, secret, events, created_at)
+		VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+		webhook.OwnerID, webhook.URL, webhook.Secret, events, webhook.CreatedAt).Scan(&webhook.ID)
+	if err != nil {
+		repo.logger.Error("error on insert webhook",
+			zap.Error(err))
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// ListWebhooks reads the webhooks that are not deleted
+func (repo *SmurlRepository) ListWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	repo.logger.Debug("Enter in pgstore func ListWebhooks()")
+	rows, err := repo.db.Query(ctx, `SELECT id, owner_id, url, secret, events, created_at FROM webhooks
+		WHERE deleted_at IS NULL ORDER BY id`)
+	if err != nil {
+		repo.logger.Error("error on query in table",
+			zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+	var webhooks []models.Webhook
+	for rows.Next() {
+		var webhook models.Webhook
+		var events []string
+		if err := rows.Scan(&webhook.ID, &webhook.OwnerID, &webhook.URL, &webhook.Secret, &events,
+			&webhook.CreatedAt); err != nil {
+			repo.logger.Error("error on rows scan",
+				zap.Error(err))
+			return nil, err
+		}
+		for _, event := range events {
+			webhook.Events = append(webhook.Events, models.EventType(event))
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	if err := rows.Err(); err != nil {
+		repo.logger.Error("error on rows read",
+			zap.Error(err))
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// DeleteWebhook deletes a webhook, its pending deliveries fail
+func (repo *SmurlRepository) DeleteWebhook(ctx context.Context, id int64) error {
+	repo.logger.Debug("Enter in pgstore func DeleteWebhook()")
+	tx, err := repo.db.Begin(ctx)
+	if err != nil {
+		repo.logger.Error("error on begin transaction",
+			zap.Error(err))
+		return err
+	}
+	defer tx.Rollback(ctx)
+	tag, err := tx.Exec(ctx, `UPDATE webhooks SET deleted_at = $1 WHERE id = $2 AND deleted_at IS NULL`,
+		time.Now(), id)
+	if err != nil {
+		repo.logger.Error("error on delete webhook",
+			zap.Error(err))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrNotFound
+	}
+	_, err = tx.Exec(ctx, `UPDATE webhook_deliveries SET status = $1 WHERE webhook_id = $2 AND status = $3`,
+		string(models.DeliveryFailed), id, string(models.DeliveryPending))
+	if err != nil {
+		repo.logger.Error("error on fail deliveries",
+			zap.Error(err))
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		repo.logger.Error("error on commit transaction",
+			zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// ListAttempts reads the newest entries of the delivery log, of
+// the webhook or of all webhooks for zero
+func (repo *SmurlRepository) ListAttempts(ctx context.Context, webhookID int64, limit int) ([]models.WebhookAttempt, error) {
+	repo.logger.Debug("Enter in pgstore func ListAttempts()")
+	rows, err := repo.db.Query(ctx, `SELECT a.delivery_id, d.webhook_id, e.type, e.small_url, a.attempt, a.at,
+		a.status_code, a.error, a.duration_ms
+		FROM webhook_attempts a JOIN webhook_deliveries d ON d.id = a.delivery_id
+		JOIN webhook_outbox e ON e.id = d.event_id
+		WHERE $1 = 0 OR d.webhook_id = $1 ORDER BY a.at DESC, a.id DESC LIMIT $2`, webhookID, limit)
+	if err != nil {
+		repo.logger.Error("error on query in table",
+			zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+	var attempts []models.WebhookAttempt
+	for rows.Next() {
+		var attempt models.WebhookAttempt
+		var eventType string
+		var duration int64
+		if err := rows.Scan(&attempt.DeliveryID, &attempt.WebhookID, &eventType, &attempt.SmallURL, &attempt.Attempt,
+			&attempt.At, &attempt.StatusCode, &attempt.Error, &duration); err != nil {
+			repo.logger.Error("error on rows scan",
+				zap.Error(err))
+			return nil, err
+		}
+		attempt.EventType = models.EventType(eventType)
+		attempt.Duration = time.Duration(duration) * time.Millisecond
+		attempts = append(attempts, attempt)
+	}
+	if err := rows.Err(); err != nil {
+		repo.logger.Error("error on rows read",
+			zap.Error(err))
+		return nil, err
+	}
+	return attempts, nil
+}
@@ -0,0 +1,289 @@
+package transfer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sanyarise/smurl/internal/helpers"
+	"github.com/sanyarise/smurl/internal/helpers/codegen"
+	"github.com/sanyarise/smurl/internal/models"
+)
+
+// Source is a shortener whose exports can be imported
+type Source string
+
+const (
+	// CSV with code and long_url columns, and optional created_at and
+	// clicks columns. The header can use url for long_url
+	GenericCSV Source = "csv"
+	// JSON of the YOURLS API, e.g. action=stats, or a list of
+	// its links with keyword, url, timestamp and clicks
+	YOURLS Source = "yourls"
+	// JSON of the short url list of the Shlink REST API, or a list of
+	// its short urls with shortCode, longUrl, dateCreated and visits
+	Shlink Source = "shlink"
+)
+
+// ParseSource returns the source with the name
+func ParseSource(name string) (Source, error) {
+	switch Source(name) {
+	case GenericCSV, YOURLS, Shlink:
+		return Source(name), nil
+	}
+	return "", fmt.Errorf("unknown source %q, use csv, yourls or shlink", name)
+}
+
+// Columns of the generic CSV for the columns of a record
+var genericColumns = map[string]string{
+	"code":   "small_url",
+	"url":    "long_url",
+	"clicks": "imported_clicks",
+}
+
+// ImportFrom imports the links exported by another shortener with their
+// codes. Their clicks are kept apart from the clicks counted here. Every
+// created small url gets a new admin token from the generator, only
+// returned in the report. Their urls pass the checks of the destinations
+// and are saved normalized. Links whose code exists with the same long url
+// were imported before and are unchanged
+func ImportFrom(ctx context.Context, store Importer, r io.Reader, source Source, adminCodes codegen.CodeGenerator,
+	destinations *Destinations) (*Report, error) {
+	var reader reader
+	var err error
+	switch source {
+	case GenericCSV:
+		reader, err = newCSVReader(r, genericColumns, "small_url", "long_url")
+	case YOURLS:
+		reader, err = newJSONReader(r, yourlsLinks, parseYOURLS)
+	case Shlink:
+		reader, err = newJSONReader(r, shlinkLinks, parseShlink)
+	default:
+		err = fmt.Errorf("unknown source %q", source)
+	}
+	if err != nil {
+		return nil, err
+	}
+	report := &Report{Conflicts: []Problem{}, Invalid: []Problem{}}
+	for {
+		record, line, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return report, nil
+		}
+		var readErr *ReadError
+		if errors.As(err, &readErr) {
+			report.Invalid = append(report.Invalid, Problem{Line: line, Reason: readErr.Error()})
+			continue
+		}
+		if err != nil {
+			return report, err
+		}
+		token, err := adminCodes.Generate(ctx)
+		if err != nil {
+			return report, err
+		}
+		record.AdminHash = helpers.HashToken(token)
+		created, err := importRecord(ctx, store, destinations, record, line, report, sameLink)
+		if err != nil {
+			return report, err
+		}
+		if created {
+			report.AdminTokens = append(report.AdminTokens, AdminToken{SmallURL: record.SmallURL, Token: token})
+		}
+	}
+}
+
+// sameLink reports whether the existing small url was imported from
+// the record. Imported admin codes are new, so only the long url counts
+func sameLink(existing *models.Smurl, record Record) bool {
+	return existing.LongURL == record.LongURL
+}
+
+// count is a number of clicks, which YOURLS writes as a string
+type count uint64
+
+func (c *count) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "" || s == "null" {
+		*c = 0
+		return nil
+	}
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("clicks %s are not a number", data)
+	}
+	*c = count(n)
+	return nil
+}
+
+// codeOf returns the code of a link, or the last segment of its short url
+func codeOf(code, shortURL string) string {
+	if code != "" || shortURL == "" {
+		return code
+	}
+	u, err := url.Parse(shortURL)
+	if err != nil {
+		return ""
+	}
+	return path.Base(strings.TrimSuffix(u.Path, "/"))
+}
+
+// jsonReader reads the records of a JSON document, their
+// line is their position in the list of the document
+type jsonReader struct {
+	items []json.RawMessage
+	parse func(item json.RawMessage) (Record, error)
+	next  int
+}
+
+// newJSONReader reads a JSON document. Links finds the
+// items of the links in it, parse reads an item
+func newJSONReader(r io.Reader, links func(doc json.RawMessage) ([]json.RawMessage, error),
+	parse func(item json.RawMessage) (Record, error)) (*jsonReader, error) {
+	var doc json.RawMessage
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("read JSON: %w", err)
+	}
+	items, err := links(doc)
+	if err != nil {
+		return nil, err
+	}
+	return &jsonReader{items: items, parse: parse}, nil
+}
+
+func (r *jsonReader) Read() (Record, int, error) {
+	if r.next >= len(r.items) {
+		return Record{}, r.next, io.EOF
+	}
+	item := r.items[r.next]
+	r.next++
+	record, err := r.parse(item)
+	if err != nil {
+		return Record{}, r.next, &ReadError{Err: err}
+	}
+	return record, r.next, nil
+}
+
+// list returns the items of a JSON list, or the values of a JSON object
+// in the order of the numbers in their keys, as link_1, link_2...
+func list(data json.RawMessage) ([]json.RawMessage, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err == nil {
+		return items, nil
+	}
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil, errors.New("links are neither a list nor an object")
+	}
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	number := func(key string) int {
+		n, _ := strconv.Atoi(key[strings.LastIndexAny(key, "_-")+1:])
+		return n
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if number(keys[i]) != number(keys[j]) {
+			return number(keys[i]) < number(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+	for _, key := range keys {
+		items = append(items, object[key])
+	}
+	return items, nil
+}
+
+// yourlsLinks finds the links of a YOURLS document
+func yourlsLinks(doc json.RawMessage) ([]json.RawMessage, error) {
+	var response struct {
+		Links json.RawMessage `json:"links"`
+	}
+	if err := json.Unmarshal(doc, &response); err == nil && response.Links != nil {
+		doc = response.Links
+	}
+	return list(doc)
+}
+
+func parseYOURLS(item json.RawMessage) (Record, error) {
+	var link struct {
+		Keyword   string `json:"keyword"`
+		ShortURL  string `json:"shorturl"`
+		URL       string `json:"url"`
+		Timestamp string `json:"timestamp"`
+		Clicks    count  `json:"clicks"`
+	}
+	if err := json.Unmarshal(item, &link); err != nil {
+		return Record{}, err
+	}
+	record := Record{
+		SmallURL:       codeOf(link.Keyword, link.ShortURL),
+		LongURL:        link.URL,
+		ImportedClicks: uint64(link.Clicks),
+	}
+	created, err := parseTime(link.Timestamp, "timestamp")
+	if err != nil {
+		return Record{}, err
+	}
+	record.CreatedAt = timeOrZero(created)
+	return record, nil
+}
+
+// shlinkLinks finds the short urls of a Shlink document
+func shlinkLinks(doc json.RawMessage) ([]json.RawMessage, error) {
+	var response struct {
+		ShortURLs struct {
+			Data []json.RawMessage `json:"data"`
+		} `json:"shortUrls"`
+	}
+	if err := json.Unmarshal(doc, &response); err == nil && response.ShortURLs.Data != nil {
+		return response.ShortURLs.Data, nil
+	}
+	return list(doc)
+}
+
+func parseShlink(item json.RawMessage) (Record, error) {
+	var link struct {
+		ShortCode     string `json:"shortCode"`
+		ShortURL      string `json:"shortUrl"`
+		LongURL       string `json:"longUrl"`
+		DateCreated   string `json:"dateCreated"`
+		VisitsCount   count  `json:"visitsCount"`
+		VisitsSummary *struct {
+			Total count `json:"total"`
+		} `json:"visitsSummary"`
+		Meta struct {
+			ValidUntil string `json:"validUntil"`
+		} `json:"meta"`
+	}
+	if err := json.Unmarshal(item, &link); err != nil {
+		return Record{}, err
+	}
+	record := Record{
+		SmallURL:       codeOf(link.ShortCode, link.ShortURL),
+		LongURL:        link.LongURL,
+		ImportedClicks: uint64(link.VisitsCount),
+	}
+	// Newer versions count the visits in a summary
+	if link.VisitsSummary != nil {
+		record.ImportedClicks = uint64(link.VisitsSummary.Total)
+	}
+	created, err := parseTime(link.DateCreated, "dateCreated")
+	if err != nil {
+		return Record{}, err
+	}
+	record.CreatedAt = timeOrZero(created)
+	record.ExpiresAt, err = parseTime(link.Meta.ValidUntil, "validUntil")
+	if err != nil {
+		return Record{}, err
+	}
+	return record, nil
+}
@@ -0,0 +1,345 @@
+package transfer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Columns of a CSV export
+var csvHeader = []string{
+	"small_url", "admin_hash", "long_url", "created_at", "modified_at", "expires_at", "deleted_at", "disabled_at",
+	"count", "uniques", "bot_count", "imported_clicks", "sticky", "passthrough", "rules", "variants",
+	"owner_id", "rollups",
+}
+
+// ReadError is a record that could not be read. The next records
+// can still be read
+type ReadError struct {
+	Err error
+}
+
+func (e *ReadError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ReadError) Unwrap() error {
+	return e.Err
+}
+
+type writer interface {
+	Write(record Record) error
+	Flush() error
+}
+
+type reader interface {
+	// Read returns the next record and its line, or io.EOF
+	Read() (Record, int, error)
+}
+
+func newWriter(w io.Writer, format Format) (writer, error) {
+	switch format {
+	case JSONL:
+		buf := bufio.NewWriter(w)
+		return &jsonlWriter{buf: buf, enc: json.NewEncoder(buf)}, nil
+	case CSV:
+		return &csvWriter{w: csv.NewWriter(w)}, nil
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+func newReader(r io.Reader, format Format) (reader, error) {
+	switch format {
+	case JSONL:
+		return &jsonlReader{r: bufio.NewReader(r)}, nil
+	case CSV:
+		return newCSVReader(r, nil, "small_url", "admin_hash", "long_url")
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+type jsonlWriter struct {
+	buf *bufio.Writer
+	enc *json.Encoder
+}
+
+func (w *jsonlWriter) Write(record Record) error {
+	return w.enc.Encode(record)
+}
+
+func (w *jsonlWriter) Flush() error {
+	return w.buf.Flush()
+}
+
+type jsonlReader struct {
+	r    *bufio.Reader
+	line int
+}
+
+func (r *jsonlReader) Read() (Record, int, error) {
+	for {
+		// ReadBytes has no limit on the length of a line
+		data, err := r.r.ReadBytes('\n')
+		if len(data) == 0 && err != nil {
+			return Record{}, r.line, err
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return Record{}, r.line, err
+		}
+		r.line++
+		if len(strings.TrimSpace(string(data))) == 0 {
+			continue
+		}
+		var record Record
+		if err := json.Unmarshal(data, &record); err != nil {
+			return Record{}, r.line, &ReadError{Err: err}
+		}
+		return record, r.line, nil
+	}
+}
+
+type csvWriter struct {
+	w      *csv.Writer
+	header bool
+}
+
+// formatTime formats a time of a CSV column, nil is empty
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+// formatJSON formats the rules or the variants of a CSV column
+func formatJSON(v interface{}, n int) (string, error) {
+	if n == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(v)
+	return string(data), err
+}
+
+func (w *csvWriter) Write(record Record) error {
+	if !w.header {
+		if err := w.w.Write(csvHeader); err != nil {
+			return err
+		}
+		w.header = true
+	}
+	rules, err := formatJSON(record.Rules, len(record.Rules))
+	if err != nil {
+		return err
+	}
+	variants, err := formatJSON(record.Variants, len(record.Variants))
+	if err != nil {
+		return err
+	}
+	rollups, err := formatJSON(record.Rollups, len(record.Rollups))
+	if err != nil {
+		return err
+	}
+	return w.w.Write([]string{
+		record.SmallURL,
+		record.AdminHash,
+		record.LongURL,
+		formatTime(&record.CreatedAt),
+		formatTime(&record.ModifiedAt),
+		formatTime(record.ExpiresAt),
+		formatTime(record.DeletedAt),
+		formatTime(record.DisabledAt),
+		strconv.FormatUint(record.Count, 10),
+		strconv.FormatUint(record.Uniques, 10),
+		strconv.FormatUint(record.BotCount, 10),
+		strconv.FormatUint(record.ImportedClicks, 10),
+		strconv.FormatBool(record.Sticky),
+		record.Passthrough,
+		rules,
+		variants,
+		strconv.FormatInt(record.OwnerID, 10),
+		rollups,
+	})
+}
+
+func (w *csvWriter) Flush() error {
+	// An empty export still has its header
+	if !w.header {
+		if err := w.w.Write(csvHeader); err != nil {
+			return err
+		}
+		w.header = true
+	}
+	w.w.Flush()
+	return w.w.Error()
+}
+
+type csvReader struct {
+	r *csv.Reader
+	// Index of the columns by name
+	columns map[string]int
+}
+
+// newCSVReader reads the header, which must have the required columns.
+// Columns are found by their names, so they can be in any order and the
+// others can be missing. Aliases rename the columns of other shorteners
+// to the columns of a record
+func newCSVReader(r io.Reader, aliases map[string]string, required ...string) (*csvReader, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read CSV header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if alias, ok := aliases[name]; ok {
+			name = alias
+		}
+		columns[name] = i
+	}
+	for _, name := range required {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("CSV header has no %s column", name)
+		}
+	}
+	return &csvReader{r: reader, columns: columns}, nil
+}
+
+func (r *csvReader) Read() (Record, int, error) {
+	fields, err := r.r.Read()
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return Record{}, parseErr.StartLine, &ReadError{Err: err}
+	}
+	if err != nil {
+		return Record{}, 0, err
+	}
+	line, _ := r.r.FieldPos(0)
+	record, err := r.parse(fields)
+	if err != nil {
+		return Record{}, line, &ReadError{Err: err}
+	}
+	return record, line, nil
+}
+
+// field returns the value of the column, empty when it is missing
+func (r *csvReader) field(fields []string, name string) string {
+	i, ok := r.columns[name]
+	if !ok || i >= len(fields) {
+		return ""
+	}
+	return fields[i]
+}
+
+func (r *csvReader) parse(fields []string) (Record, error) {
+	record := Record{
+		SmallURL:    r.field(fields, "small_url"),
+		AdminHash:   r.field(fields, "admin_hash"),
+		LongURL:     r.field(fields, "long_url"),
+		Passthrough: r.field(fields, "passthrough"),
+	}
+	times := []struct {
+		name string
+		to   **time.Time
+	}{
+		{"expires_at", &record.ExpiresAt},
+		{"deleted_at", &record.DeletedAt},
+		{"disabled_at", &record.DisabledAt},
+	}
+	for _, t := range times {
+		value, err := parseTime(r.field(fields, t.name), t.name)
+		if err != nil {
+			return record, err
+		}
+		*t.to = value
+	}
+	created, err := parseTime(r.field(fields, "created_at"), "created_at")
+	if err != nil {
+		return record, err
+	}
+	record.CreatedAt = timeOrZero(created)
+	modified, err := parseTime(r.field(fields, "modified_at"), "modified_at")
+	if err != nil {
+		return record, err
+	}
+	record.ModifiedAt = timeOrZero(modified)
+	counters := []struct {
+		name string
+		to   *uint64
+	}{
+		{"count", &record.Count},
+		{"uniques", &record.Uniques},
+		{"bot_count", &record.BotCount},
+		{"imported_clicks", &record.ImportedClicks},
+	}
+	for _, c := range counters {
+		value := r.field(fields, c.name)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return record, fmt.Errorf("%s %q is not a number", c.name, value)
+		}
+		*c.to = n
+	}
+	if value := r.field(fields, "sticky"); value != "" {
+		sticky, err := strconv.ParseBool(value)
+		if err != nil {
+			return record, fmt.Errorf("sticky %q is not a boolean", value)
+		}
+		record.Sticky = sticky
+	}
+	if value := r.field(fields, "rules"); value != "" {
+		if err := json.Unmarshal([]byte(value), &record.Rules); err != nil {
+			return record, fmt.Errorf("rules: %w", err)
+		}
+	}
+	if value := r.field(fields, "variants"); value != "" {
+		if err := json.Unmarshal([]byte(value), &record.Variants); err != nil {
+			return record, fmt.Errorf("variants: %w", err)
+		}
+	}
+	if value := r.field(fields, "owner_id"); value != "" {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return record, fmt.Errorf("owner_id %q is not a number", value)
+		}
+		record.OwnerID = id
+	}
+	if value := r.field(fields, "rollups"); value != "" {
+		if err := json.Unmarshal([]byte(value), &record.Rollups); err != nil {
+			return record, fmt.Errorf("rollups: %w", err)
+		}
+	}
+	return record, nil
+}
+
+// Layouts of the times of other shorteners, tried after RFC 3339.
+// Times without a zone are in UTC
+var timeLayouts = []string{"2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02"}
+
+// parseTime parses a time of a CSV column, empty is nil
+func parseTime(value, name string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	for _, layout := range timeLayouts {
+		if err == nil {
+			break
+		}
+		t, err = time.Parse(layout, value)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s %q is not an RFC 3339 time", name, value)
+	}
+	return &t, nil
+}
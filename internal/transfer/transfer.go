@@ -0,0 +1,441 @@
+// Package transfer exports the small urls of a store and imports them
+// into another one as JSON Lines or CSV, keeping their codes
+package transfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sanyarise/smurl/internal/helpers"
+	"github.com/sanyarise/smurl/internal/models"
+)
+
+// Format of an export
+type Format string
+
+const (
+	// One JSON object per line
+	JSONL Format = "jsonl"
+	// A header line, then one line per small url. Rules, variants
+	// and rollups are JSON arrays in their columns
+	CSV Format = "csv"
+)
+
+// ParseFormat returns the format with the name
+func ParseFormat(name string) (Format, error) {
+	switch Format(name) {
+	case JSONL, CSV:
+		return Format(name), nil
+	}
+	return "", fmt.Errorf("unknown format %q, use jsonl or csv", name)
+}
+
+// Exporter streams the small urls of a store
+type Exporter interface {
+	ExportLinks(ctx context.Context, fn func(models.Smurl) error) error
+}
+
+// Importer saves small urls with their codes
+type Importer interface {
+	ImportLink(ctx context.Context, smurl models.Smurl) (*models.Smurl, error)
+}
+
+// DomainPolicy checks the host of a destination
+// against the allow and deny lists
+type DomainPolicy interface {
+	Check(host string) error
+}
+
+// Destinations checks the urls of imported records as the service checks
+// those of new links, rules and variants, and normalizes them
+type Destinations struct {
+	urls   helpers.Helper
+	policy DomainPolicy
+}
+
+// NewDestinations creates the checks with the url helpers and the domain policy of the service
+func NewDestinations(urls helpers.Helper, policy DomainPolicy) *Destinations {
+	return &Destinations{
+		urls:   urls,
+		policy: policy,
+	}
+}
+
+// checkURL returns the normalized url, or why it is rejected
+func (d *Destinations) checkURL(rawURL string) (string, error) {
+	normalized, err := d.urls.CheckURL(rawURL)
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(normalized)
+	if err != nil {
+		return "", err
+	}
+	if err := d.policy.Check(u.Hostname()); err != nil {
+		return "", err
+	}
+	return normalized, nil
+}
+
+// check returns the record with its long url, rule
+// urls and variant urls normalized
+func (d *Destinations) check(record Record) (Record, error) {
+	longURL, err := d.checkURL(record.LongURL)
+	if err != nil {
+		return record, err
+	}
+	record.LongURL = longURL
+	// The rules and the variants are copied, the record may share them
+	rules := make([]Rule, 0, len(record.Rules))
+	for _, rule := range record.Rules {
+		if rule.URL, err = d.checkURL(rule.URL); err != nil {
+			return record, fmt.Errorf("rule: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+	variants := make([]Variant, 0, len(record.Variants))
+	for _, variant := range record.Variants {
+		if variant.URL, err = d.checkURL(variant.URL); err != nil {
+			return record, fmt.Errorf("variant: %w", err)
+		}
+		variants = append(variants, variant)
+	}
+	if len(record.Rules) > 0 {
+		record.Rules = rules
+	}
+	if len(record.Variants) > 0 {
+		record.Variants = variants
+	}
+	return record, nil
+}
+
+// Record is an exported small url. Only the hash of the admin
+// token is known, so it is exported instead of the token
+type Record struct {
+	SmallURL   string     `json:"small_url"`
+	AdminHash  string     `json:"admin_hash"`
+	LongURL    string     `json:"long_url"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ModifiedAt time.Time  `json:"modified_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	DeletedAt  *time.Time `json:"deleted_at,omitempty"`
+	DisabledAt *time.Time `json:"disabled_at,omitempty"`
+	Count      uint64     `json:"count"`
+	Uniques    uint64     `json:"uniques"`
+	BotCount   uint64     `json:"bot_count"`
+	// Clicks counted by the shortener the small url was imported from
+	ImportedClicks uint64    `json:"imported_clicks,omitempty"`
+	Sticky         bool      `json:"sticky,omitempty"`
+	Passthrough    string    `json:"passthrough,omitempty"`
+	Rules          []Rule    `json:"rules,omitempty"`
+	Variants       []Variant `json:"variants,omitempty"`
+	// API key that created the small url, zero for none
+	OwnerID int64    `json:"owner_id,omitempty"`
+	Rollups []Rollup `json:"rollups,omitempty"`
+}
+
+// Rule is an exported routing rule
+type Rule struct {
+	Position int    `json:"position"`
+	Platform string `json:"platform,omitempty"`
+	Country  string `json:"country,omitempty"`
+	Language string `json:"language,omitempty"`
+	URL      string `json:"url"`
+}
+
+// Variant is an exported weighted destination
+type Variant struct {
+	URL    string `json:"url"`
+	Weight int    `json:"weight"`
+	Count  uint64 `json:"count"`
+}
+
+// Rollup is an exported bucket of the time series of the clicks
+type Rollup struct {
+	Granularity string    `json:"granularity"`
+	Start       time.Time `json:"start"`
+	Count       uint64    `json:"count"`
+}
+
+// Problem is a record that was not imported
+type Problem struct {
+	// Line of the record in the input, or its
+	// position in the list of a JSON document
+	Line     int    `json:"line"`
+	SmallURL string `json:"small_url"`
+	Reason   string `json:"reason"`
+}
+
+// Report tells what an import did
+type Report struct {
+	Created int `json:"created"`
+	// Records already imported before
+	Unchanged int `json:"unchanged"`
+	// Records whose codes belong to other small urls
+	Conflicts []Problem `json:"conflicts"`
+	// Records that could not be read or are not valid
+	Invalid []Problem `json:"invalid"`
+	// Admin tokens of the small urls created by an import from
+	// another shortener, they are not stored and shown only here
+	AdminTokens []AdminToken `json:"admin_tokens,omitempty"`
+}
+
+// AdminToken is the admin token of an imported small url
+type AdminToken struct {
+	SmallURL string `json:"small_url"`
+	Token    string `json:"token"`
+}
+
+// timeOrNil returns nil for a zero time
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	t = t.UTC()
+	return &t
+}
+
+// timeOrZero returns the zero time for nil
+func timeOrZero(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return *t
+}
+
+// NewRecord converts a small url of a store to a record
+func NewRecord(smurl models.Smurl) Record {
+	record := Record{
+		SmallURL:       smurl.SmallURL,
+		AdminHash:      smurl.AdminURL,
+		LongURL:        smurl.LongURL,
+		CreatedAt:      smurl.CreatedAt.UTC(),
+		ModifiedAt:     smurl.ModifiedAt.UTC(),
+		ExpiresAt:      timeOrNil(smurl.ExpiresAt),
+		DeletedAt:      timeOrNil(smurl.DeletedAt),
+		DisabledAt:     timeOrNil(smurl.DisabledAt),
+		Count:          smurl.Count,
+		Uniques:        smurl.Uniques,
+		BotCount:       smurl.BotCount,
+		ImportedClicks: smurl.ImportedClicks,
+		Sticky:         smurl.Sticky,
+		Passthrough:    string(smurl.Passthrough),
+		OwnerID:        smurl.OwnerID,
+	}
+	for _, rule := range smurl.Rules {
+		record.Rules = append(record.Rules, Rule{
+			Position: rule.Position,
+			Platform: rule.Platform,
+			Country:  rule.Country,
+			Language: rule.Language,
+			URL:      rule.URL,
+		})
+	}
+	for _, variant := range smurl.Variants {
+		record.Variants = append(record.Variants, Variant{URL: variant.URL, Weight: variant.Weight, Count: variant.Count})
+	}
+	for _, rollup := range smurl.Rollups {
+		record.Rollups = append(record.Rollups, Rollup{Granularity: string(rollup.Granularity), Start: rollup.Start.UTC(), Count: rollup.Count})
+	}
+	return record
+}
+
+// Smurl converts the record to a small url of a store
+func (r Record) Smurl() models.Smurl {
+	smurl := models.Smurl{
+		SmallURL:       r.SmallURL,
+		AdminURL:       r.AdminHash,
+		LongURL:        r.LongURL,
+		CreatedAt:      r.CreatedAt,
+		ModifiedAt:     r.ModifiedAt,
+		ExpiresAt:      timeOrZero(r.ExpiresAt),
+		DeletedAt:      timeOrZero(r.DeletedAt),
+		DisabledAt:     timeOrZero(r.DisabledAt),
+		Count:          r.Count,
+		Uniques:        r.Uniques,
+		BotCount:       r.BotCount,
+		ImportedClicks: r.ImportedClicks,
+		Sticky:         r.Sticky,
+		Passthrough:    models.Passthrough(r.Passthrough),
+		OwnerID:        r.OwnerID,
+	}
+	for _, rule := range r.Rules {
+		smurl.Rules = append(smurl.Rules, models.Rule{
+			Position: rule.Position,
+			Platform: rule.Platform,
+			Country:  rule.Country,
+			Language: rule.Language,
+			URL:      rule.URL,
+		})
+	}
+	for _, variant := range r.Variants {
+		smurl.Variants = append(smurl.Variants, models.Variant{URL: variant.URL, Weight: variant.Weight, Count: variant.Count})
+	}
+	for _, rollup := range r.Rollups {
+		smurl.Rollups = append(smurl.Rollups, models.Rollup{Granularity: models.Granularity(rollup.Granularity), Start: rollup.Start, Count: rollup.Count})
+	}
+	return smurl
+}
+
+// isHash reports whether s is a hex encoded SHA-256 hash
+func isHash(s string) bool {
+	if len(s) != 64 {
+		return false
+	}
+	for _, c := range s {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// isURL reports whether s is an absolute url
+func isURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
+
+// Validate checks that the record can be imported
+func (r Record) Validate() error {
+	switch {
+	case r.SmallURL == "":
+		return errors.New("missing small_url")
+	case strings.ContainsAny(r.SmallURL, "/?#% \t\r\n"):
+		return fmt.Errorf("small_url %q is not a valid code", r.SmallURL)
+	case !isHash(r.AdminHash):
+		return errors.New("admin_hash is not a hex encoded SHA-256 hash")
+	case !isURL(r.LongURL):
+		return fmt.Errorf("long_url %q is not an absolute url", r.LongURL)
+	case !models.Passthrough(r.Passthrough).Valid():
+		return fmt.Errorf("unknown passthrough mode %q", r.Passthrough)
+	}
+	for _, rule := range r.Rules {
+		if !isURL(rule.URL) {
+			return fmt.Errorf("rule url %q is not an absolute url", rule.URL)
+		}
+	}
+	for _, variant := range r.Variants {
+		if !isURL(variant.URL) {
+			return fmt.Errorf("variant url %q is not an absolute url", variant.URL)
+		}
+		if variant.Weight <= 0 {
+			return fmt.Errorf("variant weight %d is not positive", variant.Weight)
+		}
+	}
+	if r.OwnerID < 0 {
+		return fmt.Errorf("owner_id %d is negative", r.OwnerID)
+	}
+	for _, rollup := range r.Rollups {
+		granularity := models.Granularity(rollup.Granularity)
+		if granularity != models.Hourly && granularity != models.Daily {
+			return fmt.Errorf("unknown rollup granularity %q", rollup.Granularity)
+		}
+		if !rollup.Start.Equal(granularity.Truncate(rollup.Start)) {
+			return fmt.Errorf("rollup start %s is not the start of its bucket", rollup.Start.Format(time.RFC3339))
+		}
+	}
+	return nil
+}
+
+// Export writes every small url of the store in the format and
+// returns their number. The small urls are written as they are read
+func Export(ctx context.Context, store Exporter, w io.Writer, format Format) (int, error) {
+	writer, err := newWriter(w, format)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	err = store.ExportLinks(ctx, func(smurl models.Smurl) error {
+		n++
+		return writer.Write(NewRecord(smurl))
+	})
+	if err != nil {
+		return n, err
+	}
+	return n, writer.Flush()
+}
+
+// Import saves the small urls read in the format into the store. Records
+// already imported are skipped, so an interrupted import can be run again.
+// Their urls pass the checks of the destinations and are saved normalized.
+// Records in conflict with the small urls of the store and invalid
+// records are reported and skipped, errors of the store stop the import
+func Import(ctx context.Context, store Importer, r io.Reader, format Format, destinations *Destinations) (*Report, error) {
+	reader, err := newReader(r, format)
+	if err != nil {
+		return nil, err
+	}
+	report := &Report{Conflicts: []Problem{}, Invalid: []Problem{}}
+	for {
+		record, line, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return report, nil
+		}
+		var readErr *ReadError
+		if errors.As(err, &readErr) {
+			report.Invalid = append(report.Invalid, Problem{Line: line, Reason: readErr.Error()})
+			continue
+		}
+		if err != nil {
+			return report, err
+		}
+		if _, err := importRecord(ctx, store, destinations, record, line, report, sameExport); err != nil {
+			return report, err
+		}
+	}
+}
+
+// sameExport reports whether the existing small url was imported from
+// the record. The admin code is part of an export, so it must match
+func sameExport(existing *models.Smurl, record Record) bool {
+	return existing.AdminURL == record.AdminHash && existing.LongURL == record.LongURL
+}
+
+// importRecord checks the urls of a record with the destinations, saves it
+// into the store, adds the outcome to the report and tells whether the small
+// url was created. The existing small urls for which same is true were
+// imported before and are unchanged. It returns the errors of the store
+func importRecord(ctx context.Context, store Importer, destinations *Destinations, record Record, line int, report *Report,
+	same func(existing *models.Smurl, record Record) bool) (bool, error) {
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = time.Now().UTC()
+	}
+	if record.ModifiedAt.IsZero() {
+		record.ModifiedAt = record.CreatedAt
+	}
+	if err := record.Validate(); err != nil {
+		report.Invalid = append(report.Invalid, Problem{Line: line, SmallURL: record.SmallURL, Reason: err.Error()})
+		return false, nil
+	}
+	checked, err := destinations.check(record)
+	if err != nil {
+		report.Invalid = append(report.Invalid, Problem{Line: line, SmallURL: record.SmallURL, Reason: err.Error()})
+		return false, nil
+	}
+	record = checked
+	existing, err := store.ImportLink(ctx, record.Smurl())
+	if errors.Is(err, models.ErrConflict) {
+		switch {
+		case existing == nil:
+			report.Conflicts = append(report.Conflicts, Problem{Line: line, SmallURL: record.SmallURL,
+				Reason: "the admin code belongs to another small url"})
+		case same(existing, record):
+			report.Unchanged++
+		default:
+			report.Conflicts = append(report.Conflicts, Problem{Line: line, SmallURL: record.SmallURL,
+				Reason: "the small url exists with another long url or admin code"})
+		}
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("import %s: %w", record.SmallURL, err)
+	}
+	report.Created++
+	return true, nil
+}
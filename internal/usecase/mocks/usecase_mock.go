@@ -7,6 +7,7 @@ package mocks
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 	models "github.com/sanyarise/smurl/internal/models"
@@ -35,19 +36,104 @@ func (m *MockUsecase) EXPECT() *MockUsecaseMockRecorder {
 	return m.recorder
 }
 
+// AddRule mocks base method.
+func (m *MockUsecase) AddRule(ctx context.Context, adminUrl string, rule models.Rule) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddRule", ctx, adminUrl, rule)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddRule indicates an expected call of AddRule.
+func (mr *MockUsecaseMockRecorder) AddRule(ctx, adminUrl, rule interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddRule", reflect.TypeOf((*MockUsecase)(nil).AddRule), ctx, adminUrl, rule)
+}
+
+// AddVariant mocks base method.
+func (m *MockUsecase) AddVariant(ctx context.Context, adminUrl string, variant models.Variant) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddVariant", ctx, adminUrl, variant)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddVariant indicates an expected call of AddVariant.
+func (mr *MockUsecaseMockRecorder) AddVariant(ctx, adminUrl, variant interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddVariant", reflect.TypeOf((*MockUsecase)(nil).AddVariant), ctx, adminUrl, variant)
+}
+
 // Create mocks base method.
-func (m *MockUsecase) Create(ctx context.Context, longUrl string) (*models.Smurl, error) {
+func (m *MockUsecase) Create(ctx context.Context, longUrl string, passthrough models.Passthrough) (*models.Smurl, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Create", ctx, longUrl)
+	ret := m.ctrl.Call(m, "Create", ctx, longUrl, passthrough)
 	ret0, _ := ret[0].(*models.Smurl)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Create indicates an expected call of Create.
-func (mr *MockUsecaseMockRecorder) Create(ctx, longUrl interface{}) *gomock.Call {
+func (mr *MockUsecaseMockRecorder) Create(ctx, longUrl, passthrough interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockUsecase)(nil).Create), ctx, longUrl, passthrough)
+}
+
+// Delete mocks base method.
+func (m *MockUsecase) Delete(ctx context.Context, adminUrl string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, adminUrl)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockUsecaseMockRecorder) Delete(ctx, adminUrl interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockUsecase)(nil).Delete), ctx, adminUrl)
+}
+
+// DeleteRule mocks base method.
+func (m *MockUsecase) DeleteRule(ctx context.Context, adminUrl string, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteRule", ctx, adminUrl, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteRule indicates an expected call of DeleteRule.
+func (mr *MockUsecaseMockRecorder) DeleteRule(ctx, adminUrl, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteRule", reflect.TypeOf((*MockUsecase)(nil).DeleteRule), ctx, adminUrl, id)
+}
+
+// DeleteVariant mocks base method.
+func (m *MockUsecase) DeleteVariant(ctx context.Context, adminUrl string, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteVariant", ctx, adminUrl, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteVariant indicates an expected call of DeleteVariant.
+func (mr *MockUsecaseMockRecorder) DeleteVariant(ctx, adminUrl, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteVariant", reflect.TypeOf((*MockUsecase)(nil).DeleteVariant), ctx, adminUrl, id)
+}
+
+// Edit mocks base method.
+func (m *MockUsecase) Edit(ctx context.Context, adminUrl string, edit models.Edit) (*models.Smurl, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Edit", ctx, adminUrl, edit)
+	ret0, _ := ret[0].(*models.Smurl)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Edit indicates an expected call of Edit.
+func (mr *MockUsecaseMockRecorder) Edit(ctx, adminUrl, edit interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockUsecase)(nil).Create), ctx, longUrl)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Edit", reflect.TypeOf((*MockUsecase)(nil).Edit), ctx, adminUrl, edit)
 }
 
 // FindURL mocks base method.
@@ -65,6 +151,35 @@ func (mr *MockUsecaseMockRecorder) FindURL(ctx, smallUrl interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindURL", reflect.TypeOf((*MockUsecase)(nil).FindURL), ctx, smallUrl)
 }
 
+// ForgetVisitors mocks base method.
+func (m *MockUsecase) ForgetVisitors(ctx context.Context, adminUrl string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ForgetVisitors", ctx, adminUrl)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ForgetVisitors indicates an expected call of ForgetVisitors.
+func (mr *MockUsecaseMockRecorder) ForgetVisitors(ctx, adminUrl interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ForgetVisitors", reflect.TypeOf((*MockUsecase)(nil).ForgetVisitors), ctx, adminUrl)
+}
+
+// PurgeClicks mocks base method.
+func (m *MockUsecase) PurgeClicks(ctx context.Context, before time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeClicks", ctx, before)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeClicks indicates an expected call of PurgeClicks.
+func (mr *MockUsecaseMockRecorder) PurgeClicks(ctx, before interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeClicks", reflect.TypeOf((*MockUsecase)(nil).PurgeClicks), ctx, before)
+}
+
 // ReadStat mocks base method.
 func (m *MockUsecase) ReadStat(ctx context.Context, adminUrl string) (*models.Smurl, error) {
 	m.ctrl.T.Helper()
@@ -80,16 +195,74 @@ func (mr *MockUsecaseMockRecorder) ReadStat(ctx, adminUrl interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadStat", reflect.TypeOf((*MockUsecase)(nil).ReadStat), ctx, adminUrl)
 }
 
+// Route mocks base method.
+func (m *MockUsecase) Route(smurl models.Smurl, click models.Click) (string, int64) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Route", smurl, click)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(int64)
+	return ret0, ret1
+}
+
+// Route indicates an expected call of Route.
+func (mr *MockUsecaseMockRecorder) Route(smurl, click interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Route", reflect.TypeOf((*MockUsecase)(nil).Route), smurl, click)
+}
+
+// SetPassthrough mocks base method.
+func (m *MockUsecase) SetPassthrough(ctx context.Context, adminUrl string, passthrough models.Passthrough) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetPassthrough", ctx, adminUrl, passthrough)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetPassthrough indicates an expected call of SetPassthrough.
+func (mr *MockUsecaseMockRecorder) SetPassthrough(ctx, adminUrl, passthrough interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPassthrough", reflect.TypeOf((*MockUsecase)(nil).SetPassthrough), ctx, adminUrl, passthrough)
+}
+
+// SetSticky mocks base method.
+func (m *MockUsecase) SetSticky(ctx context.Context, adminUrl string, sticky bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetSticky", ctx, adminUrl, sticky)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetSticky indicates an expected call of SetSticky.
+func (mr *MockUsecaseMockRecorder) SetSticky(ctx, adminUrl, sticky interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetSticky", reflect.TypeOf((*MockUsecase)(nil).SetSticky), ctx, adminUrl, sticky)
+}
+
+// TimeSeries mocks base method.
+func (m *MockUsecase) TimeSeries(ctx context.Context, adminUrl string, granularity models.Granularity, from, to time.Time) ([]models.TimeBucket, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TimeSeries", ctx, adminUrl, granularity, from, to)
+	ret0, _ := ret[0].([]models.TimeBucket)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TimeSeries indicates an expected call of TimeSeries.
+func (mr *MockUsecaseMockRecorder) TimeSeries(ctx, adminUrl, granularity, from, to interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TimeSeries", reflect.TypeOf((*MockUsecase)(nil).TimeSeries), ctx, adminUrl, granularity, from, to)
+}
+
 // UpdateStat mocks base method.
-func (m *MockUsecase) UpdateStat(ctx context.Context, updatedSmurl models.Smurl) error {
+func (m *MockUsecase) UpdateStat(ctx context.Context, updatedSmurl models.Smurl, click models.Click) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateStat", ctx, updatedSmurl)
+	ret := m.ctrl.Call(m, "UpdateStat", ctx, updatedSmurl, click)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // UpdateStat indicates an expected call of UpdateStat.
-func (mr *MockUsecaseMockRecorder) UpdateStat(ctx, updatedSmurl interface{}) *gomock.Call {
+func (mr *MockUsecaseMockRecorder) UpdateStat(ctx, updatedSmurl, click interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateStat", reflect.TypeOf((*MockUsecase)(nil).UpdateStat), ctx, updatedSmurl)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateStat", reflect.TypeOf((*MockUsecase)(nil).UpdateStat), ctx, updatedSmurl, click)
 }
@@ -2,9 +2,16 @@ package usecase
 
 import (
 	"context"
+	"crypto/rand"
+	"errors"
 	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+	"time"
 
 	"github.com/sanyarise/smurl/internal/helpers"
+	"github.com/sanyarise/smurl/internal/helpers/codegen"
 	"github.com/sanyarise/smurl/internal/models"
 	"go.uber.org/zap"
 )
@@ -12,56 +19,154 @@ import (
 // Interface for communication with the database
 type SmurlStore interface {
 	Create(ctx context.Context, smurl models.Smurl) (*models.Smurl, error)
-	UpdateStat(ctx context.Context, smurl models.Smurl) error
-	ReadStat(ctx context.Context, adminUrl string) (*models.Smurl, error)
+	UpdateStat(ctx context.Context, smurl models.Smurl, click models.Click) error
+	ReadStat(ctx context.Context, adminHash string) (*models.Smurl, error)
+	ReadClickStats(ctx context.Context, smallUrl string) (*models.ClickStats, error)
+	ReadTimeSeries(ctx context.Context, smallUrl string, granularity models.Granularity, from, to time.Time) ([]models.TimeBucket, error)
 	FindURL(ctx context.Context, smallUrl string) (*models.Smurl, error)
+	ForgetVisitors(ctx context.Context, smallUrl string) error
+	PurgeClicks(ctx context.Context, before time.Time) (int64, error)
+	AddRule(ctx context.Context, smallUrl string, rule models.Rule) error
+	DeleteRule(ctx context.Context, smallUrl string, id int64) error
+	AddVariant(ctx context.Context, smallUrl string, variant models.Variant) error
+	DeleteVariant(ctx context.Context, smallUrl string, id int64) error
+	SetSticky(ctx context.Context, smallUrl string, sticky bool) error
+	SetPassthrough(ctx context.Context, smallUrl string, passthrough models.Passthrough) error
+	Edit(ctx context.Context, smallUrl string, edit models.Edit) error
+	Delete(ctx context.Context, smallUrl string) error
+	// ExportLinks calls fn with every small url, deleted ones included,
+	// with the hash of its admin token, its counters, rules and variants
+	ExportLinks(ctx context.Context, fn func(models.Smurl) error) error
+	// ImportLink saves a small url with its codes. When a code is taken
+	// it returns ErrConflict with the small url holding the code, if any
+	ImportLink(ctx context.Context, smurl models.Smurl) (*models.Smurl, error)
+}
+
+// Maximum number of buckets in a time series
+const maxBuckets = 1000
+
+// Maximum weight of a variant
+const maxWeight = 1000
+
+// Number of codes tried when creating a small url
+const maxCodeAttempts = 5
+
+// Interface for checking destination domains against allow and deny lists
+type DomainPolicy interface {
+	Check(host string) error
+}
+
+// Interface for finding the location of client addresses
+type GeoLocator interface {
+	Locate(ip string) models.Location
+}
+
+// Interface for streaming the saved clicks, Publish must not block
+type ClickPublisher interface {
+	Publish(click models.Click)
 }
 
 var _ Usecase = SmurlUsecase{}
 
 type SmurlUsecase struct {
 	repository SmurlStore
+	policy     DomainPolicy
+	geo        GeoLocator
+	clicks     ClickPublisher
 	helpers    helpers.Helper
+	codes      codegen.CodeGenerator
+	adminCodes codegen.CodeGenerator
 	logger     *zap.Logger
 }
 
-func NewSmurlUsecase(smurlStore SmurlStore, helpers helpers.Helper, logger *zap.Logger) *SmurlUsecase {
+// NewSmurlUsecase creates the usecase. The codes of small urls
+// and of admin urls are made by separate generators
+func NewSmurlUsecase(smurlStore SmurlStore, policy DomainPolicy, geo GeoLocator, clicks ClickPublisher, helpers helpers.Helper, codes codegen.CodeGenerator, adminCodes codegen.CodeGenerator, logger *zap.Logger) *SmurlUsecase {
 	logger.Debug("Enter in usecase NewSmurlUsecase()")
 	return &SmurlUsecase{
 		repository: smurlStore,
+		policy:     policy,
+		geo:        geo,
+		clicks:     clicks,
 		helpers:    helpers,
+		codes:      codes,
+		adminCodes: adminCodes,
 		logger:     logger,
 	}
 }
 
-func (usecase SmurlUsecase) Create(ctx context.Context, longUrl string) (*models.Smurl, error) {
+func (usecase SmurlUsecase) Create(ctx context.Context, longUrl string, passthrough models.Passthrough) (*models.Smurl, error) {
 	usecase.logger.Debug("Enter in usecase Create()")
-	createdSmurl := models.Smurl{
-		LongURL: longUrl,
+	if !passthrough.Valid() {
+		return nil, &models.ValidationError{Reason: fmt.Sprintf("unknown passthrough mode %q", passthrough)}
 	}
-	createdSmurl.SmallURL = usecase.helpers.RandString()
-	createdSmurl.AdminURL = usecase.helpers.RandString()
-
-	smurl, err := usecase.repository.Create(ctx, createdSmurl)
-	if err != nil {
-		usecase.logger.Error("",
+	if err := usecase.checkDomain(longUrl); err != nil {
+		usecase.logger.Info("",
 			zap.Error(err))
-		return nil, fmt.Errorf("create url error: %w", err)
+		return nil, err
+	}
+	createdSmurl := models.Smurl{
+		LongURL:     longUrl,
+		Passthrough: passthrough,
+		OwnerID:     models.OwnerFrom(ctx),
+	}
+	// A random code may already be taken, then new codes are generated
+	for attempt := 1; ; attempt++ {
+		var err error
+		createdSmurl.SmallURL, err = usecase.codes.Generate(ctx)
+		if err != nil {
+			usecase.logger.Error("",
+				zap.Error(err))
+			return nil, fmt.Errorf("generate code error: %w", err)
+		}
+		adminToken, err := usecase.adminCodes.Generate(ctx)
+		if err != nil {
+			usecase.logger.Error("",
+				zap.Error(err))
+			return nil, fmt.Errorf("generate admin code error: %w", err)
+		}
+		// Only the hash of the admin token is stored
+		createdSmurl.AdminURL = helpers.HashToken(adminToken)
+		smurl, err := usecase.repository.Create(ctx, createdSmurl)
+		if errors.Is(err, models.ErrConflict) && attempt < maxCodeAttempts {
+			usecase.logger.Warn("generated code is taken",
+				zap.Int("attempt", attempt))
+			continue
+		}
+		if err != nil {
+			usecase.logger.Error("",
+				zap.Error(err))
+			return nil, fmt.Errorf("create url error: %w", err)
+		}
+		smurl.AdminURL = adminToken
+		return smurl, nil
 	}
-	return smurl, nil
 }
 
-func (usecase SmurlUsecase) UpdateStat(ctx context.Context, updatedSmurl models.Smurl) error {
+func (usecase SmurlUsecase) UpdateStat(ctx context.Context, updatedSmurl models.Smurl, click models.Click) error {
 	usecase.logger.Debug("Enter in usecase UpdateStat()")
-	// Update the hit counter field
-	updatedSmurl.Count++
+	// Fill in the click details derived from the request headers
+	click.SmallURL = updatedSmurl.SmallURL
+	if click.CreatedAt.IsZero() {
+		click.CreatedAt = time.Now()
+	}
+	click.VisitorID = usecase.helpers.VisitorID(click.IP, click.UserAgent, click.CreatedAt)
+	click.Location = usecase.geo.Locate(click.IP)
+	// Only the anonymized address is stored
+	click.IP = usecase.helpers.AnonymizeIP(click.IP)
+	click.RefererDomain = helpers.RefererDomain(click.Referer)
+	ua := helpers.ParseUserAgent(click.UserAgent)
+	click.Browser = ua.Browser
+	click.OS = ua.OS
+	click.Device = ua.Device
 	// Call the database method to update statistics
-	err := usecase.repository.UpdateStat(ctx, updatedSmurl)
+	err := usecase.repository.UpdateStat(ctx, updatedSmurl, click)
 	if err != nil {
 		usecase.logger.Error("",
 			zap.Error(err))
 		return fmt.Errorf("update stat error: %w", err)
 	}
+	usecase.clicks.Publish(click)
 	return nil
 }
 
@@ -71,14 +176,415 @@ func (usecase SmurlUsecase) FindURL(ctx context.Context, smallUrl string) (*mode
 	if err != nil {
 		return nil, err
 	}
+	if smurl.Deleted() {
+		return nil, fmt.Errorf("small url %s: %w", smallUrl, models.ErrDeleted)
+	}
+	if smurl.Disabled() {
+		return nil, fmt.Errorf("small url %s: %w", smallUrl, models.ErrDisabled)
+	}
+	if smurl.Expired(time.Now()) {
+		return nil, fmt.Errorf("small url %s: %w", smallUrl, models.ErrExpired)
+	}
+	// The lists may have changed since the link was created
+	if err := usecase.checkDomain(smurl.LongURL); err != nil {
+		usecase.logger.Info("",
+			zap.Error(err))
+		return nil, err
+	}
+	return smurl, nil
+}
+
+// readAdmin finds the small url by its admin token. The store is
+// searched by the hash of the token, the hash is compared again
+// in constant time. The token is returned in AdminURL. Deleted
+// small urls are not found
+func (usecase SmurlUsecase) readAdmin(ctx context.Context, adminUrl string) (*models.Smurl, error) {
+	smurl, err := usecase.repository.ReadStat(ctx, helpers.HashToken(adminUrl))
+	if err != nil {
+		return nil, err
+	}
+	if !helpers.TokenMatches(adminUrl, smurl.AdminURL) {
+		return nil, models.ErrNotFound
+	}
+	// The statistics of an expired small url stay available
+	if smurl.Deleted() {
+		return nil, models.ErrDeleted
+	}
+	smurl.AdminURL = adminUrl
 	return smurl, nil
 }
 
 func (usecase SmurlUsecase) ReadStat(ctx context.Context, adminUrl string) (*models.Smurl, error) {
 	usecase.logger.Debug("Enter in usecase ReadStat()")
-	smurl, err := usecase.repository.ReadStat(ctx, adminUrl)
+	smurl, err := usecase.readAdmin(ctx, adminUrl)
 	if err != nil {
 		return nil, err
 	}
+	stats, err := usecase.repository.ReadClickStats(ctx, smurl.SmallURL)
+	if err != nil {
+		usecase.logger.Error("",
+			zap.Error(err))
+		return nil, fmt.Errorf("read click stats error: %w", err)
+	}
+	smurl.Stats = *stats
 	return smurl, nil
 }
+
+// TimeSeries returns the number of clicks in every bucket of the given
+// granularity between from and to, including buckets without clicks
+func (usecase SmurlUsecase) TimeSeries(ctx context.Context, adminUrl string, granularity models.Granularity, from, to time.Time) ([]models.TimeBucket, error) {
+	usecase.logger.Debug("Enter in usecase TimeSeries()")
+	if granularity != models.Hourly && granularity != models.Daily {
+		return nil, &models.ValidationError{Reason: fmt.Sprintf("unknown granularity %q", granularity)}
+	}
+	from = granularity.Truncate(from)
+	to = granularity.Truncate(to)
+	if to.Before(from) {
+		return nil, &models.ValidationError{Reason: "time range ends before it starts"}
+	}
+	count := int(to.Sub(from)/granularity.Duration()) + 1
+	if count > maxBuckets {
+		return nil, &models.ValidationError{Reason: fmt.Sprintf("time range exceeds %d buckets", maxBuckets)}
+	}
+	smurl, err := usecase.readAdmin(ctx, adminUrl)
+	if err != nil {
+		return nil, err
+	}
+	buckets, err := usecase.repository.ReadTimeSeries(ctx, smurl.SmallURL, granularity, from, to)
+	if err != nil {
+		usecase.logger.Error("",
+			zap.Error(err))
+		return nil, fmt.Errorf("read time series error: %w", err)
+	}
+	// Fill the buckets without clicks with zeros
+	counts := make(map[int64]uint64, len(buckets))
+	for _, bucket := range buckets {
+		counts[bucket.Start.Unix()] = bucket.Count
+	}
+	series := make([]models.TimeBucket, count)
+	for i := range series {
+		start := from.Add(time.Duration(i) * granularity.Duration())
+		series[i] = models.TimeBucket{Start: start, Count: counts[start.Unix()]}
+	}
+	return series, nil
+}
+
+// ForgetVisitors deletes the click details and visitor identifiers
+// of a small url, keeping only the aggregated counters
+func (usecase SmurlUsecase) ForgetVisitors(ctx context.Context, adminUrl string) error {
+	usecase.logger.Debug("Enter in usecase ForgetVisitors()")
+	smurl, err := usecase.readAdmin(ctx, adminUrl)
+	if err != nil {
+		return err
+	}
+	err = usecase.repository.ForgetVisitors(ctx, smurl.SmallURL)
+	if err != nil {
+		usecase.logger.Error("",
+			zap.Error(err))
+		return fmt.Errorf("forget visitors error: %w", err)
+	}
+	return nil
+}
+
+// PurgeClicks deletes the click details recorded before the given time
+// and returns the number of deleted clicks
+func (usecase SmurlUsecase) PurgeClicks(ctx context.Context, before time.Time) (int64, error) {
+	usecase.logger.Debug("Enter in usecase PurgeClicks()")
+	purged, err := usecase.repository.PurgeClicks(ctx, before)
+	if err != nil {
+		usecase.logger.Error("",
+			zap.Error(err))
+		return 0, fmt.Errorf("purge clicks error: %w", err)
+	}
+	return purged, nil
+}
+
+// Route returns the destination of a click and the ID of its variant.
+// The URL of the first routing rule matching the click wins, otherwise
+// the click goes to one of the variants or to the long url. For sticky
+// small urls click.Variant is the variant the visitor got before
+func (usecase SmurlUsecase) Route(smurl models.Smurl, click models.Click) (string, int64) {
+	usecase.logger.Debug("Enter in usecase Route()")
+	if len(smurl.Rules) > 0 {
+		platform := helpers.ParseUserAgent(click.UserAgent).OS
+		country := usecase.geo.Locate(click.IP).Country
+		language := helpers.PreferredLanguage(click.AcceptLanguage)
+		for _, rule := range smurl.Rules {
+			if rule.Platform != "" && !strings.EqualFold(rule.Platform, platform) ||
+				rule.Country != "" && !strings.EqualFold(rule.Country, country) ||
+				rule.Language != "" && !strings.EqualFold(rule.Language, language) {
+				continue
+			}
+			// The lists may have changed since the rule was added
+			if err := usecase.checkDomain(rule.URL); err != nil {
+				usecase.logger.Info("",
+					zap.Error(err))
+				continue
+			}
+			return rule.URL, 0
+		}
+	}
+	if variant := usecase.chooseVariant(smurl, click.Variant); variant != nil {
+		return variant.URL, variant.ID
+	}
+	return smurl.LongURL, 0
+}
+
+// chooseVariant picks a variant with probability proportional to its
+// weight, or the previous one for sticky small urls. Variants with
+// blocked destinations are skipped
+func (usecase SmurlUsecase) chooseVariant(smurl models.Smurl, previous int64) *models.Variant {
+	var variants []models.Variant
+	total := 0
+	for _, variant := range smurl.Variants {
+		if err := usecase.checkDomain(variant.URL); err != nil {
+			usecase.logger.Info("",
+				zap.Error(err))
+			continue
+		}
+		if smurl.Sticky && variant.ID == previous {
+			return &variant
+		}
+		variants = append(variants, variant)
+		total += variant.Weight
+	}
+	if total <= 0 {
+		return nil
+	}
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(total)))
+	if err != nil {
+		usecase.logger.Error("",
+			zap.Error(err))
+		return &variants[0]
+	}
+	pick := int(n.Int64())
+	for i := range variants {
+		pick -= variants[i].Weight
+		if pick < 0 {
+			return &variants[i]
+		}
+	}
+	return &variants[len(variants)-1]
+}
+
+// AddRule validates a routing rule and adds it to the small url
+func (usecase SmurlUsecase) AddRule(ctx context.Context, adminUrl string, rule models.Rule) error {
+	usecase.logger.Debug("Enter in usecase AddRule()")
+	rule.Platform = strings.TrimSpace(rule.Platform)
+	rule.Country = strings.ToUpper(strings.TrimSpace(rule.Country))
+	rule.Language = strings.ToLower(strings.TrimSpace(rule.Language))
+	if rule.Platform == "" && rule.Country == "" && rule.Language == "" {
+		return &models.ValidationError{Reason: "the rule must have at least one condition"}
+	}
+	if rule.Platform != "" && !isPlatform(rule.Platform) {
+		return &models.ValidationError{Reason: fmt.Sprintf("unknown platform %q", rule.Platform)}
+	}
+	if rule.Country != "" && !isLetters(rule.Country, 2, 2) {
+		return &models.ValidationError{Reason: "the country must be a two-letter ISO code"}
+	}
+	if rule.Language != "" && !isLetters(rule.Language, 2, 3) {
+		return &models.ValidationError{Reason: "the language must be a two or three letter code"}
+	}
+	if rule.Position < 0 {
+		return &models.ValidationError{Reason: "the position must not be negative"}
+	}
+	destination, err := usecase.helpers.CheckURL(rule.URL)
+	if err != nil {
+		return &models.ValidationError{Reason: err.Error()}
+	}
+	rule.URL = destination
+	if err := usecase.checkDomain(rule.URL); err != nil {
+		return err
+	}
+
+	smurl, err := usecase.readAdmin(ctx, adminUrl)
+	if err != nil {
+		return err
+	}
+	err = usecase.repository.AddRule(ctx, smurl.SmallURL, rule)
+	if err != nil {
+		usecase.logger.Error("",
+			zap.Error(err))
+		return fmt.Errorf("add rule error: %w", err)
+	}
+	return nil
+}
+
+// DeleteRule deletes a routing rule of the small url
+func (usecase SmurlUsecase) DeleteRule(ctx context.Context, adminUrl string, id int64) error {
+	usecase.logger.Debug("Enter in usecase DeleteRule()")
+	smurl, err := usecase.readAdmin(ctx, adminUrl)
+	if err != nil {
+		return err
+	}
+	err = usecase.repository.DeleteRule(ctx, smurl.SmallURL, id)
+	if err != nil {
+		usecase.logger.Error("",
+			zap.Error(err))
+		return fmt.Errorf("delete rule error: %w", err)
+	}
+	return nil
+}
+
+// AddVariant validates a weighted destination and adds it to the small url
+func (usecase SmurlUsecase) AddVariant(ctx context.Context, adminUrl string, variant models.Variant) error {
+	usecase.logger.Debug("Enter in usecase AddVariant()")
+	if variant.Weight < 1 || variant.Weight > maxWeight {
+		return &models.ValidationError{Reason: fmt.Sprintf("the weight must be between 1 and %d", maxWeight)}
+	}
+	destination, err := usecase.helpers.CheckURL(variant.URL)
+	if err != nil {
+		return &models.ValidationError{Reason: err.Error()}
+	}
+	variant.URL = destination
+	if err := usecase.checkDomain(variant.URL); err != nil {
+		return err
+	}
+
+	smurl, err := usecase.readAdmin(ctx, adminUrl)
+	if err != nil {
+		return err
+	}
+	err = usecase.repository.AddVariant(ctx, smurl.SmallURL, variant)
+	if err != nil {
+		usecase.logger.Error("",
+			zap.Error(err))
+		return fmt.Errorf("add variant error: %w", err)
+	}
+	return nil
+}
+
+// DeleteVariant deletes a weighted destination of the small url
+func (usecase SmurlUsecase) DeleteVariant(ctx context.Context, adminUrl string, id int64) error {
+	usecase.logger.Debug("Enter in usecase DeleteVariant()")
+	smurl, err := usecase.readAdmin(ctx, adminUrl)
+	if err != nil {
+		return err
+	}
+	err = usecase.repository.DeleteVariant(ctx, smurl.SmallURL, id)
+	if err != nil {
+		usecase.logger.Error("",
+			zap.Error(err))
+		return fmt.Errorf("delete variant error: %w", err)
+	}
+	return nil
+}
+
+// SetSticky sets whether visitors of the small url keep their variant
+func (usecase SmurlUsecase) SetSticky(ctx context.Context, adminUrl string, sticky bool) error {
+	usecase.logger.Debug("Enter in usecase SetSticky()")
+	smurl, err := usecase.readAdmin(ctx, adminUrl)
+	if err != nil {
+		return err
+	}
+	err = usecase.repository.SetSticky(ctx, smurl.SmallURL, sticky)
+	if err != nil {
+		usecase.logger.Error("",
+			zap.Error(err))
+		return fmt.Errorf("set sticky error: %w", err)
+	}
+	return nil
+}
+
+// SetPassthrough sets how the query of a click is forwarded to the destination
+func (usecase SmurlUsecase) SetPassthrough(ctx context.Context, adminUrl string, passthrough models.Passthrough) error {
+	usecase.logger.Debug("Enter in usecase SetPassthrough()")
+	if !passthrough.Valid() {
+		return &models.ValidationError{Reason: fmt.Sprintf("unknown passthrough mode %q", passthrough)}
+	}
+	smurl, err := usecase.readAdmin(ctx, adminUrl)
+	if err != nil {
+		return err
+	}
+	err = usecase.repository.SetPassthrough(ctx, smurl.SmallURL, passthrough)
+	if err != nil {
+		usecase.logger.Error("",
+			zap.Error(err))
+		return fmt.Errorf("set passthrough error: %w", err)
+	}
+	return nil
+}
+
+// Edit changes the destination, the expiry, the passthrough mode or
+// the stickiness of a small url and returns the changed small url
+func (usecase SmurlUsecase) Edit(ctx context.Context, adminUrl string, edit models.Edit) (*models.Smurl, error) {
+	usecase.logger.Debug("Enter in usecase Edit()")
+	if edit.Empty() {
+		return nil, &models.ValidationError{Reason: "nothing to change"}
+	}
+	if edit.LongURL != nil {
+		longURL, err := usecase.helpers.CheckURL(*edit.LongURL)
+		if err != nil {
+			return nil, &models.ValidationError{Reason: err.Error()}
+		}
+		if err := usecase.checkDomain(longURL); err != nil {
+			return nil, err
+		}
+		edit.LongURL = &longURL
+	}
+	if edit.ExpiresAt != nil && !edit.ExpiresAt.IsZero() && !edit.ExpiresAt.After(time.Now()) {
+		return nil, &models.ValidationError{Reason: "the expiry must be in the future"}
+	}
+	if edit.Passthrough != nil && !edit.Passthrough.Valid() {
+		return nil, &models.ValidationError{Reason: fmt.Sprintf("unknown passthrough mode %q", *edit.Passthrough)}
+	}
+	smurl, err := usecase.readAdmin(ctx, adminUrl)
+	if err != nil {
+		return nil, err
+	}
+	err = usecase.repository.Edit(ctx, smurl.SmallURL, edit)
+	if err != nil {
+		usecase.logger.Error("",
+			zap.Error(err))
+		return nil, fmt.Errorf("edit error: %w", err)
+	}
+	return usecase.readAdmin(ctx, adminUrl)
+}
+
+// Delete deletes a small url. It stops redirecting, the row is
+// kept so that its code is never given to another long url
+func (usecase SmurlUsecase) Delete(ctx context.Context, adminUrl string) error {
+	usecase.logger.Debug("Enter in usecase Delete()")
+	smurl, err := usecase.readAdmin(ctx, adminUrl)
+	if err != nil {
+		return err
+	}
+	err = usecase.repository.Delete(ctx, smurl.SmallURL)
+	if err != nil {
+		usecase.logger.Error("",
+			zap.Error(err))
+		return fmt.Errorf("delete error: %w", err)
+	}
+	return nil
+}
+
+func isPlatform(platform string) bool {
+	for _, name := range helpers.Platforms() {
+		if strings.EqualFold(name, platform) {
+			return true
+		}
+	}
+	return false
+}
+
+// isLetters reports whether s consists of min to max ASCII letters
+func isLetters(s string, min int, max int) bool {
+	if len(s) < min || len(s) > max {
+		return false
+	}
+	for _, c := range s {
+		if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+// checkDomain checks the host of a long url against the domain policy
+func (usecase SmurlUsecase) checkDomain(longUrl string) error {
+	u, err := url.Parse(longUrl)
+	if err != nil {
+		return fmt.Errorf("parse long url error: %w", err)
+	}
+	return usecase.policy.Check(u.Hostname())
+}
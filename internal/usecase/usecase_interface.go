@@ -2,13 +2,26 @@ package usecase
 
 import (
 	"context"
+	"time"
 
 	"github.com/sanyarise/smurl/internal/models"
 )
 
 type Usecase interface {
-	Create(ctx context.Context, longUrl string) (*models.Smurl, error)
-	UpdateStat(ctx context.Context, updatedSmurl models.Smurl) error
+	Create(ctx context.Context, longUrl string, passthrough models.Passthrough) (*models.Smurl, error)
+	UpdateStat(ctx context.Context, updatedSmurl models.Smurl, click models.Click) error
 	FindURL(ctx context.Context, smallUrl string) (*models.Smurl, error)
 	ReadStat(ctx context.Context, adminUrl string) (*models.Smurl, error)
+	TimeSeries(ctx context.Context, adminUrl string, granularity models.Granularity, from, to time.Time) ([]models.TimeBucket, error)
+	ForgetVisitors(ctx context.Context, adminUrl string) error
+	PurgeClicks(ctx context.Context, before time.Time) (int64, error)
+	Route(smurl models.Smurl, click models.Click) (string, int64)
+	AddRule(ctx context.Context, adminUrl string, rule models.Rule) error
+	DeleteRule(ctx context.Context, adminUrl string, id int64) error
+	AddVariant(ctx context.Context, adminUrl string, variant models.Variant) error
+	DeleteVariant(ctx context.Context, adminUrl string, id int64) error
+	SetSticky(ctx context.Context, adminUrl string, sticky bool) error
+	SetPassthrough(ctx context.Context, adminUrl string, passthrough models.Passthrough) error
+	Edit(ctx context.Context, adminUrl string, edit models.Edit) (*models.Smurl, error)
+	Delete(ctx context.Context, adminUrl string) error
 }
@@ -4,8 +4,11 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
+	helper "github.com/sanyarise/smurl/internal/helpers"
+	codegen "github.com/sanyarise/smurl/internal/helpers/codegen/mocks"
 	helpers "github.com/sanyarise/smurl/internal/helpers/mocks"
 	"github.com/sanyarise/smurl/internal/models"
 	"github.com/sanyarise/smurl/internal/repository/mocks"
@@ -14,42 +17,79 @@ import (
 )
 
 type TestStatement struct {
-	store   *mocks.MockSmurlStore
-	helpers *helpers.MockHelper
-	logger  *zap.Logger
-	usecase *SmurlUsecase
+	store      *mocks.MockSmurlStore
+	policy     *mocks.MockDomainPolicy
+	geo        *mocks.MockGeoLocator
+	clicks     *mocks.MockClickPublisher
+	helpers    *helpers.MockHelper
+	codes      *codegen.MockCodeGenerator
+	adminCodes *codegen.MockCodeGenerator
+	logger     *zap.Logger
+	usecase    *SmurlUsecase
 }
 
 func NewTestStatement(ctrl *gomock.Controller) *TestStatement {
 	store := mocks.NewMockSmurlStore(ctrl)
+	policy := mocks.NewMockDomainPolicy(ctrl)
+	geo := mocks.NewMockGeoLocator(ctrl)
+	clicks := mocks.NewMockClickPublisher(ctrl)
 	helpers := helpers.NewMockHelper(ctrl)
+	codes := codegen.NewMockCodeGenerator(ctrl)
+	adminCodes := codegen.NewMockCodeGenerator(ctrl)
 	logger := zap.L()
-	usecase := NewSmurlUsecase(store, helpers, logger)
+	usecase := NewSmurlUsecase(store, policy, geo, clicks, helpers, codes, adminCodes, logger)
 	return &TestStatement{
-		store:   store,
-		helpers: helpers,
-		logger:  logger,
-		usecase: usecase,
+		store:      store,
+		policy:     policy,
+		geo:        geo,
+		clicks:     clicks,
+		helpers:    helpers,
+		codes:      codes,
+		adminCodes: adminCodes,
+		logger:     logger,
+		usecase:    usecase,
 	}
 }
 
 var (
 	ctx             = context.Background()
+	adminHash       = helper.HashToken("admin")
+	testHash        = helper.HashToken("test")
 	testCreateSmurl = models.Smurl{
 		LongURL:  "test",
 		SmallURL: "test",
-		AdminURL: "test",
+		AdminURL: testHash,
 	}
-	testUpdateSmurl = models.Smurl{
+	testFoundSmurl = models.Smurl{
+		LongURL:  "http://example.com/path",
 		SmallURL: "test",
-		AdminURL: "test",
-		IPInfo:   []string{"test"},
 	}
-	testUpdatedSmurl = models.Smurl{
+	testUpdateSmurl = models.Smurl{
 		SmallURL: "test",
 		AdminURL: "test",
 		IPInfo:   []string{"test"},
-		Count:    1,
+	}
+	testClick = models.Click{
+		CreatedAt: time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+		IP:        "1.2.3.4",
+		Referer:   "https://www.google.com/search?q=smurl",
+		UserAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 16_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.0 Mobile/15E148 Safari/604.1",
+	}
+	testSavedClick = models.Click{
+		SmallURL:      "test",
+		CreatedAt:     time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+		IP:            "1.2.3.0",
+		VisitorID:     "testVisitor",
+		Referer:       "https://www.google.com/search?q=smurl",
+		RefererDomain: "google.com",
+		UserAgent:     "Mozilla/5.0 (iPhone; CPU iPhone OS 16_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.0 Mobile/15E148 Safari/604.1",
+		Browser:       "Safari",
+		OS:            "iOS",
+		Device:        "mobile",
+		Location:      models.Location{Country: "GB", City: "London"},
+	}
+	testClickStats = models.ClickStats{
+		Browsers: []models.StatItem{{Name: "Safari", Count: 1}},
 	}
 	err = errors.New("test error")
 )
@@ -59,20 +99,96 @@ func TestCreate(t *testing.T) {
 	defer ctrl.Finish()
 	s := NewTestStatement(ctrl)
 
-	s.helpers.EXPECT().RandString().Return("test")
-	s.helpers.EXPECT().RandString().Return("test")
+	s.policy.EXPECT().Check("").Return(nil).Times(3)
+	s.codes.EXPECT().Generate(ctx).Return("test", nil)
+	s.adminCodes.EXPECT().Generate(ctx).Return("test", nil)
 	s.store.EXPECT().Create(ctx, testCreateSmurl).Return(nil, err)
-	res, err := s.usecase.Create(ctx, "test")
+	res, err := s.usecase.Create(ctx, "test", models.PassthroughOff)
 	require.Error(t, err)
 	require.Nil(t, res)
 
-	s.helpers.EXPECT().RandString().Return("test")
-	s.helpers.EXPECT().RandString().Return("test")
-	s.store.EXPECT().Create(ctx, testCreateSmurl).Return(&testCreateSmurl, nil)
-	res, err = s.usecase.Create(ctx, "test")
+	s.codes.EXPECT().Generate(ctx).Return("test", nil)
+	s.adminCodes.EXPECT().Generate(ctx).Return("test", nil)
+	s.store.EXPECT().Create(ctx, testCreateSmurl).Return(&models.Smurl{SmallURL: "test", AdminURL: testHash}, nil)
+	res, err = s.usecase.Create(ctx, "test", models.PassthroughOff)
 	require.NoError(t, err)
 	require.NotNil(t, res)
-	require.Equal(t, res, &testCreateSmurl)
+	// The token is returned, only its hash is stored
+	require.Equal(t, res, &models.Smurl{SmallURL: "test", AdminURL: "test"})
+
+	// Links created with an API key are owned by the key
+	ownerCtx := models.WithOwner(ctx, 7)
+	owned := testCreateSmurl
+	owned.OwnerID = 7
+	s.codes.EXPECT().Generate(ownerCtx).Return("test", nil)
+	s.adminCodes.EXPECT().Generate(ownerCtx).Return("test", nil)
+	s.store.EXPECT().Create(ownerCtx, owned).Return(&models.Smurl{SmallURL: "test", AdminURL: testHash}, nil)
+	_, err = s.usecase.Create(ownerCtx, "test", models.PassthroughOff)
+	require.NoError(t, err)
+}
+
+func TestCreateConflict(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(ctrl)
+
+	s.policy.EXPECT().Check("").Return(nil).Times(3)
+	s.codes.EXPECT().Generate(ctx).Return("taken", nil)
+	s.codes.EXPECT().Generate(ctx).Return("test", nil)
+	s.adminCodes.EXPECT().Generate(ctx).Return("test", nil).Times(2)
+	s.store.EXPECT().Create(ctx, gomock.Any()).Return(nil, models.ErrConflict)
+	s.store.EXPECT().Create(ctx, testCreateSmurl).Return(&models.Smurl{SmallURL: "test", AdminURL: testHash}, nil)
+	res, err := s.usecase.Create(ctx, "test", models.PassthroughOff)
+	require.NoError(t, err)
+	require.Equal(t, &models.Smurl{SmallURL: "test", AdminURL: "test"}, res)
+
+	// The store keeps reporting taken codes
+	s.codes.EXPECT().Generate(ctx).Return("taken", nil).Times(maxCodeAttempts)
+	s.adminCodes.EXPECT().Generate(ctx).Return("test", nil).Times(maxCodeAttempts)
+	s.store.EXPECT().Create(ctx, gomock.Any()).Return(nil, models.ErrConflict).Times(maxCodeAttempts)
+	_, err = s.usecase.Create(ctx, "test", models.PassthroughOff)
+	require.ErrorIs(t, err, models.ErrConflict)
+
+	s.codes.EXPECT().Generate(ctx).Return("", errors.New("error"))
+	_, err = s.usecase.Create(ctx, "test", models.PassthroughOff)
+	require.Error(t, err)
+}
+
+func TestCreateBlocked(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(ctrl)
+
+	s.policy.EXPECT().Check("example.com").Return(models.ErrBlocked)
+	res, err := s.usecase.Create(ctx, "http://example.com/path", models.PassthroughOff)
+	require.ErrorIs(t, err, models.ErrBlocked)
+	require.Nil(t, res)
+}
+
+func TestCreatePassthrough(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(ctrl)
+
+	_, err := s.usecase.Create(ctx, "http://example.com/path", models.Passthrough("always"))
+	require.ErrorIs(t, err, models.ErrValidation)
+
+	s.policy.EXPECT().Check("example.com").Return(nil)
+	s.codes.EXPECT().Generate(ctx).Return("small", nil)
+	s.adminCodes.EXPECT().Generate(ctx).Return("admin", nil)
+	s.store.EXPECT().Create(ctx, models.Smurl{
+		LongURL:     "http://example.com/path",
+		SmallURL:    "small",
+		AdminURL:    adminHash,
+		Passthrough: models.PassthroughKeep,
+	}).Return(&models.Smurl{SmallURL: "small", AdminURL: adminHash}, nil)
+	_, err = s.usecase.Create(ctx, "http://example.com/path", models.PassthroughKeep)
+	require.NoError(t, err)
+
+	require.ErrorIs(t, s.usecase.SetPassthrough(ctx, "admin", models.Passthrough("always")), models.ErrValidation)
+	s.store.EXPECT().ReadStat(ctx, adminHash).Return(&models.Smurl{SmallURL: "small", AdminURL: adminHash}, nil)
+	s.store.EXPECT().SetPassthrough(ctx, "small", models.PassthroughOverride).Return(nil)
+	require.NoError(t, s.usecase.SetPassthrough(ctx, "admin", models.PassthroughOverride))
 }
 
 func TestUpdateStat(t *testing.T) {
@@ -80,12 +196,36 @@ func TestUpdateStat(t *testing.T) {
 	defer ctrl.Finish()
 	s := NewTestStatement(ctrl)
 
-	s.store.EXPECT().UpdateStat(ctx, testUpdatedSmurl).Return(err)
-	err := s.usecase.UpdateStat(ctx, testUpdateSmurl)
+	s.helpers.EXPECT().VisitorID("1.2.3.4", testClick.UserAgent, testClick.CreatedAt).Return("testVisitor").Times(2)
+	s.helpers.EXPECT().AnonymizeIP("1.2.3.4").Return("1.2.3.0").Times(2)
+	s.geo.EXPECT().Locate("1.2.3.4").Return(models.Location{Country: "GB", City: "London"}).Times(2)
+	s.store.EXPECT().UpdateStat(ctx, testUpdateSmurl, testSavedClick).Return(err)
+	err := s.usecase.UpdateStat(ctx, testUpdateSmurl, testClick)
 	require.Error(t, err)
 
-	s.store.EXPECT().UpdateStat(ctx, testUpdatedSmurl).Return(nil)
-	err = s.usecase.UpdateStat(ctx, testUpdateSmurl)
+	// Only saved clicks are streamed
+	s.store.EXPECT().UpdateStat(ctx, testUpdateSmurl, testSavedClick).Return(nil)
+	s.clicks.EXPECT().Publish(testSavedClick)
+	err = s.usecase.UpdateStat(ctx, testUpdateSmurl, testClick)
+	require.NoError(t, err)
+}
+
+func TestUpdateStatBot(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(ctrl)
+
+	click := testClick
+	click.Bot = true
+	saved := testSavedClick
+	saved.Bot = true
+
+	s.helpers.EXPECT().VisitorID("1.2.3.4", testClick.UserAgent, testClick.CreatedAt).Return("testVisitor")
+	s.helpers.EXPECT().AnonymizeIP("1.2.3.4").Return("1.2.3.0")
+	s.geo.EXPECT().Locate("1.2.3.4").Return(models.Location{Country: "GB", City: "London"})
+	s.store.EXPECT().UpdateStat(ctx, testUpdateSmurl, saved).Return(nil)
+	s.clicks.EXPECT().Publish(saved)
+	err := s.usecase.UpdateStat(ctx, testUpdateSmurl, click)
 	require.NoError(t, err)
 }
 
@@ -99,11 +239,54 @@ func TestFindURL(t *testing.T) {
 	require.Error(t, err)
 	require.Nil(t, res)
 
-	s.store.EXPECT().FindURL(ctx, "test").Return(&testCreateSmurl, nil)
+	s.store.EXPECT().FindURL(ctx, "test").Return(&testFoundSmurl, nil)
+	s.policy.EXPECT().Check("example.com").Return(nil)
 	res, err = s.usecase.FindURL(ctx, "test")
 	require.NoError(t, err)
 	require.NotNil(t, res)
-	require.Equal(t, res, &testCreateSmurl)
+	require.Equal(t, res, &testFoundSmurl)
+
+	s.store.EXPECT().FindURL(ctx, "test").Return(&testFoundSmurl, nil)
+	s.policy.EXPECT().Check("example.com").Return(models.ErrBlocked)
+	res, err = s.usecase.FindURL(ctx, "test")
+	require.ErrorIs(t, err, models.ErrBlocked)
+	require.Nil(t, res)
+}
+
+func TestFindURLGone(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(ctrl)
+
+	deleted := testFoundSmurl
+	deleted.DeletedAt = time.Now().Add(-time.Hour)
+	s.store.EXPECT().FindURL(ctx, "test").Return(&deleted, nil)
+	res, err := s.usecase.FindURL(ctx, "test")
+	require.ErrorIs(t, err, models.ErrDeleted)
+	require.Nil(t, res)
+
+	disabled := testFoundSmurl
+	disabled.DisabledAt = time.Now().Add(-time.Hour)
+	s.store.EXPECT().FindURL(ctx, "test").Return(&disabled, nil)
+	res, err = s.usecase.FindURL(ctx, "test")
+	require.ErrorIs(t, err, models.ErrDisabled)
+	require.Nil(t, res)
+
+	expired := testFoundSmurl
+	expired.ExpiresAt = time.Now().Add(-time.Minute)
+	s.store.EXPECT().FindURL(ctx, "test").Return(&expired, nil)
+	res, err = s.usecase.FindURL(ctx, "test")
+	require.ErrorIs(t, err, models.ErrExpired)
+	require.Nil(t, res)
+
+	// Links expiring in the future still redirect
+	expiring := testFoundSmurl
+	expiring.ExpiresAt = time.Now().Add(time.Hour)
+	s.store.EXPECT().FindURL(ctx, "test").Return(&expiring, nil)
+	s.policy.EXPECT().Check("example.com").Return(nil)
+	res, err = s.usecase.FindURL(ctx, "test")
+	require.NoError(t, err)
+	require.Equal(t, &expiring, res)
 }
 
 func TestReadStat(t *testing.T) {
@@ -111,14 +294,320 @@ func TestReadStat(t *testing.T) {
 	defer ctrl.Finish()
 	s := NewTestStatement(ctrl)
 
-	s.store.EXPECT().ReadStat(ctx, "test").Return(nil, err)
+	s.store.EXPECT().ReadStat(ctx, testHash).Return(nil, err)
 	res, err := s.usecase.ReadStat(ctx, "test")
 	require.Error(t, err)
 	require.Nil(t, res)
 
-	s.store.EXPECT().ReadStat(ctx, "test").Return(&testCreateSmurl, nil)
+	s.store.EXPECT().ReadStat(ctx, testHash).Return(&models.Smurl{SmallURL: "test", AdminURL: testHash}, nil)
+	s.store.EXPECT().ReadClickStats(ctx, "test").Return(nil, err)
+	res, err = s.usecase.ReadStat(ctx, "test")
+	require.Error(t, err)
+	require.Nil(t, res)
+
+	s.store.EXPECT().ReadStat(ctx, testHash).Return(&models.Smurl{SmallURL: "test", AdminURL: testHash}, nil)
+	s.store.EXPECT().ReadClickStats(ctx, "test").Return(&testClickStats, nil)
 	res, err = s.usecase.ReadStat(ctx, "test")
 	require.NoError(t, err)
 	require.NotNil(t, res)
-	require.Equal(t, res, &testCreateSmurl)
+	require.Equal(t, res, &models.Smurl{SmallURL: "test", AdminURL: "test", Stats: testClickStats})
+
+	// The stored hash must match the token
+	s.store.EXPECT().ReadStat(ctx, testHash).Return(&models.Smurl{SmallURL: "test", AdminURL: adminHash}, nil)
+	_, err = s.usecase.ReadStat(ctx, "test")
+	require.ErrorIs(t, err, models.ErrNotFound)
+
+	// Expired links keep their statistics, deleted ones do not
+	expired := time.Now().Add(-time.Hour)
+	s.store.EXPECT().ReadStat(ctx, testHash).Return(&models.Smurl{SmallURL: "test", AdminURL: testHash, ExpiresAt: expired}, nil)
+	s.store.EXPECT().ReadClickStats(ctx, "test").Return(&testClickStats, nil)
+	_, err = s.usecase.ReadStat(ctx, "test")
+	require.NoError(t, err)
+
+	s.store.EXPECT().ReadStat(ctx, testHash).Return(&models.Smurl{SmallURL: "test", AdminURL: testHash, DeletedAt: expired}, nil)
+	_, err = s.usecase.ReadStat(ctx, "test")
+	require.ErrorIs(t, err, models.ErrDeleted)
+}
+
+func TestTimeSeries(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(ctrl)
+
+	from := time.Date(2023, 1, 2, 3, 40, 0, 0, time.UTC)
+	to := time.Date(2023, 1, 2, 6, 10, 0, 0, time.UTC)
+	hour := func(h int) time.Time {
+		return time.Date(2023, 1, 2, h, 0, 0, 0, time.UTC)
+	}
+
+	_, err := s.usecase.TimeSeries(ctx, "test", "minute", from, to)
+	require.ErrorIs(t, err, models.ErrValidation)
+	_, err = s.usecase.TimeSeries(ctx, "test", models.Hourly, to, from)
+	require.ErrorIs(t, err, models.ErrValidation)
+	_, err = s.usecase.TimeSeries(ctx, "test", models.Hourly, from, from.AddDate(1, 0, 0))
+	require.ErrorIs(t, err, models.ErrValidation)
+
+	s.store.EXPECT().ReadStat(ctx, testHash).Return(nil, models.ErrNotFound)
+	_, err = s.usecase.TimeSeries(ctx, "test", models.Hourly, from, to)
+	require.ErrorIs(t, err, models.ErrNotFound)
+
+	s.store.EXPECT().ReadStat(ctx, testHash).Return(&models.Smurl{SmallURL: "small", AdminURL: testHash}, nil)
+	s.store.EXPECT().ReadTimeSeries(ctx, "small", models.Hourly, hour(3), hour(6)).Return(nil, err)
+	_, err = s.usecase.TimeSeries(ctx, "test", models.Hourly, from, to)
+	require.Error(t, err)
+
+	s.store.EXPECT().ReadStat(ctx, testHash).Return(&models.Smurl{SmallURL: "small", AdminURL: testHash}, nil)
+	s.store.EXPECT().ReadTimeSeries(ctx, "small", models.Hourly, hour(3), hour(6)).Return([]models.TimeBucket{
+		{Start: hour(4), Count: 2},
+		{Start: hour(6), Count: 5},
+	}, nil)
+	res, err := s.usecase.TimeSeries(ctx, "test", models.Hourly, from, to)
+	require.NoError(t, err)
+	require.Equal(t, []models.TimeBucket{
+		{Start: hour(3), Count: 0},
+		{Start: hour(4), Count: 2},
+		{Start: hour(5), Count: 0},
+		{Start: hour(6), Count: 5},
+	}, res)
+}
+
+func TestForgetVisitors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(ctrl)
+
+	s.store.EXPECT().ReadStat(ctx, adminHash).Return(nil, models.ErrNotFound)
+	err := s.usecase.ForgetVisitors(ctx, "admin")
+	require.ErrorIs(t, err, models.ErrNotFound)
+
+	s.store.EXPECT().ReadStat(ctx, adminHash).Return(&models.Smurl{SmallURL: "small", AdminURL: adminHash}, nil)
+	s.store.EXPECT().ForgetVisitors(ctx, "small").Return(err)
+	require.Error(t, s.usecase.ForgetVisitors(ctx, "admin"))
+
+	s.store.EXPECT().ReadStat(ctx, adminHash).Return(&models.Smurl{SmallURL: "small", AdminURL: adminHash}, nil)
+	s.store.EXPECT().ForgetVisitors(ctx, "small").Return(nil)
+	require.NoError(t, s.usecase.ForgetVisitors(ctx, "admin"))
+}
+
+func TestPurgeClicks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(ctrl)
+	before := time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	s.store.EXPECT().PurgeClicks(ctx, before).Return(int64(0), err)
+	_, err := s.usecase.PurgeClicks(ctx, before)
+	require.Error(t, err)
+
+	s.store.EXPECT().PurgeClicks(ctx, before).Return(int64(7), nil)
+	purged, err := s.usecase.PurgeClicks(ctx, before)
+	require.NoError(t, err)
+	require.Equal(t, int64(7), purged)
+}
+
+func TestRoute(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(ctrl)
+	iphone := "Mozilla/5.0 (iPhone; CPU iPhone OS 16_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.0 Mobile/15E148 Safari/604.1"
+	smurl := models.Smurl{
+		LongURL: "https://example.com",
+		Rules: []models.Rule{
+			{ID: 1, Position: 1, Platform: "android", URL: "https://play.google.com/app"},
+			{ID: 2, Position: 2, Platform: "ios", Country: "DE", URL: "https://apps.apple.com/de/app"},
+			{ID: 3, Position: 3, Language: "de", URL: "https://example.de"},
+		},
+	}
+
+	destination, _ := s.usecase.Route(models.Smurl{LongURL: "https://example.com"}, models.Click{})
+	require.Equal(t, "https://example.com", destination)
+
+	s.geo.EXPECT().Locate("1.2.3.4").Return(models.Location{Country: "DE"})
+	s.policy.EXPECT().Check("apps.apple.com").Return(nil)
+	destination, _ = s.usecase.Route(smurl, models.Click{IP: "1.2.3.4", UserAgent: iphone})
+	require.Equal(t, "https://apps.apple.com/de/app", destination)
+
+	s.geo.EXPECT().Locate("1.2.3.4").Return(models.Location{Country: "FR"})
+	s.policy.EXPECT().Check("example.de").Return(nil)
+	destination, _ = s.usecase.Route(smurl, models.Click{
+		IP: "1.2.3.4", UserAgent: iphone, AcceptLanguage: "de-AT,de;q=0.9,en;q=0.5",
+	})
+	require.Equal(t, "https://example.de", destination)
+
+	s.geo.EXPECT().Locate("1.2.3.4").Return(models.Location{})
+	s.policy.EXPECT().Check("example.de").Return(models.ErrBlocked)
+	destination, _ = s.usecase.Route(smurl, models.Click{IP: "1.2.3.4", AcceptLanguage: "de"})
+	require.Equal(t, "https://example.com", destination)
+}
+
+func TestRouteVariants(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(ctrl)
+	smurl := models.Smurl{
+		LongURL: "https://example.com",
+		Variants: []models.Variant{
+			{ID: 1, URL: "https://a.example.com", Weight: 3},
+			{ID: 2, URL: "https://b.example.com", Weight: 1},
+		},
+	}
+	s.policy.EXPECT().Check(gomock.Any()).Return(nil).AnyTimes()
+
+	counts := make(map[int64]int)
+	for i := 0; i < 4000; i++ {
+		destination, variant := s.usecase.Route(smurl, models.Click{})
+		require.Equal(t, smurl.Variants[variant-1].URL, destination)
+		counts[variant]++
+	}
+	require.InDelta(t, 3000, counts[1], 200)
+	require.InDelta(t, 1000, counts[2], 200)
+
+	// A returning visitor keeps the variant only for sticky small urls
+	smurl.Sticky = true
+	for i := 0; i < 20; i++ {
+		_, variant := s.usecase.Route(smurl, models.Click{Variant: 2})
+		require.Equal(t, int64(2), variant)
+	}
+}
+
+func TestRouteVariantsBlocked(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(ctrl)
+	smurl := models.Smurl{
+		LongURL:  "https://example.com",
+		Variants: []models.Variant{{ID: 1, URL: "https://a.example.com", Weight: 1}},
+	}
+	s.policy.EXPECT().Check("a.example.com").Return(models.ErrBlocked)
+	destination, variant := s.usecase.Route(smurl, models.Click{})
+	require.Equal(t, "https://example.com", destination)
+	require.Equal(t, int64(0), variant)
+}
+
+func TestAddVariant(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(ctrl)
+
+	err := s.usecase.AddVariant(ctx, "admin", models.Variant{URL: "https://example.de"})
+	require.ErrorIs(t, err, models.ErrValidation)
+
+	s.helpers.EXPECT().CheckURL("https://example.de").Return("https://example.de", nil)
+	s.policy.EXPECT().Check("example.de").Return(nil)
+	s.store.EXPECT().ReadStat(ctx, adminHash).Return(&models.Smurl{SmallURL: "small", AdminURL: adminHash}, nil)
+	s.store.EXPECT().AddVariant(ctx, "small", models.Variant{URL: "https://example.de", Weight: 2}).Return(nil)
+	require.NoError(t, s.usecase.AddVariant(ctx, "admin", models.Variant{URL: "https://example.de", Weight: 2}))
+
+	s.store.EXPECT().ReadStat(ctx, adminHash).Return(&models.Smurl{SmallURL: "small", AdminURL: adminHash}, nil)
+	s.store.EXPECT().DeleteVariant(ctx, "small", int64(1)).Return(models.ErrNotFound)
+	require.ErrorIs(t, s.usecase.DeleteVariant(ctx, "admin", 1), models.ErrNotFound)
+
+	s.store.EXPECT().ReadStat(ctx, adminHash).Return(&models.Smurl{SmallURL: "small", AdminURL: adminHash}, nil)
+	s.store.EXPECT().SetSticky(ctx, "small", true).Return(nil)
+	require.NoError(t, s.usecase.SetSticky(ctx, "admin", true))
+}
+
+func TestAddRule(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(ctrl)
+
+	err := s.usecase.AddRule(ctx, "admin", models.Rule{URL: "https://example.de"})
+	require.ErrorIs(t, err, models.ErrValidation)
+	err = s.usecase.AddRule(ctx, "admin", models.Rule{Platform: "Amiga", URL: "https://example.de"})
+	require.ErrorIs(t, err, models.ErrValidation)
+	err = s.usecase.AddRule(ctx, "admin", models.Rule{Country: "DEU", URL: "https://example.de"})
+	require.ErrorIs(t, err, models.ErrValidation)
+
+	s.helpers.EXPECT().CheckURL("ftp://example.de").Return("", errors.New("invalid url"))
+	err = s.usecase.AddRule(ctx, "admin", models.Rule{Country: "de", URL: "ftp://example.de"})
+	require.ErrorIs(t, err, models.ErrValidation)
+
+	s.helpers.EXPECT().CheckURL("https://example.de").Return("https://example.de", nil)
+	s.policy.EXPECT().Check("example.de").Return(models.ErrBlocked)
+	err = s.usecase.AddRule(ctx, "admin", models.Rule{Country: "de", URL: "https://example.de"})
+	require.ErrorIs(t, err, models.ErrBlocked)
+
+	s.helpers.EXPECT().CheckURL("https://example.de").Return("https://example.de", nil)
+	s.policy.EXPECT().Check("example.de").Return(nil)
+	s.store.EXPECT().ReadStat(ctx, adminHash).Return(&models.Smurl{SmallURL: "small", AdminURL: adminHash}, nil)
+	s.store.EXPECT().AddRule(ctx, "small", models.Rule{Country: "DE", Language: "de", URL: "https://example.de"}).Return(nil)
+	err = s.usecase.AddRule(ctx, "admin", models.Rule{Country: " de", Language: "DE", URL: "https://example.de"})
+	require.NoError(t, err)
+}
+
+func TestDeleteRule(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(ctrl)
+
+	s.store.EXPECT().ReadStat(ctx, adminHash).Return(nil, models.ErrNotFound)
+	require.ErrorIs(t, s.usecase.DeleteRule(ctx, "admin", 1), models.ErrNotFound)
+
+	s.store.EXPECT().ReadStat(ctx, adminHash).Return(&models.Smurl{SmallURL: "small", AdminURL: adminHash}, nil)
+	s.store.EXPECT().DeleteRule(ctx, "small", int64(1)).Return(models.ErrNotFound)
+	require.ErrorIs(t, s.usecase.DeleteRule(ctx, "admin", 1), models.ErrNotFound)
+
+	s.store.EXPECT().ReadStat(ctx, adminHash).Return(&models.Smurl{SmallURL: "small", AdminURL: adminHash}, nil)
+	s.store.EXPECT().DeleteRule(ctx, "small", int64(1)).Return(nil)
+	require.NoError(t, s.usecase.DeleteRule(ctx, "admin", 1))
+}
+
+func TestEdit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(ctrl)
+
+	_, err := s.usecase.Edit(ctx, "test", models.Edit{})
+	require.ErrorIs(t, err, models.ErrValidation)
+
+	past := time.Now().Add(-time.Hour)
+	_, err = s.usecase.Edit(ctx, "test", models.Edit{ExpiresAt: &past})
+	require.ErrorIs(t, err, models.ErrValidation)
+
+	unknown := models.Passthrough("always")
+	_, err = s.usecase.Edit(ctx, "test", models.Edit{Passthrough: &unknown})
+	require.ErrorIs(t, err, models.ErrValidation)
+
+	blocked := "http://blocked.com"
+	s.helpers.EXPECT().CheckURL(blocked).Return(blocked, nil)
+	s.policy.EXPECT().Check("blocked.com").Return(models.ErrBlocked)
+	_, err = s.usecase.Edit(ctx, "test", models.Edit{LongURL: &blocked})
+	require.ErrorIs(t, err, models.ErrBlocked)
+
+	longURL := "http://example.com/new"
+	future := time.Now().Add(time.Hour)
+	edit := models.Edit{LongURL: &longURL, ExpiresAt: &future}
+	edited := &models.Smurl{SmallURL: "test", AdminURL: testHash, LongURL: longURL, ExpiresAt: future}
+	s.helpers.EXPECT().CheckURL(longURL).Return(longURL, nil)
+	s.policy.EXPECT().Check("example.com").Return(nil)
+	s.store.EXPECT().ReadStat(ctx, testHash).Return(&models.Smurl{SmallURL: "test", AdminURL: testHash}, nil)
+	s.store.EXPECT().Edit(ctx, "test", edit).Return(nil)
+	s.store.EXPECT().ReadStat(ctx, testHash).Return(edited, nil)
+	res, err := s.usecase.Edit(ctx, "test", edit)
+	require.NoError(t, err)
+	require.Equal(t, longURL, res.LongURL)
+	require.Equal(t, "test", res.AdminURL)
+
+	// A zero expiry removes it
+	never := time.Time{}
+	s.store.EXPECT().ReadStat(ctx, testHash).Return(&models.Smurl{SmallURL: "test", AdminURL: testHash}, nil)
+	s.store.EXPECT().Edit(ctx, "test", models.Edit{ExpiresAt: &never}).Return(errors.New("error"))
+	_, err = s.usecase.Edit(ctx, "test", models.Edit{ExpiresAt: &never})
+	require.Error(t, err)
+}
+
+func TestDelete(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := NewTestStatement(ctrl)
+
+	s.store.EXPECT().ReadStat(ctx, testHash).Return(nil, models.ErrNotFound)
+	err := s.usecase.Delete(ctx, "test")
+	require.ErrorIs(t, err, models.ErrNotFound)
+
+	s.store.EXPECT().ReadStat(ctx, testHash).Return(&models.Smurl{SmallURL: "test", AdminURL: testHash}, nil)
+	s.store.EXPECT().Delete(ctx, "test").Return(nil)
+	err = s.usecase.Delete(ctx, "test")
+	require.NoError(t, err)
 }
@@ -5,5 +5,120 @@ CREATE TABLE IF NOT EXISTS smurls (
 	long_url varchar NOT NULL,
 	admin_url varchar NOT NULL,
 	count integer,
+	unique_count bigint NOT NULL DEFAULT 0,
+	bot_count bigint NOT NULL DEFAULT 0,
+	imported_clicks bigint NOT NULL DEFAULT 0,
+	owner_id bigint NOT NULL DEFAULT 0,
+	expired_notified boolean NOT NULL DEFAULT false,
+	sticky boolean NOT NULL DEFAULT false,
+	passthrough varchar NOT NULL DEFAULT '',
+	admin_hashed boolean NOT NULL DEFAULT true,
+	expires_at timestamptz,
+	deleted_at timestamptz,
+	disabled_at timestamptz,
 	ip_info text[]
-	);
\ No newline at end of file
+	);
+CREATE TABLE IF NOT EXISTS clicks (
+	small_url varchar NOT NULL,
+	created_at timestamptz NOT NULL,
+	ip varchar NOT NULL,
+	visitor_id varchar NOT NULL,
+	referer varchar NOT NULL,
+	referer_domain varchar NOT NULL,
+	user_agent varchar NOT NULL,
+	browser varchar NOT NULL,
+	os varchar NOT NULL,
+	device varchar NOT NULL,
+	accept_language varchar NOT NULL,
+	bot boolean NOT NULL DEFAULT false,
+	country varchar NOT NULL DEFAULT '',
+	region varchar NOT NULL DEFAULT '',
+	city varchar NOT NULL DEFAULT '',
+	variant_id bigint NOT NULL DEFAULT 0
+	);
+CREATE INDEX IF NOT EXISTS clicks_small_url_idx ON clicks (small_url, created_at);
+CREATE TABLE IF NOT EXISTS click_rollups (
+	small_url varchar NOT NULL,
+	granularity varchar NOT NULL,
+	bucket timestamptz NOT NULL,
+	count bigint NOT NULL,
+	PRIMARY KEY (small_url, granularity, bucket)
+	);
+CREATE TABLE IF NOT EXISTS visitors (
+	small_url varchar NOT NULL,
+	visitor_id varchar NOT NULL,
+	PRIMARY KEY (small_url, visitor_id)
+	);
+CREATE TABLE IF NOT EXISTS visitor_sketches (
+	small_url varchar PRIMARY KEY,
+	sketch bytea NOT NULL
+	);
+
+CREATE TABLE IF NOT EXISTS routing_rules (
+	id bigserial PRIMARY KEY,
+	small_url varchar NOT NULL,
+	position integer NOT NULL,
+	platform varchar NOT NULL DEFAULT '',
+	country varchar NOT NULL DEFAULT '',
+	language varchar NOT NULL DEFAULT '',
+	url varchar NOT NULL
+	);
+CREATE INDEX IF NOT EXISTS routing_rules_small_url_idx ON routing_rules (small_url, position);
+CREATE TABLE IF NOT EXISTS variants (
+	id bigserial PRIMARY KEY,
+	small_url varchar NOT NULL,
+	url varchar NOT NULL,
+	weight integer NOT NULL,
+	count bigint NOT NULL DEFAULT 0
+	);
+CREATE INDEX IF NOT EXISTS variants_small_url_idx ON variants (small_url);
+CREATE SEQUENCE IF NOT EXISTS smurl_codes;
+CREATE UNIQUE INDEX IF NOT EXISTS smurls_small_url_key ON smurls (small_url);
+CREATE UNIQUE INDEX IF NOT EXISTS smurls_admin_url_key ON smurls (admin_url);
+CREATE TABLE IF NOT EXISTS api_keys (
+	id bigserial PRIMARY KEY,
+	name varchar NOT NULL,
+	key_hash varchar NOT NULL UNIQUE,
+	created_at timestamptz NOT NULL,
+	revoked_at timestamptz
+	);
+CREATE TABLE IF NOT EXISTS webhooks (
+	id bigserial PRIMARY KEY,
+	owner_id bigint NOT NULL DEFAULT 0,
+	url varchar NOT NULL,
+	secret varchar NOT NULL,
+	events text[] NOT NULL DEFAULT '{}',
+	created_at timestamptz NOT NULL,
+	deleted_at timestamptz
+	);
+CREATE TABLE IF NOT EXISTS webhook_outbox (
+	id bigserial PRIMARY KEY,
+	type varchar NOT NULL,
+	small_url varchar NOT NULL,
+	owner_id bigint NOT NULL DEFAULT 0,
+	payload jsonb NOT NULL,
+	created_at timestamptz NOT NULL,
+	dispatched_at timestamptz
+	);
+CREATE INDEX IF NOT EXISTS webhook_outbox_pending_idx ON webhook_outbox (id) WHERE dispatched_at IS NULL;
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+	id bigserial PRIMARY KEY,
+	webhook_id bigint NOT NULL,
+	event_id bigint NOT NULL,
+	status varchar NOT NULL,
+	attempts integer NOT NULL DEFAULT 0,
+	next_attempt_at timestamptz NOT NULL,
+	created_at timestamptz NOT NULL,
+	delivered_at timestamptz
+	);
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE TABLE IF NOT EXISTS webhook_attempts (
+	id bigserial PRIMARY KEY,
+	delivery_id bigint NOT NULL,
+	attempt integer NOT NULL,
+	at timestamptz NOT NULL,
+	status_code integer NOT NULL DEFAULT 0,
+	error varchar NOT NULL DEFAULT '',
+	duration_ms bigint NOT NULL DEFAULT 0
+	);
+CREATE INDEX IF NOT EXISTS webhook_attempts_delivery_idx ON webhook_attempts (delivery_id);
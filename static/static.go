@@ -0,0 +1,36 @@
+// Package static holds the templates and assets of the web interface
+package static
+
+import (
+	"embed"
+	"errors"
+	"io/fs"
+	"os"
+)
+
+//go:embed *.tmpl css images
+var embedded embed.FS
+
+// Assets returns the embedded templates and assets. Files in the
+// optional override directory replace the embedded files of the same
+// name, so that a custom theme needs only the changed files
+func Assets(overrideDir string) fs.FS {
+	if overrideDir == "" {
+		return embedded
+	}
+	return overlay{top: os.DirFS(overrideDir), base: embedded}
+}
+
+// overlay opens files from top, falling back to base
+type overlay struct {
+	top  fs.FS
+	base fs.FS
+}
+
+func (o overlay) Open(name string) (fs.File, error) {
+	f, err := o.top.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return o.base.Open(name)
+	}
+	return f, err
+}
@@ -0,0 +1,29 @@
+package static
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssets(t *testing.T) {
+	home, err := fs.ReadFile(Assets(""), "home.tmpl")
+	require.NoError(t, err)
+	require.NotEmpty(t, home)
+	_, err = fs.Stat(Assets(""), "images/gopher.png")
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "home.tmpl"), []byte("custom"), 0o644))
+	assets := Assets(dir)
+	custom, err := fs.ReadFile(assets, "home.tmpl")
+	require.NoError(t, err)
+	require.Equal(t, "custom", string(custom))
+	// Files missing in the override directory come from the binary
+	result, err := fs.ReadFile(assets, "result.tmpl")
+	require.NoError(t, err)
+	require.NotEmpty(t, result)
+}